@@ -0,0 +1,86 @@
+// Command envdoc regenerates .env.example, docs/configuration.md and docs/config.schema.json from the registered
+// configuration keys.
+//
+//	go run ./cmd/envdoc          # rewrite the generated files
+//	go run ./cmd/envdoc -check   # fail when the generated files are out of date
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"github.com/hbttundar/diabuddy-api-config/config/schema"
+	"github.com/hbttundar/diabuddy-api-config/util/generator/envdoc"
+	"github.com/hbttundar/diabuddy-api-config/util/resolver/rootpath"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+type target struct {
+	path  string
+	write func(io.Writer, []envmanager.KeyDefinition) diabuddyErrors.ApiErrors
+}
+
+func main() {
+	envExample := flag.String("env-example", ".env.example", "path of the generated dotenv example, relative to the module root")
+	markdown := flag.String("markdown", filepath.Join("docs", "configuration.md"), "path of the generated key reference, relative to the module root")
+	jsonSchema := flag.String("schema", filepath.Join("docs", "config.schema.json"), "path of the generated JSON Schema, relative to the module root")
+	check := flag.Bool("check", false, "only verify that the generated files are up to date")
+	flag.Parse()
+
+	root, err := rootpath.NewRootPathResolver().Resolve(".")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	definitions := envmanager.KeyDefinitions()
+	targets := []target{
+		{path: *envExample, write: envdoc.WriteEnvExample},
+		{path: *markdown, write: envdoc.WriteMarkdown},
+		{path: *jsonSchema, write: writeSchema},
+	}
+
+	stale := false
+	for _, t := range targets {
+		var generated bytes.Buffer
+		if err := t.write(&generated, definitions); err != nil {
+			log.Fatal(err)
+		}
+
+		path := filepath.Join(root, t.path)
+		if *check {
+			current, readErr := os.ReadFile(path)
+			if readErr != nil || !bytes.Equal(current, generated.Bytes()) {
+				fmt.Fprintf(os.Stderr, "%s is out of date, run: go run ./cmd/envdoc\n", t.path)
+				stale = true
+			}
+			continue
+		}
+
+		if mkdirErr := os.MkdirAll(filepath.Dir(path), 0755); mkdirErr != nil {
+			log.Fatal(mkdirErr)
+		}
+		if writeErr := os.WriteFile(path, generated.Bytes(), 0644); writeErr != nil {
+			log.Fatal(writeErr)
+		}
+	}
+
+	if stale {
+		os.Exit(1)
+	}
+}
+
+func writeSchema(w io.Writer, definitions []envmanager.KeyDefinition) diabuddyErrors.ApiErrors {
+	document, err := schema.GenerateFor(definitions).JSON()
+	if err != nil {
+		return err
+	}
+	if _, writeErr := w.Write(document); writeErr != nil {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "failed to write configuration schema", diabuddyErrors.WithInternalError(writeErr))
+	}
+	return nil
+}
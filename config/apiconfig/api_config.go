@@ -1,45 +1,294 @@
 package apiconfig
 
 import (
+	"fmt"
 	"github.com/hbttundar/diabuddy-api-config/config"
 	appconfig "github.com/hbttundar/diabuddy-api-config/config/appconfig"
 	dbconfig "github.com/hbttundar/diabuddy-api-config/config/dbconfig"
 	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
 	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
 )
 
+// ApiConfig holds the sections of a service. App and DB are always present; services register further sections,
+// such as their Kafka or Redis config, with Register or WithSectionBuilder.
 type ApiConfig struct {
-	DB  config.Config
+	DB  dbconfig.Config
 	App config.Config
+	// DBs holds the named connections listed in DB_CONNECTIONS or given with WithDBConnectionOptions.
+	DBs               map[string]dbconfig.Config
+	envManager        *envmanager.EnvManager
+	appConfig         *appconfig.AppConfig
+	envOptions        []envmanager.EnvOption
+	appOptions        []appconfig.AppOption
+	dbOptions         []dbconfig.ConfigOption
+	connectionOptions map[string][]dbconfig.ConfigOption
+	connectionNames   []string
+	pendingSections   []pendingSection
+	validationPolicy  ValidationPolicy
+	profile           *Profile
+	mu                sync.RWMutex
+	sections          map[string]config.Config
+	order             []string
 }
 
-func NewApiConfig(envManager *envmanager.EnvManager) (*ApiConfig, diabuddyErrors.ApiErrors) {
-	appConfig, err := appconfig.NewAppConfig(envManager)
+// NewApiConfig creates the EnvManager, App, DB and any extra sections in one call, e.g.
+// NewApiConfig(WithUseCache(true), WithDBOptions(dbconfig.WithType(dbconfig.Mysql))), and validates them
+// according to the validation policy.
+func NewApiConfig(options ...ApiOption) (*ApiConfig, diabuddyErrors.ApiErrors) {
+	apiConfig := &ApiConfig{
+		DBs:               make(map[string]dbconfig.Config),
+		connectionOptions: make(map[string][]dbconfig.ConfigOption),
+		sections:          make(map[string]config.Config),
+	}
+	for _, option := range options {
+		if err := option(apiConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	envManager := apiConfig.envManager
+	if envManager != nil && len(apiConfig.envOptions) > 0 {
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "WithEnvManager cannot be combined with WithEnvOptions or WithUseCache")
+	}
+	if envManager == nil {
+		envOptions := apiConfig.envOptions
+		if apiConfig.profile != nil {
+			envOptions = append([]envmanager.EnvOption{envmanager.WithExtendedDefaults(apiConfig.profile.ExtendDefaults)}, envOptions...)
+		}
+		var err diabuddyErrors.ApiErrors
+		if envManager, err = envmanager.NewEnvManager(envOptions...); err != nil {
+			return nil, err
+		}
+		apiConfig.envManager = envManager
+	}
+
+	appConfig, err := appconfig.NewAppConfig(envManager, apiConfig.appOptions...)
 	if err != nil {
 		return nil, err
 	}
-	dbConfig, err := dbconfig.NewDBConfig(envManager)
+	dbConfig, err := dbconfig.NewDBConfig(envManager, apiConfig.dbOptions...)
 	if err != nil {
 		return nil, err
 	}
-
-	apiConfig := &ApiConfig{
-		App: appConfig,
-		DB:  dbConfig,
+	apiConfig.App = appConfig
+	apiConfig.appConfig = appConfig
+	apiConfig.DB = dbConfig
+	if err = apiConfig.Register(envmanager.AppSection, appConfig); err != nil {
+		return nil, err
 	}
-	err = apiConfig.Validate()
-	if err != nil {
+	if err = apiConfig.Register(envmanager.DbSection, dbConfig); err != nil {
+		return nil, err
+	}
+	if err = apiConfig.registerConnections(); err != nil {
+		return nil, err
+	}
+	if err = apiConfig.registerProfileSections(); err != nil {
 		return nil, err
 	}
+	for _, pending := range apiConfig.pendingSections {
+		section, err := pending.builder(envManager)
+		if err != nil {
+			return nil, err
+		}
+		if err = apiConfig.Register(pending.name, section); err != nil {
+			return nil, err
+		}
+	}
+
+	switch apiConfig.validationPolicy {
+	case ValidateNone:
+	case ValidateWarn:
+		if err = apiConfig.Validate(); err != nil {
+			log.Printf("apiconfig: configuration is invalid: %s", err.Error())
+		}
+	default:
+		if err = apiConfig.Validate(); err != nil {
+			return nil, err
+		}
+	}
 	return apiConfig, nil
 }
 
+// registerConnections creates the named connections and registers each as a db.<name> section.
+func (ac *ApiConfig) registerConnections() diabuddyErrors.ApiErrors {
+	names := ac.envManager.GetList(envmanager.DbConnectionsKey)
+	for _, name := range ac.connectionNames {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	owners := make(map[string]string, len(names))
+	for _, name := range names {
+		// Names that differ only in case or in - and _ read the same DB_<NAME>_* keys.
+		typeKey := dbconfig.ConnectionKey(name, dbconfig.TypeKeySuffix)
+		if owner, ok := owners[typeKey]; ok {
+			return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("connections %s and %s would both read %s", owner, name, strings.TrimSuffix(typeKey, dbconfig.TypeKeySuffix)+"*"))
+		}
+		owners[typeKey] = name
+		options := append([]dbconfig.ConfigOption{dbconfig.WithConnection(name)}, ac.connectionOptions[name]...)
+		connection, err := dbconfig.NewDBConfig(ac.envManager, options...)
+		if err != nil {
+			return err
+		}
+		if err = ac.Register(envmanager.DbSection+"."+name, connection); err != nil {
+			return err
+		}
+		ac.DBs[name] = connection
+	}
+	return nil
+}
+
+// registerProfileSections creates the sections of the profile that are not given as extra sections.
+func (ac *ApiConfig) registerProfileSections() diabuddyErrors.ApiErrors {
+	if ac.profile == nil {
+		return nil
+	}
+	ac.envManager.Declare(ac.profile.RequiredKeys...)
+	for _, name := range ac.profile.Sections {
+		if slices.ContainsFunc(ac.pendingSections, func(pending pendingSection) bool { return pending.name == name }) {
+			continue
+		}
+		section, err := profileSections[name](ac)
+		if err != nil {
+			return err
+		}
+		if err = ac.Register(name, section); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Profile returns the profile selected with WithProfile.
+func (ac *ApiConfig) Profile() (Profile, bool) {
+	if ac.profile == nil {
+		return Profile{}, false
+	}
+	return ac.profile.clone(), true
+}
+
+// EnvManager returns the EnvManager shared by all sections.
+func (ac *ApiConfig) EnvManager() *envmanager.EnvManager {
+	return ac.envManager
+}
+
+// Register adds a section under a unique name. The app and db names are taken by App and DB.
+func (ac *ApiConfig) Register(name string, cfg config.Config) diabuddyErrors.ApiErrors {
+	if name == "" || cfg == nil {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "a section needs a name and a config")
+	}
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if _, ok := ac.sections[name]; ok {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("section %s is already registered", name))
+	}
+	ac.sections[name] = cfg
+	ac.order = append(ac.order, name)
+	return nil
+}
+
+// Section returns the section registered under the name.
+func (ac *ApiConfig) Section(name string) (config.Config, bool) {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	cfg, ok := ac.sections[name]
+	return cfg, ok
+}
+
+// Sections returns the names of the registered sections in registration order.
+func (ac *ApiConfig) Sections() []string {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	return append([]string(nil), ac.order...)
+}
+
+// SectionAs returns the section registered under the name as its concrete type, e.g.
+// SectionAs[*serverconfig.ServerConfig](apiConfig, "server").
+func SectionAs[T config.Config](ac *ApiConfig, name string) (T, diabuddyErrors.ApiErrors) {
+	var typed T
+	cfg, ok := ac.Section(name)
+	if !ok {
+		return typed, diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("section %s is not registered", name))
+	}
+	typed, ok = cfg.(T)
+	if !ok {
+		return typed, diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("section %s is a %T, not a %T", name, cfg, typed))
+	}
+	return typed, nil
+}
+
+// Validate checks the required keys of the profile and validates every registered section. A single failure
+// is returned as is; several are combined into one error naming each section.
 func (ac *ApiConfig) Validate() diabuddyErrors.ApiErrors {
-	if err := ac.App.Validate(); err != nil {
-		return err
+	var failures []string
+	var firstErr diabuddyErrors.ApiErrors
+	if err := ac.validateProfile(); err != nil {
+		firstErr = err
+		failures = append(failures, fmt.Sprintf("profile %s: %s", ac.profile.Name, err.Error()))
 	}
-	if err := ac.DB.Validate(); err != nil {
-		return err
+	for _, name := range ac.Sections() {
+		cfg, _ := ac.Section(name)
+		if err := cfg.Validate(); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failures = append(failures, fmt.Sprintf("%s: %s", name, err.Error()))
+		}
+	}
+	if len(failures) > 1 {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%d sections are invalid: %s", len(failures), strings.Join(failures, "; ")))
+	}
+	return firstErr
+}
+
+// validateProfile checks that the required keys of the profile have a value.
+func (ac *ApiConfig) validateProfile() diabuddyErrors.ApiErrors {
+	if ac.profile == nil {
+		return nil
+	}
+	var missingKeys []string
+	for _, key := range ac.profile.RequiredKeys {
+		if strings.TrimSpace(ac.envManager.Get(key)) == "" {
+			missingKeys = append(missingKeys, key)
+		}
+	}
+	if len(missingKeys) > 0 {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("missing required key(s): %s", strings.Join(missingKeys, ", ")))
 	}
 	return nil
 }
+
+// Dump returns the snapshot of every registered section that can take one, keyed by section name. Sensitive
+// values are redacted.
+func (ac *ApiConfig) Dump() map[string]map[string]string {
+	dump := make(map[string]map[string]string)
+	for _, name := range ac.Sections() {
+		cfg, _ := ac.Section(name)
+		if snapshotter, ok := cfg.(config.Snapshotter); ok {
+			dump[name] = snapshotter.Snapshot()
+		}
+	}
+	return dump
+}
+
+// Reload re-reads the env file, reloads every section that implements config.Reloader and validates the result.
+func (ac *ApiConfig) Reload() diabuddyErrors.ApiErrors {
+	if err := ac.envManager.Reload(); err != nil {
+		return err
+	}
+	for _, name := range ac.Sections() {
+		cfg, _ := ac.Section(name)
+		if reloader, ok := cfg.(config.Reloader); ok {
+			if err := reloader.Reload(); err != nil {
+				return err
+			}
+		}
+	}
+	return ac.Validate()
+}
@@ -0,0 +1,129 @@
+package apiconfig
+
+import (
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	appconfig "github.com/hbttundar/diabuddy-api-config/config/appconfig"
+	dbconfig "github.com/hbttundar/diabuddy-api-config/config/dbconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"strings"
+)
+
+// ValidationPolicy decides what NewApiConfig does with the result of Validate.
+type ValidationPolicy int
+
+const (
+	// ValidateAll fails NewApiConfig when any section is invalid.
+	ValidateAll ValidationPolicy = iota
+	// ValidateWarn logs validation failures and still returns the ApiConfig.
+	ValidateWarn
+	// ValidateNone skips validation; call Validate once the environment is complete.
+	ValidateNone
+)
+
+// ApiOption Option function type for configuring ApiConfig.
+type ApiOption func(*ApiConfig) diabuddyErrors.ApiErrors
+
+// SectionBuilder creates a section from the EnvManager shared by all sections.
+type SectionBuilder func(envManager *envmanager.EnvManager) (config.Config, diabuddyErrors.ApiErrors)
+
+type pendingSection struct {
+	name    string
+	builder SectionBuilder
+}
+
+// WithEnvManager uses an existing EnvManager instead of creating one. It cannot be combined with
+// WithEnvOptions or WithUseCache.
+func WithEnvManager(envManager *envmanager.EnvManager) ApiOption {
+	return func(ac *ApiConfig) diabuddyErrors.ApiErrors {
+		if envManager == nil {
+			return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "WithEnvManager needs an EnvManager")
+		}
+		ac.envManager = envManager
+		return nil
+	}
+}
+
+// WithEnvOptions passes options to the EnvManager created by NewApiConfig.
+func WithEnvOptions(options ...envmanager.EnvOption) ApiOption {
+	return func(ac *ApiConfig) diabuddyErrors.ApiErrors {
+		ac.envOptions = append(ac.envOptions, options...)
+		return nil
+	}
+}
+
+// WithUseCache enables or disables caching in the EnvManager created by NewApiConfig.
+func WithUseCache(useCache bool) ApiOption {
+	return WithEnvOptions(envmanager.WithUseCache(useCache))
+}
+
+// WithAppOptions passes options to NewAppConfig.
+func WithAppOptions(options ...appconfig.AppOption) ApiOption {
+	return func(ac *ApiConfig) diabuddyErrors.ApiErrors {
+		ac.appOptions = append(ac.appOptions, options...)
+		return nil
+	}
+}
+
+// WithDBOptions passes options such as dbconfig.WithType to NewDBConfig.
+func WithDBOptions(options ...dbconfig.ConfigOption) ApiOption {
+	return func(ac *ApiConfig) diabuddyErrors.ApiErrors {
+		ac.dbOptions = append(ac.dbOptions, options...)
+		return nil
+	}
+}
+
+// WithDBConnectionOptions passes options such as dbconfig.WithType to the named connection. Connections given
+// here are created even when DB_CONNECTIONS does not list them; WithDBOptions only applies to DB.
+func WithDBConnectionOptions(name string, options ...dbconfig.ConfigOption) ApiOption {
+	return func(ac *ApiConfig) diabuddyErrors.ApiErrors {
+		if _, ok := ac.connectionOptions[name]; !ok {
+			ac.connectionNames = append(ac.connectionNames, name)
+		}
+		ac.connectionOptions[name] = append(ac.connectionOptions[name], options...)
+		return nil
+	}
+}
+
+// WithSection registers an already created section after App and DB.
+func WithSection(name string, cfg config.Config) ApiOption {
+	return WithSectionBuilder(name, func(*envmanager.EnvManager) (config.Config, diabuddyErrors.ApiErrors) {
+		return cfg, nil
+	})
+}
+
+// WithSectionBuilder creates a section from the shared EnvManager and registers it after App and DB. Sections
+// are registered in the order of the options.
+func WithSectionBuilder(name string, builder SectionBuilder) ApiOption {
+	return func(ac *ApiConfig) diabuddyErrors.ApiErrors {
+		if builder == nil {
+			return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "WithSectionBuilder needs a builder")
+		}
+		ac.pendingSections = append(ac.pendingSections, pendingSection{name: name, builder: builder})
+		return nil
+	}
+}
+
+// WithValidationPolicy sets what NewApiConfig does with the result of Validate; ValidateAll by default.
+func WithValidationPolicy(policy ValidationPolicy) ApiOption {
+	return func(ac *ApiConfig) diabuddyErrors.ApiErrors {
+		ac.validationPolicy = policy
+		return nil
+	}
+}
+
+// WithProfile selects a registered profile, e.g. WithProfile(AuthApiProfile). NewApiConfig creates the sections
+// of the profile after DB, unless a section of the same name is given with WithSection or WithSectionBuilder,
+// and Validate checks its required keys. The defaults of the profile only apply to the EnvManager created by
+// NewApiConfig.
+func WithProfile(name string) ApiOption {
+	return func(ac *ApiConfig) diabuddyErrors.ApiErrors {
+		profile, ok := LookupProfile(name)
+		if !ok {
+			return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("profile %s is not registered; expected one of %s", name, strings.Join(Profiles(), ", ")))
+		}
+		ac.profile = &profile
+		return nil
+	}
+}
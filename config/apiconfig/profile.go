@@ -0,0 +1,179 @@
+package apiconfig
+
+import (
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/authconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/cacheconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/elasticsearchconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"github.com/hbttundar/diabuddy-api-config/config/kafkaconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/logconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/mailconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/observabilityconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/securityconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/serverconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/storageconfig"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Names of the profiles of the Diabuddy services.
+const (
+	UserApiProfile = "user_api"
+	AuthApiProfile = "auth_api"
+	FoodApiProfile = "food_api"
+)
+
+// Profile declares what a service needs on top of App and DB: the sections it uses, the keys that must be set
+// although no section requires them, and defaults that differ from the registered ones.
+type Profile struct {
+	Name string
+	// Sections names the sections NewApiConfig creates, in order; see ProfileSections for the accepted names.
+	Sections     []string
+	RequiredKeys []string
+	// Defaults replace the registered defaults; values from the environment and the env file still win.
+	Defaults map[string]string
+}
+
+// ExtendDefaults copies the defaults of the profile into the defaults of an EnvManager. Pass it to
+// envmanager.WithExtendedDefaults when the EnvManager is given with WithEnvManager.
+func (p Profile) ExtendDefaults(defaults map[string]string) {
+	for key, value := range p.Defaults {
+		defaults[key] = value
+	}
+}
+
+func (p Profile) clone() Profile {
+	p.Sections = slices.Clone(p.Sections)
+	p.RequiredKeys = slices.Clone(p.RequiredKeys)
+	p.Defaults = maps.Clone(p.Defaults)
+	return p
+}
+
+// profileSectionBuilder creates a section of a profile once App and DB exist.
+type profileSectionBuilder func(ac *ApiConfig) (config.Config, diabuddyErrors.ApiErrors)
+
+// profileSections builds the sections a profile can name, keyed by the section name they are registered under.
+var profileSections = map[string]profileSectionBuilder{
+	envmanager.AuthSection: func(ac *ApiConfig) (config.Config, diabuddyErrors.ApiErrors) {
+		return authconfig.NewAuthConfig(ac.envManager)
+	},
+	envmanager.CacheSection: func(ac *ApiConfig) (config.Config, diabuddyErrors.ApiErrors) {
+		return cacheconfig.NewCacheConfig(ac.envManager)
+	},
+	envmanager.ElasticsearchSection: func(ac *ApiConfig) (config.Config, diabuddyErrors.ApiErrors) {
+		return elasticsearchconfig.NewElasticsearchConfig(ac.envManager)
+	},
+	envmanager.KafkaSection: func(ac *ApiConfig) (config.Config, diabuddyErrors.ApiErrors) {
+		return kafkaconfig.NewKafkaConfig(ac.envManager)
+	},
+	envmanager.LogSection: func(ac *ApiConfig) (config.Config, diabuddyErrors.ApiErrors) {
+		return logconfig.NewLogConfig(ac.envManager)
+	},
+	envmanager.MailSection: func(ac *ApiConfig) (config.Config, diabuddyErrors.ApiErrors) {
+		return mailconfig.NewMailConfig(ac.envManager)
+	},
+	envmanager.ObservabilitySection: func(ac *ApiConfig) (config.Config, diabuddyErrors.ApiErrors) {
+		return observabilityconfig.NewObservabilityConfig(ac.envManager)
+	},
+	envmanager.SecuritySection: func(ac *ApiConfig) (config.Config, diabuddyErrors.ApiErrors) {
+		return securityconfig.NewSecurityConfig(ac.envManager)
+	},
+	envmanager.ServerSection: func(ac *ApiConfig) (config.Config, diabuddyErrors.ApiErrors) {
+		return serverconfig.NewServerConfig(ac.envManager)
+	},
+	envmanager.StorageSection: func(ac *ApiConfig) (config.Config, diabuddyErrors.ApiErrors) {
+		// Local disks are rooted in the storage path of App, so they honour WithAppOptions.
+		return storageconfig.NewStorageConfig(ac.envManager, storageconfig.WithAppConfig(ac.appConfig))
+	},
+}
+
+var (
+	profilesMu sync.RWMutex
+	profiles   = make(map[string]Profile)
+)
+
+func init() {
+	for _, profile := range builtinProfiles() {
+		if err := RegisterProfile(profile); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+// builtinProfiles declares the Diabuddy services. Every service listens behind the same gateway, so each gets
+// its own default port. Only auth_api signs tokens and encrypts values, so only it needs the auth section,
+// AUTH_SECRET and APP_KEY; the other services add the auth section themselves once they verify tokens.
+func builtinProfiles() []Profile {
+	return []Profile{
+		{
+			Name:     UserApiProfile,
+			Sections: []string{envmanager.ServerSection, envmanager.LogSection, envmanager.SecuritySection, envmanager.MailSection, envmanager.ObservabilitySection},
+			Defaults: map[string]string{envmanager.AppNameKey: "diabuddy-user-api", envmanager.ServerPortKey: "8080"},
+		},
+		{
+			Name:         AuthApiProfile,
+			Sections:     []string{envmanager.ServerSection, envmanager.LogSection, envmanager.SecuritySection, envmanager.AuthSection, envmanager.CacheSection, envmanager.ObservabilitySection},
+			RequiredKeys: []string{envmanager.AuthSecretKey, envmanager.AppEncryptionKey},
+			Defaults:     map[string]string{envmanager.AppNameKey: "diabuddy-auth-api", envmanager.ServerPortKey: "8081"},
+		},
+		{
+			Name:     FoodApiProfile,
+			Sections: []string{envmanager.ServerSection, envmanager.LogSection, envmanager.SecuritySection, envmanager.ElasticsearchSection, envmanager.ObservabilitySection},
+			Defaults: map[string]string{envmanager.AppNameKey: "diabuddy-food-api", envmanager.ServerPortKey: "8082"},
+		},
+	}
+}
+
+// RegisterProfile adds a profile, or replaces the profile with the same name. Every section it names must be
+// one of ProfileSections.
+func RegisterProfile(profile Profile) diabuddyErrors.ApiErrors {
+	if profile.Name == "" {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "a profile needs a name")
+	}
+	for _, section := range profile.Sections {
+		if _, ok := profileSections[section]; !ok {
+			return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("profile %s names unknown section %s; expected one of %s", profile.Name, section, strings.Join(ProfileSections(), ", ")))
+		}
+	}
+
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profiles[profile.Name] = profile.clone()
+	return nil
+}
+
+// LookupProfile returns the profile registered under the name.
+func LookupProfile(name string) (Profile, bool) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	profile, ok := profiles[name]
+	return profile.clone(), ok
+}
+
+// Profiles returns the sorted names of the registered profiles.
+func Profiles() []string {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileSections returns the sorted names of the sections a profile can name.
+func ProfileSections() []string {
+	names := make([]string, 0, len(profileSections))
+	for name := range profileSections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
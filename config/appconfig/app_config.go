@@ -1,22 +1,79 @@
 package appconfig
 
 import (
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/appconfig/encrypter"
 	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"github.com/hbttundar/diabuddy-api-config/util/envfile"
 	"github.com/hbttundar/diabuddy-api-config/util/resolver/rootpath"
 	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
-	"path/filepath"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var requiredKeys = []string{envmanager.AppNameKey, envmanager.AppEnvKey, envmanager.AppUrlKey, envmanager.AppDebugKey}
+
+var (
+	_ config.Introspectable = (*AppConfig)(nil)
+	_ config.Reloader       = (*AppConfig)(nil)
 )
 
 type AppConfig struct {
-	envManager   *envmanager.EnvManager
-	pathResolver *rootpath.RootPathResolver
+	envManager    *envmanager.EnvManager
+	pathResolver  *rootpath.RootPathResolver
+	encrypter     *encrypter.Encrypter
+	encrypterErr  diabuddyErrors.ApiErrors
+	encrypterOnce sync.Once
+	basePath      string
+	ensurePaths   bool
+	// state holds the parsed values; Reload replaces it as a whole, so readers never see half a reload.
+	state atomic.Pointer[appState]
+}
+
+type appState struct {
+	name           string
+	env            envmanager.Environment
+	debug          bool
+	url            *url.URL
+	location       *time.Location
+	locale         string
+	fallbackLocale string
+	cipher         string
+}
+
+type AppOption func(*AppConfig) diabuddyErrors.ApiErrors
+
+// WithEnsurePaths makes the path helpers create missing directories and check that the storage and tmp
+// directories are writable
+func WithEnsurePaths(ensurePaths bool) AppOption {
+	return func(ac *AppConfig) diabuddyErrors.ApiErrors {
+		ac.ensurePaths = ensurePaths
+		return nil
+	}
 }
 
-func NewAppConfig(envManager *envmanager.EnvManager) (*AppConfig, diabuddyErrors.ApiErrors) {
+// NewAppConfig creates an AppConfig and resolves the application root. Malformed APP_* values fail here.
+func NewAppConfig(envManager *envmanager.EnvManager, options ...AppOption) (*AppConfig, diabuddyErrors.ApiErrors) {
 	ac := &AppConfig{
 		envManager:   envManager,
 		pathResolver: rootpath.NewRootPathResolver(),
 	}
+	for _, option := range options {
+		if err := option(ac); err != nil {
+			return nil, err
+		}
+	}
+	state, err := ac.resolve()
+	if err != nil {
+		return nil, err
+	}
+	ac.state.Store(state)
+	if err := ac.resolveBasePath(); err != nil {
+		return nil, err
+	}
 	return ac, nil
 }
 
@@ -24,20 +81,179 @@ func (ac *AppConfig) Get(key string, defaultValue ...string) string {
 	return ac.envManager.Get(key, defaultValue...)
 }
 
-func (ac *AppConfig) BasePath() (string, diabuddyErrors.ApiErrors) {
-	basePath, err := filepath.Abs("../")
+// Name returns APP_NAME.
+func (ac *AppConfig) Name() string {
+	return ac.state.Load().name
+}
+
+// Env returns the environment resolved by the EnvManager, which also decides APP_ENV.
+func (ac *AppConfig) Env() envmanager.Environment {
+	return ac.state.Load().env
+}
+
+// Debug returns APP_DEBUG; an empty value means false.
+func (ac *AppConfig) Debug() bool {
+	return ac.state.Load().debug
+}
+
+// URL returns a copy of the parsed APP_URL.
+func (ac *AppConfig) URL() *url.URL {
+	u := *ac.state.Load().url
+	return &u
+}
+
+// Location returns the time zone loaded from APP_TIMEZONE; an empty value means UTC.
+func (ac *AppConfig) Location() *time.Location {
+	return ac.state.Load().location
+}
+
+// Locale returns APP_LOCALE.
+func (ac *AppConfig) Locale() string {
+	return ac.state.Load().locale
+}
+
+// FallbackLocale returns APP_FALLBACK_LOCALE.
+func (ac *AppConfig) FallbackLocale() string {
+	return ac.state.Load().fallbackLocale
+}
+
+// Cipher returns APP_CIPHER; an empty value means AES-256-CBC.
+func (ac *AppConfig) Cipher() string {
+	return ac.state.Load().cipher
+}
+
+// Encrypter returns the Encrypter for APP_KEY and APP_CIPHER that also decrypts with APP_PREVIOUS_KEYS. It is
+// created on first use, so services that never encrypt anything can be created without an APP_KEY.
+func (ac *AppConfig) Encrypter() (*encrypter.Encrypter, diabuddyErrors.ApiErrors) {
+	ac.encrypterOnce.Do(func() {
+		if ac.encrypterErr = ac.validateKeys(); ac.encrypterErr != nil {
+			return
+		}
+		ac.encrypter, ac.encrypterErr = encrypter.NewFromString(ac.Get(envmanager.AppEncryptionKey), encrypter.Cipher(ac.state.Load().cipher), encrypter.WithPreviousKeys(ac.envManager.GetList(envmanager.AppPreviousKeysKey)...))
+	})
+	return ac.encrypter, ac.encrypterErr
+}
+
+// GenerateKey generates a key for APP_CIPHER and writes it as APP_KEY into the given .env file, keeping its
+// comments and layout. The running AppConfig keeps using its current key.
+func (ac *AppConfig) GenerateKey(envFile string) (string, diabuddyErrors.ApiErrors) {
+	key, err := encrypter.GenerateKey(encrypter.Cipher(ac.state.Load().cipher))
+	if err != nil {
+		return "", err
+	}
+	if err := envfile.Set(envFile, envmanager.AppEncryptionKey, key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// validateKeys checks the length and encoding of APP_KEY and APP_PREVIOUS_KEYS for APP_CIPHER.
+func (ac *AppConfig) validateKeys() diabuddyErrors.ApiErrors {
+	state := ac.state.Load()
+	key := ac.Get(envmanager.AppEncryptionKey)
+	if key == "" {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, envmanager.AppEncryptionKey+" is required")
+	}
+	if err := encrypter.ValidateKey(key, encrypter.Cipher(state.cipher)); err != nil {
+		return invalidKeyError(envmanager.AppEncryptionKey, state.cipher, err)
+	}
+	for _, previousKey := range ac.envManager.GetList(envmanager.AppPreviousKeysKey) {
+		if err := encrypter.ValidateKey(previousKey, encrypter.Cipher(state.cipher)); err != nil {
+			return invalidKeyError(envmanager.AppPreviousKeysKey, state.cipher, err)
+		}
+	}
+	return nil
+}
+
+// Reload re-reads the typed app values and swaps them in at once, so it may run while the section is read. On
+// error the previous values are kept. The encrypter keeps the keys it was created with.
+func (ac *AppConfig) Reload() diabuddyErrors.ApiErrors {
+	state, err := ac.resolve()
+	if err != nil {
+		return err
+	}
+	ac.state.Store(state)
+	return nil
+}
+
+// resolve reads and parses every typed app value.
+func (ac *AppConfig) resolve() (*appState, diabuddyErrors.ApiErrors) {
+	state := &appState{}
+	debug, apiErr := config.Bool(ac, envmanager.AppDebugKey)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	rawURL := ac.Get(envmanager.AppUrlKey)
+	appURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, config.InvalidValueError(envmanager.AppUrlKey, rawURL, "a URL", err)
+	}
+
+	timezone := ac.Get(envmanager.AppTimezoneKey)
+	location, err := time.LoadLocation(timezone)
 	if err != nil {
-		return "", diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "could not find appconfig root directory", diabuddyErrors.WithInternalError(err))
+		return nil, config.InvalidValueError(envmanager.AppTimezoneKey, timezone, "an IANA time zone", err)
+	}
+
+	cipher := ac.Get(envmanager.AppCipherKey, string(encrypter.AES256CBC))
+	if !encrypter.Cipher(cipher).IsSupported() {
+		return nil, config.InvalidValueError(envmanager.AppCipherKey, cipher, "AES-256-CBC or AES-256-GCM", nil)
 	}
-	return ac.pathResolver.Resolve(basePath)
+
+	state.name = ac.Get(envmanager.AppNameKey)
+	state.env = ac.envManager.Environment()
+	state.debug = debug
+	state.url = appURL
+	state.location = location
+	state.locale = ac.Get(envmanager.AppLocaleKey)
+	state.fallbackLocale = ac.Get(envmanager.AppFallbackLocaleKey)
+	state.cipher = cipher
+	return state, nil
+}
+
+// invalidKeyError never includes the key itself, so secrets do not end up in logs.
+func invalidKeyError(key, cipher string, err error) diabuddyErrors.ApiErrors {
+	return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s must hold %d byte keys for %s, optionally prefixed with %s", key, encrypter.Cipher(cipher).KeySize(), cipher, encrypter.KeyPrefix), diabuddyErrors.WithInternalError(err))
+}
+
+// Keys returns the keys owned by the app section.
+func (ac *AppConfig) Keys() []string {
+	return config.KeyNames(ac.Describe())
+}
+
+// RequiredKeys returns the keys Validate insists on.
+func (ac *AppConfig) RequiredKeys() []string {
+	return append([]string(nil), requiredKeys...)
+}
+
+// Describe returns the definitions of the keys owned by the app section.
+func (ac *AppConfig) Describe() []envmanager.KeyDefinition {
+	return envmanager.SectionKeyDefinitions(envmanager.AppSection)
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults.
+func (ac *AppConfig) Lookup(key string) (string, bool) {
+	value, found, _ := ac.envManager.Lookup(key)
+	return value, found
+}
+
+// Snapshot returns the current values of the app section with sensitive values redacted.
+func (ac *AppConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(ac, ac.Describe())
 }
 
 func (ac *AppConfig) Validate() diabuddyErrors.ApiErrors {
-	requiredKeys := []string{envmanager.AppNameKey, envmanager.AppEnvKey, envmanager.AppUrlKey, envmanager.AppDebugKey}
 	for _, key := range requiredKeys {
 		if ac.Get(key) == "" {
 			return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, key+" is required")
 		}
 	}
-	return nil
+	// Local and test may leave APP_KEY empty until Encrypter is called; everywhere else it is required. A key that
+	// is set must be usable.
+	environment := ac.Env()
+	if (environment.IsLocal() || environment.IsTest()) && ac.Get(envmanager.AppEncryptionKey) == "" && len(ac.envManager.GetList(envmanager.AppPreviousKeysKey)) == 0 {
+		return nil
+	}
+	return ac.validateKeys()
 }
@@ -0,0 +1,360 @@
+package encrypter
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"strings"
+)
+
+// Cipher names accepted in APP_CIPHER.
+type Cipher string
+
+const (
+	AES256CBC Cipher = "AES-256-CBC"
+	AES256GCM Cipher = "AES-256-GCM"
+)
+
+// KeyPrefix marks a base64 encoded key, e.g. APP_KEY=base64:...
+const KeyPrefix = "base64:"
+
+// KeySize is the key length in bytes required by both ciphers.
+const KeySize = 32
+
+// PayloadVersion is written as the first byte of every payload, so the format can change later without
+// breaking existing ciphertexts.
+const PayloadVersion byte = 1
+
+// Cipher ids written as the second byte of every payload.
+const (
+	cbcID byte = 1
+	gcmID byte = 2
+)
+
+const headerSize = 2
+
+// Ciphers returns the supported cipher names.
+func Ciphers() []Cipher {
+	return []Cipher{AES256CBC, AES256GCM}
+}
+
+// KeySize returns the key length in bytes required by the cipher.
+func (c Cipher) KeySize() int {
+	return KeySize
+}
+
+// IsSupported reports whether the cipher can be used by an Encrypter.
+func (c Cipher) IsSupported() bool {
+	return c == AES256CBC || c == AES256GCM
+}
+
+// Encrypter encrypts and authenticates values with the current key. Payloads are laid out as
+// version | cipher id | cipher specific body:
+//   - AES-256-CBC: iv | ciphertext | HMAC-SHA256 over everything before the MAC
+//   - AES-256-GCM: nonce | ciphertext and tag, with the header as additional data
+//
+// Decrypt reads the cipher id from the payload, so values written with either cipher stay readable after
+// APP_CIPHER changes. Previous keys are only used by Decrypt, so values written before a key rotation stay
+// readable.
+type Encrypter struct {
+	cipher       Cipher
+	key          []byte
+	current      keySet
+	previousKeys []keySet
+}
+
+type keySet struct {
+	encKey  []byte
+	authKey []byte
+}
+
+type Option func(*Encrypter) diabuddyErrors.ApiErrors
+
+// WithPreviousKeys adds retired keys, written like APP_KEY, that Decrypt falls back to in the given order
+func WithPreviousKeys(keys ...string) Option {
+	return func(e *Encrypter) diabuddyErrors.ApiErrors {
+		for _, key := range keys {
+			rawKey, err := ParseKey(key)
+			if err != nil {
+				return err
+			}
+			if err := validateKeySize(rawKey, e.cipher); err != nil {
+				return err
+			}
+			e.previousKeys = append(e.previousKeys, newKeySet(rawKey))
+		}
+		return nil
+	}
+}
+
+// New creates an Encrypter for a raw 32 byte key.
+func New(key []byte, cipher Cipher, options ...Option) (*Encrypter, diabuddyErrors.ApiErrors) {
+	if !cipher.IsSupported() {
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("unsupported cipher %q", cipher))
+	}
+	if err := validateKeySize(key, cipher); err != nil {
+		return nil, err
+	}
+	e := &Encrypter{
+		cipher:  cipher,
+		key:     append([]byte(nil), key...),
+		current: newKeySet(key),
+	}
+	for _, option := range options {
+		if err := option(e); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// NewFromString creates an Encrypter for a key as written in APP_KEY; see ParseKey.
+func NewFromString(key string, cipher Cipher, options ...Option) (*Encrypter, diabuddyErrors.ApiErrors) {
+	rawKey, err := ParseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return New(rawKey, cipher, options...)
+}
+
+// GenerateKey returns a random key of the right size for the cipher, written like APP_KEY with the base64:
+// prefix.
+func GenerateKey(cipher Cipher) (string, diabuddyErrors.ApiErrors) {
+	if !cipher.IsSupported() {
+		return "", diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("unsupported cipher %q", cipher))
+	}
+	key := make([]byte, cipher.KeySize())
+	if _, err := rand.Read(key); err != nil {
+		return "", diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "could not generate a key", diabuddyErrors.WithInternalError(err))
+	}
+	return KeyPrefix + base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ValidateKey checks that a key written like APP_KEY decodes and has the right size for the cipher.
+func ValidateKey(key string, cipher Cipher) diabuddyErrors.ApiErrors {
+	if !cipher.IsSupported() {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("unsupported cipher %q", cipher))
+	}
+	rawKey, err := ParseKey(key)
+	if err != nil {
+		return err
+	}
+	return validateKeySize(rawKey, cipher)
+}
+
+func validateKeySize(key []byte, cipher Cipher) diabuddyErrors.ApiErrors {
+	if len(key) != cipher.KeySize() {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s requires a %d byte key, got %d bytes", cipher, cipher.KeySize(), len(key)))
+	}
+	return nil
+}
+
+// ParseKey decodes a key with the base64: prefix and returns any other key as raw bytes.
+func ParseKey(key string) ([]byte, diabuddyErrors.ApiErrors) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return []byte(key), nil
+	}
+	rawKey, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(key, KeyPrefix))
+	if err != nil {
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, "key is not valid base64", diabuddyErrors.WithInternalError(err))
+	}
+	return rawKey, nil
+}
+
+// Cipher returns the cipher used by Encrypt.
+func (e *Encrypter) Cipher() Cipher {
+	return e.cipher
+}
+
+// Key returns a copy of the raw key.
+func (e *Encrypter) Key() []byte {
+	return append([]byte(nil), e.key...)
+}
+
+// Encrypt encrypts and authenticates the plaintext.
+func (e *Encrypter) Encrypt(plaintext []byte) ([]byte, diabuddyErrors.ApiErrors) {
+	var (
+		payload []byte
+		err     error
+	)
+	if e.cipher == AES256GCM {
+		payload, err = e.encryptGCM(plaintext)
+	} else {
+		payload, err = e.encryptCBC(plaintext)
+	}
+	if err != nil {
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "could not encrypt the value", diabuddyErrors.WithInternalError(err))
+	}
+	return payload, nil
+}
+
+// Decrypt verifies and decrypts a payload written by Encrypt with either cipher, trying the current key first
+// and then the previous keys.
+func (e *Encrypter) Decrypt(payload []byte) ([]byte, diabuddyErrors.ApiErrors) {
+	if len(payload) < headerSize || payload[0] != PayloadVersion {
+		return nil, invalidPayloadError(nil)
+	}
+
+	var decrypt func(keySet, []byte) ([]byte, error)
+	switch payload[1] {
+	case cbcID:
+		decrypt = decryptCBC
+	case gcmID:
+		decrypt = decryptGCM
+	default:
+		return nil, invalidPayloadError(nil)
+	}
+
+	plaintext, err := decrypt(e.current, payload)
+	for _, keys := range e.previousKeys {
+		if err == nil {
+			break
+		}
+		plaintext, err = decrypt(keys, payload)
+	}
+	if err != nil {
+		return nil, invalidPayloadError(err)
+	}
+	return plaintext, nil
+}
+
+// EncryptString encrypts a string and returns the payload base64 encoded.
+func (e *Encrypter) EncryptString(plaintext string) (string, diabuddyErrors.ApiErrors) {
+	payload, err := e.Encrypt([]byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(payload), nil
+}
+
+// DecryptString decrypts a base64 encoded payload written by EncryptString.
+func (e *Encrypter) DecryptString(payload string) (string, diabuddyErrors.ApiErrors) {
+	rawPayload, decodeErr := base64.StdEncoding.DecodeString(payload)
+	if decodeErr != nil {
+		return "", invalidPayloadError(decodeErr)
+	}
+	plaintext, err := e.Decrypt(rawPayload)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func (e *Encrypter) encryptCBC(plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(e.current.encKey)
+	if err != nil {
+		return nil, err
+	}
+	padded := pad(plaintext, aes.BlockSize)
+
+	payload := make([]byte, headerSize+aes.BlockSize+len(padded), headerSize+aes.BlockSize+len(padded)+sha256.Size)
+	payload[0], payload[1] = PayloadVersion, cbcID
+	iv := payload[headerSize : headerSize+aes.BlockSize]
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(payload[headerSize+aes.BlockSize:], padded)
+
+	return append(payload, e.current.mac(payload)...), nil
+}
+
+func decryptCBC(keys keySet, payload []byte) ([]byte, error) {
+	if len(payload) < headerSize+2*aes.BlockSize+sha256.Size {
+		return nil, fmt.Errorf("payload is too short")
+	}
+	body, tag := payload[:len(payload)-sha256.Size], payload[len(payload)-sha256.Size:]
+	if !hmac.Equal(tag, keys.mac(body)) {
+		return nil, fmt.Errorf("MAC is invalid")
+	}
+
+	ciphertext := body[headerSize+aes.BlockSize:]
+	if len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("ciphertext is not a multiple of the block size")
+	}
+	block, err := aes.NewCipher(keys.encKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, body[headerSize:headerSize+aes.BlockSize]).CryptBlocks(plaintext, ciphertext)
+	return unpad(plaintext, aes.BlockSize)
+}
+
+func (e *Encrypter) encryptGCM(plaintext []byte) ([]byte, error) {
+	aead, err := e.current.gcm()
+	if err != nil {
+		return nil, err
+	}
+	payload := make([]byte, headerSize+aead.NonceSize(), headerSize+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	payload[0], payload[1] = PayloadVersion, gcmID
+	if _, err := rand.Read(payload[headerSize:]); err != nil {
+		return nil, err
+	}
+	return aead.Seal(payload, payload[headerSize:], plaintext, payload[:headerSize]), nil
+}
+
+func decryptGCM(keys keySet, payload []byte) ([]byte, error) {
+	aead, err := keys.gcm()
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) < headerSize+aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("payload is too short")
+	}
+	nonce := payload[headerSize : headerSize+aead.NonceSize()]
+	return aead.Open(nil, nonce, payload[headerSize+aead.NonceSize():], payload[:headerSize])
+}
+
+// newKeySet derives independent encryption and authentication keys, so the same bytes are never used for both.
+func newKeySet(key []byte) keySet {
+	return keySet{encKey: deriveKey(key, "encryption"), authKey: deriveKey(key, "authentication")}
+}
+
+func (k keySet) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.encKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (k keySet) mac(data []byte) []byte {
+	h := hmac.New(sha256.New, k.authKey)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func deriveKey(key []byte, purpose string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(purpose))
+	return h.Sum(nil)
+}
+
+func pad(data []byte, blockSize int) []byte {
+	padding := blockSize - len(data)%blockSize
+	return append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+}
+
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("plaintext is empty")
+	}
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > blockSize || padding > len(data) {
+		return nil, fmt.Errorf("padding is invalid")
+	}
+	return data[:len(data)-padding], nil
+}
+
+func invalidPayloadError(err error) diabuddyErrors.ApiErrors {
+	if err == nil {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, "the payload is invalid")
+	}
+	return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, "the payload is invalid", diabuddyErrors.WithInternalError(err))
+}
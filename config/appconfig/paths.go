@@ -0,0 +1,121 @@
+package appconfig
+
+import (
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"os"
+	"path/filepath"
+)
+
+// Directories below the base path.
+const (
+	ConfigDir   = "config"
+	StorageDir  = "storage"
+	ResourceDir = "resources"
+	TempDir     = "tmp"
+)
+
+// BasePath returns the application root joined with the given elements. The root is APP_BASE_PATH if set,
+// otherwise the module root (the nearest directory with a go.mod, searched from the working directory and
+// then from the executable), otherwise the directory of the executable.
+func (ac *AppConfig) BasePath(elem ...string) (string, diabuddyErrors.ApiErrors) {
+	return ac.path(ac.basePath, false, elem)
+}
+
+// ConfigPath returns the config directory below the base path joined with the given elements.
+func (ac *AppConfig) ConfigPath(elem ...string) (string, diabuddyErrors.ApiErrors) {
+	return ac.path(filepath.Join(ac.basePath, ConfigDir), false, elem)
+}
+
+// StoragePath returns the storage directory below the base path joined with the given elements.
+func (ac *AppConfig) StoragePath(elem ...string) (string, diabuddyErrors.ApiErrors) {
+	return ac.path(filepath.Join(ac.basePath, StorageDir), true, elem)
+}
+
+// ResourcePath returns the resources directory below the base path joined with the given elements.
+func (ac *AppConfig) ResourcePath(elem ...string) (string, diabuddyErrors.ApiErrors) {
+	return ac.path(filepath.Join(ac.basePath, ResourceDir), false, elem)
+}
+
+// TempPath returns the tmp directory below the storage path joined with the given elements.
+func (ac *AppConfig) TempPath(elem ...string) (string, diabuddyErrors.ApiErrors) {
+	return ac.path(filepath.Join(ac.basePath, StorageDir, TempDir), true, elem)
+}
+
+// path joins the elements to dir. With WithEnsurePaths, dir is created when missing and, if it has to be
+// writable, checked by creating and removing a file in it.
+func (ac *AppConfig) path(dir string, writable bool, elem []string) (string, diabuddyErrors.ApiErrors) {
+	if ac.ensurePaths {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("could not create directory %s", dir), diabuddyErrors.WithInternalError(err))
+		}
+		if writable {
+			if err := checkWritable(dir); err != nil {
+				return "", diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("directory %s is not writable", dir), diabuddyErrors.WithInternalError(err))
+			}
+		}
+	}
+	return filepath.Join(append([]string{dir}, elem...)...), nil
+}
+
+func checkWritable(dir string) error {
+	file, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return err
+	}
+	name := file.Name()
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// resolveBasePath resolves the application root once, so that it does not depend on where later calls run.
+func (ac *AppConfig) resolveBasePath() diabuddyErrors.ApiErrors {
+	if override := ac.Get(envmanager.AppBasePathKey); override != "" {
+		basePath, err := filepath.Abs(override)
+		if err != nil {
+			return config.InvalidValueError(envmanager.AppBasePathKey, override, "a valid path", err)
+		}
+		info, err := os.Stat(basePath)
+		if err != nil || !info.IsDir() {
+			return config.InvalidValueError(envmanager.AppBasePathKey, override, "an existing directory", err)
+		}
+		ac.basePath = basePath
+		return nil
+	}
+
+	var candidates []string
+	if workingDir, err := os.Getwd(); err == nil {
+		candidates = append(candidates, workingDir)
+	}
+	executableDir, executableErr := executableDir()
+	if executableErr == nil {
+		candidates = append(candidates, executableDir)
+	}
+	for _, candidate := range candidates {
+		if moduleRoot, err := ac.pathResolver.Resolve(candidate); err == nil {
+			ac.basePath = moduleRoot
+			return nil
+		}
+	}
+
+	if executableErr != nil {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "could not find appconfig root directory", diabuddyErrors.WithInternalError(executableErr))
+	}
+	ac.basePath = executableDir
+	return nil
+}
+
+func executableDir() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+	return filepath.Dir(executable), nil
+}
@@ -0,0 +1,342 @@
+package authconfig
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var requiredKeys = []string{envmanager.AuthAlgorithmKey}
+
+var (
+	_ config.Introspectable = (*AuthConfig)(nil)
+	_ config.Reloader       = (*AuthConfig)(nil)
+)
+
+type AuthConfig struct {
+	envManager *envmanager.EnvManager
+	// state holds the parsed values; Reload replaces it as a whole, so readers never see half a reload.
+	state atomic.Pointer[authState]
+}
+
+type authState struct {
+	algorithm       Algorithm
+	issuer          string
+	audience        []string
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	clockSkew       time.Duration
+	currentKey      *Key
+	previousKeys    []*Key
+}
+
+// NewAuthConfig creates an AuthConfig and loads the key material once. Malformed or weak keys fail here;
+// missing keys are reported by Validate, so services can be created before their keys are provisioned.
+func NewAuthConfig(envManager *envmanager.EnvManager) (*AuthConfig, diabuddyErrors.ApiErrors) {
+	ac := &AuthConfig{envManager: envManager}
+	state, err := ac.resolve()
+	if err != nil {
+		return nil, err
+	}
+	ac.state.Store(state)
+	return ac, nil
+}
+
+func (ac *AuthConfig) Get(key string, defaultValue ...string) string {
+	return ac.envManager.Get(key, defaultValue...)
+}
+
+// Algorithm returns AUTH_ALGORITHM.
+func (ac *AuthConfig) Algorithm() Algorithm {
+	return ac.state.Load().algorithm
+}
+
+// Issuer returns AUTH_ISSUER.
+func (ac *AuthConfig) Issuer() string {
+	return ac.state.Load().issuer
+}
+
+// Audience returns AUTH_AUDIENCE.
+func (ac *AuthConfig) Audience() []string {
+	return append([]string(nil), ac.state.Load().audience...)
+}
+
+// AccessTokenTTL returns AUTH_ACCESS_TOKEN_TTL.
+func (ac *AuthConfig) AccessTokenTTL() time.Duration {
+	return ac.state.Load().accessTokenTTL
+}
+
+// RefreshTokenTTL returns AUTH_REFRESH_TOKEN_TTL.
+func (ac *AuthConfig) RefreshTokenTTL() time.Duration {
+	return ac.state.Load().refreshTokenTTL
+}
+
+// ClockSkew returns AUTH_CLOCK_SKEW.
+func (ac *AuthConfig) ClockSkew() time.Duration {
+	return ac.state.Load().clockSkew
+}
+
+// SigningKey returns the current key, or nil if none is configured. Services that only verify tokens get a key
+// whose CanSign reports false.
+func (ac *AuthConfig) SigningKey() *Key {
+	return ac.state.Load().currentKey
+}
+
+// VerificationKeys returns the current key followed by the keys of AUTH_PREVIOUS_KEYS.
+func (ac *AuthConfig) VerificationKeys() []*Key {
+	state := ac.state.Load()
+	var keys []*Key
+	if state.currentKey != nil {
+		keys = append(keys, state.currentKey)
+	}
+	return append(keys, state.previousKeys...)
+}
+
+// VerificationKey returns the key with the given ID, e.g. the kid header of a token.
+func (ac *AuthConfig) VerificationKey(id string) (*Key, bool) {
+	for _, key := range ac.VerificationKeys() {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// Reload re-reads the auth values and key material and swaps them in at once, so it may run while the section is
+// read. On error the previous values are kept.
+func (ac *AuthConfig) Reload() diabuddyErrors.ApiErrors {
+	state, err := ac.resolve()
+	if err != nil {
+		return err
+	}
+	ac.state.Store(state)
+	return nil
+}
+
+// resolve reads and parses every auth value.
+func (ac *AuthConfig) resolve() (*authState, diabuddyErrors.ApiErrors) {
+	state := &authState{}
+	algorithm := Algorithm(ac.Get(envmanager.AuthAlgorithmKey, string(HS256)))
+	if !algorithm.IsSupported() {
+		return nil, config.InvalidValueError(envmanager.AuthAlgorithmKey, string(algorithm), "HS256, RS256 or EdDSA", nil)
+	}
+	state.algorithm = algorithm
+
+	durations := []struct {
+		key    string
+		target *time.Duration
+	}{
+		{envmanager.AuthAccessTokenTTLKey, &state.accessTokenTTL},
+		{envmanager.AuthRefreshTokenTTLKey, &state.refreshTokenTTL},
+		{envmanager.AuthClockSkewKey, &state.clockSkew},
+	}
+	for _, duration := range durations {
+		value, err := config.Duration(ac, duration.key)
+		if err != nil {
+			return nil, err
+		}
+		if value < 0 {
+			return nil, config.InvalidValueError(duration.key, ac.Get(duration.key), "a duration of zero or more", nil)
+		}
+		*duration.target = value
+	}
+
+	currentKey, err := ac.loadCurrentKey(state)
+	if err != nil {
+		return nil, err
+	}
+	previousKeys, err := ac.loadPreviousKeys(state, currentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	state.issuer = ac.Get(envmanager.AuthIssuerKey)
+	state.audience = ac.envManager.GetList(envmanager.AuthAudienceKey)
+	state.currentKey = currentKey
+	state.previousKeys = previousKeys
+	return state, nil
+}
+
+func (ac *AuthConfig) loadCurrentKey(state *authState) (*Key, diabuddyErrors.ApiErrors) {
+	key := &Key{Algorithm: state.algorithm}
+
+	if state.algorithm.IsSymmetric() {
+		value := ac.Get(envmanager.AuthSecretKey)
+		if value == "" {
+			return nil, nil
+		}
+		secret, err := parseSecret(value)
+		if err != nil {
+			return nil, invalidKeyError(envmanager.AuthSecretKey, err)
+		}
+		key.Secret = secret
+	} else {
+		privatePEM, err := ac.loadPEM(envmanager.AuthPrivateKeyKey, envmanager.AuthPrivateKeyFileKey)
+		if err != nil {
+			return nil, err
+		}
+		publicPEM, err := ac.loadPEM(envmanager.AuthPublicKeyKey, envmanager.AuthPublicKeyFileKey)
+		if err != nil {
+			return nil, err
+		}
+		if privatePEM == nil && publicPEM == nil {
+			return nil, nil
+		}
+
+		if privatePEM != nil {
+			privateKey, parseErr := parsePrivateKey(state.algorithm, privatePEM)
+			if parseErr != nil {
+				return nil, invalidKeyError(envmanager.AuthPrivateKeyKey, parseErr)
+			}
+			key.PrivateKey = privateKey
+			key.PublicKey = privateKey.Public()
+		}
+		if publicPEM != nil {
+			publicKey, parseErr := parsePublicKey(state.algorithm, publicPEM)
+			if parseErr != nil {
+				return nil, invalidKeyError(envmanager.AuthPublicKeyKey, parseErr)
+			}
+			if key.PublicKey != nil && !samePublicKey(key.PublicKey, publicKey) {
+				return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s does not belong to %s", envmanager.AuthPublicKeyKey, envmanager.AuthPrivateKeyKey))
+			}
+			key.PublicKey = publicKey
+		}
+	}
+
+	key.ID = ac.Get(envmanager.AuthKeyIDKey)
+	if key.ID == "" {
+		id, err := deriveKeyID(key)
+		if err != nil {
+			return nil, invalidKeyError(envmanager.AuthKeyIDKey, err)
+		}
+		key.ID = id
+	}
+	return key, nil
+}
+
+// loadPEM returns the PEM data of the inline key or of the file it points to; setting both is an error.
+func (ac *AuthConfig) loadPEM(inlineKey, fileKey string) ([]byte, diabuddyErrors.ApiErrors) {
+	inline, path := ac.Get(inlineKey), ac.Get(fileKey)
+	switch {
+	case inline != "" && path != "":
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("set either %s or %s, not both", inlineKey, fileKey))
+	case inline != "":
+		return []byte(inline), nil
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s points to a file that cannot be read: %s", fileKey, path), diabuddyErrors.WithInternalError(err))
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// loadPreviousKeys parses AUTH_PREVIOUS_KEYS, a list of kid=key pairs where the key is a secret for HS256 and
+// the path of a PEM public key otherwise.
+func (ac *AuthConfig) loadPreviousKeys(state *authState, currentKey *Key) ([]*Key, diabuddyErrors.ApiErrors) {
+	var keys []*Key
+	seen := make(map[string]bool)
+	if currentKey != nil {
+		seen[currentKey.ID] = true
+	}
+
+	for _, entry := range ac.envManager.GetList(envmanager.AuthPreviousKeysKey) {
+		id, value, ok := strings.Cut(entry, "=")
+		id, value = strings.TrimSpace(id), strings.TrimSpace(value)
+		if !ok || id == "" || value == "" {
+			return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, envmanager.AuthPreviousKeysKey+" must hold kid=key pairs")
+		}
+		if seen[id] {
+			return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s repeats the key ID %q", envmanager.AuthPreviousKeysKey, id))
+		}
+		seen[id] = true
+
+		key := &Key{ID: id, Algorithm: state.algorithm}
+		if state.algorithm.IsSymmetric() {
+			secret, err := parseSecret(value)
+			if err != nil {
+				return nil, invalidKeyError(envmanager.AuthPreviousKeysKey, fmt.Errorf("key %s: %w", id, err))
+			}
+			key.Secret = secret
+		} else {
+			data, err := os.ReadFile(value)
+			if err != nil {
+				return nil, invalidKeyError(envmanager.AuthPreviousKeysKey, fmt.Errorf("key %s: %w", id, err))
+			}
+			publicKey, err := parsePublicKey(state.algorithm, data)
+			if err != nil {
+				return nil, invalidKeyError(envmanager.AuthPreviousKeysKey, fmt.Errorf("key %s: %w", id, err))
+			}
+			key.PublicKey = publicKey
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func samePublicKey(a, b any) bool {
+	derA, errA := x509.MarshalPKIXPublicKey(a)
+	derB, errB := x509.MarshalPKIXPublicKey(b)
+	return errA == nil && errB == nil && bytes.Equal(derA, derB)
+}
+
+// invalidKeyError describes why key material was rejected without including the material itself.
+func invalidKeyError(key string, err error) diabuddyErrors.ApiErrors {
+	return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s is invalid: %s", key, err.Error()), diabuddyErrors.WithInternalError(err))
+}
+
+// Keys returns the keys owned by the auth section.
+func (ac *AuthConfig) Keys() []string {
+	return config.KeyNames(ac.Describe())
+}
+
+// RequiredKeys returns the keys Validate insists on; the key material it requires depends on the algorithm.
+func (ac *AuthConfig) RequiredKeys() []string {
+	return append([]string(nil), requiredKeys...)
+}
+
+// Describe returns the definitions of the keys owned by the auth section.
+func (ac *AuthConfig) Describe() []envmanager.KeyDefinition {
+	return envmanager.SectionKeyDefinitions(envmanager.AuthSection)
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults.
+func (ac *AuthConfig) Lookup(key string) (string, bool) {
+	value, found, _ := ac.envManager.Lookup(key)
+	return value, found
+}
+
+// Snapshot returns the current values of the auth section with sensitive values redacted.
+func (ac *AuthConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(ac, ac.Describe())
+}
+
+func (ac *AuthConfig) Validate() diabuddyErrors.ApiErrors {
+	state := ac.state.Load()
+	for _, key := range requiredKeys {
+		if ac.Get(key) == "" {
+			return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, key+" is required")
+		}
+	}
+	if state.currentKey == nil {
+		if state.algorithm.IsSymmetric() {
+			return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s is required for %s", envmanager.AuthSecretKey, state.algorithm))
+		}
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s requires a private or public key", state.algorithm))
+	}
+	if state.accessTokenTTL <= 0 {
+		return config.InvalidValueError(envmanager.AuthAccessTokenTTLKey, ac.Get(envmanager.AuthAccessTokenTTLKey), "a positive duration", nil)
+	}
+	if state.refreshTokenTTL < state.accessTokenTTL {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s must not be shorter than %s", envmanager.AuthRefreshTokenTTLKey, envmanager.AuthAccessTokenTTLKey))
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package authconfig
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config/appconfig/encrypter"
+	"strings"
+)
+
+// Algorithm is a JWS signing algorithm accepted in AUTH_ALGORITHM.
+type Algorithm string
+
+const (
+	HS256 Algorithm = "HS256"
+	RS256 Algorithm = "RS256"
+	EdDSA Algorithm = "EdDSA"
+)
+
+// Minimum key strength per algorithm.
+const (
+	MinSecretBytes = 32
+	MinRSABits     = 2048
+)
+
+// IsSupported reports whether the algorithm can be configured.
+func (a Algorithm) IsSupported() bool {
+	return a == HS256 || a == RS256 || a == EdDSA
+}
+
+// IsSymmetric reports whether the algorithm signs and verifies with the same secret.
+func (a Algorithm) IsSymmetric() bool {
+	return a == HS256
+}
+
+// Key is one signing or verification key. Secret is set for HS256; PublicKey, and PrivateKey when the
+// service issues tokens, are set for RS256 and EdDSA.
+type Key struct {
+	ID         string
+	Algorithm  Algorithm
+	Secret     []byte
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// CanSign reports whether the key can sign tokens and not only verify them.
+func (k *Key) CanSign() bool {
+	return len(k.Secret) > 0 || k.PrivateKey != nil
+}
+
+// parseSecret decodes an HS256 secret written like APP_KEY and checks its length.
+func parseSecret(value string) ([]byte, error) {
+	secret, err := encrypter.ParseKey(value)
+	if err != nil {
+		return nil, fmt.Errorf("secret is not valid base64")
+	}
+	if len(secret) < MinSecretBytes {
+		return nil, fmt.Errorf("secret must be at least %d bytes, got %d", MinSecretBytes, len(secret))
+	}
+	return secret, nil
+}
+
+// parsePrivateKey parses a PKCS#8 or PKCS#1 PEM private key for the algorithm.
+func parsePrivateKey(algorithm Algorithm, data []byte) (crypto.Signer, error) {
+	block, err := decodePEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if err := checkPublicKey(algorithm, signer.Public()); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// parsePublicKey parses a PKIX or PKCS#1 PEM public key, or a certificate, for the algorithm.
+func parsePublicKey(algorithm Algorithm, data []byte) (crypto.PublicKey, error) {
+	block, err := decodePEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var key any
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var certificate *x509.Certificate
+		if certificate, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = certificate.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPublicKey(algorithm, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// checkPublicKey checks that the key fits the algorithm and is strong enough.
+func checkPublicKey(algorithm Algorithm, key any) error {
+	switch algorithm {
+	case RS256:
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("RS256 requires an RSA key, got %T", key)
+		}
+		if rsaKey.N.BitLen() < MinRSABits {
+			return fmt.Errorf("RSA key must be at least %d bits, got %d", MinRSABits, rsaKey.N.BitLen())
+		}
+	case EdDSA:
+		if _, ok := key.(ed25519.PublicKey); !ok {
+			return fmt.Errorf("EdDSA requires an Ed25519 key, got %T", key)
+		}
+	default:
+		return fmt.Errorf("%s does not use key pairs", algorithm)
+	}
+	return nil
+}
+
+// decodePEM decodes the first PEM block. Escaped newlines are accepted, so a key fits on one line of a .env file.
+func decodePEM(data []byte) (*pem.Block, error) {
+	normalized := strings.ReplaceAll(strings.TrimSpace(string(data)), `\n`, "\n")
+	block, _ := pem.Decode([]byte(normalized))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	return block, nil
+}
+
+// deriveKeyID returns a stable key ID, so that services agree on it without configuring AUTH_KEY_ID: a
+// truncated SHA-256 over the secret or the PKIX encoded public key.
+func deriveKeyID(key *Key) (string, error) {
+	material := key.Secret
+	if key.PublicKey != nil {
+		der, err := x509.MarshalPKIXPublicKey(key.PublicKey)
+		if err != nil {
+			return "", err
+		}
+		material = der
+	}
+	sum := sha256.Sum256(material)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
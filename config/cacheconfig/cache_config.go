@@ -0,0 +1,405 @@
+package cacheconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Mode is the Redis deployment the cache talks to.
+type Mode string
+
+const (
+	Standalone Mode = "standalone"
+	Sentinel   Mode = "sentinel"
+	Cluster    Mode = "cluster"
+)
+
+const defaultPort = "6379"
+
+var (
+	_ config.Introspectable = (*CacheConfig)(nil)
+	_ config.Reloader       = (*CacheConfig)(nil)
+)
+
+// Options is the client independent result of CacheConfig; its fields are named after those of go-redis's
+// UniversalOptions, which picks a standalone, Sentinel or cluster client from them.
+type Options struct {
+	Mode Mode
+	// Addrs holds the server address, the Sentinel addresses or the cluster seeds, depending on Mode.
+	Addrs            []string
+	MasterName       string
+	Username         string
+	Password         string
+	SentinelPassword string
+	DB               int
+	// TLS is nil when TLS is disabled.
+	TLS          *tls.Config
+	PoolSize     int
+	MinIdleConns int
+	KeyPrefix    string
+}
+
+// Key returns a cache key with the prefix, e.g. diabuddy-user-api:production:session:42.
+func (o Options) Key(name string) string {
+	return o.KeyPrefix + name
+}
+
+type CacheConfig struct {
+	envManager *envmanager.EnvManager
+	// state holds the parsed values; Reload replaces it as a whole, so readers never see half a reload.
+	state atomic.Pointer[cacheState]
+}
+
+type cacheState struct {
+	mode              Mode
+	address           string
+	username          string
+	password          string
+	db                int
+	tlsEnabled        bool
+	sentinelAddresses []string
+	clusterAddresses  []string
+	keyPrefix         string
+	poolSize          int
+	minIdleConns      int
+}
+
+// NewCacheConfig creates a CacheConfig from REDIS_URL, or from REDIS_HOST and REDIS_PORT when it is empty.
+// It is independent of DBConfig, so a service can use Redis as a cache next to a Postgres database.
+func NewCacheConfig(envManager *envmanager.EnvManager) (*CacheConfig, diabuddyErrors.ApiErrors) {
+	cc := &CacheConfig{envManager: envManager}
+	state, err := cc.resolve()
+	if err != nil {
+		return nil, err
+	}
+	cc.state.Store(state)
+	return cc, nil
+}
+
+func (cc *CacheConfig) Get(key string, defaultValue ...string) string {
+	return cc.envManager.Get(key, defaultValue...)
+}
+
+// Mode returns Cluster when REDIS_CLUSTER_ADDRESSES is set, Sentinel when REDIS_SENTINEL_MASTER is set and
+// Standalone otherwise.
+func (cc *CacheConfig) Mode() Mode {
+	return cc.state.Load().mode
+}
+
+// Address returns the host:port of a standalone server, taken from REDIS_URL or REDIS_HOST and REDIS_PORT.
+func (cc *CacheConfig) Address() string {
+	return cc.state.Load().address
+}
+
+// DB returns the logical database index.
+func (cc *CacheConfig) DB() int {
+	return cc.state.Load().db
+}
+
+// TLSEnabled reports whether REDIS_TLS_ENABLED is true or REDIS_URL uses the rediss scheme.
+func (cc *CacheConfig) TLSEnabled() bool {
+	return cc.state.Load().tlsEnabled
+}
+
+// KeyPrefix returns REDIS_KEY_PREFIX, or APP_NAME:APP_ENV: when it is empty.
+func (cc *CacheConfig) KeyPrefix() string {
+	return cc.state.Load().keyPrefix
+}
+
+// Key returns a cache key with the prefix, e.g. diabuddy-user-api:production:session:42.
+func (cc *CacheConfig) Key(name string) string {
+	return cc.state.Load().keyPrefix + name
+}
+
+// PoolSize returns REDIS_POOL_SIZE.
+func (cc *CacheConfig) PoolSize() int {
+	return cc.state.Load().poolSize
+}
+
+// MinIdleConns returns REDIS_MIN_IDLE_CONNS.
+func (cc *CacheConfig) MinIdleConns() int {
+	return cc.state.Load().minIdleConns
+}
+
+// URL returns the connection URL understood by go-redis's ParseURL, or by ParseClusterURL in cluster mode, where
+// the seeds after the first are passed as addr parameters. Sentinel setups have no URL form, so it returns an
+// empty string for them.
+func (cc *CacheConfig) URL() string {
+	state := cc.state.Load()
+	scheme := "redis"
+	if state.tlsEnabled {
+		scheme = "rediss"
+	}
+	redisURL := &url.URL{Scheme: scheme}
+	if state.username != "" || state.password != "" {
+		redisURL.User = url.UserPassword(state.username, state.password)
+	}
+
+	switch state.mode {
+	case Sentinel:
+		return ""
+	case Cluster:
+		redisURL.Host = state.clusterAddresses[0]
+		query := url.Values{}
+		for _, address := range state.clusterAddresses[1:] {
+			query.Add("addr", address)
+		}
+		redisURL.RawQuery = query.Encode()
+	default:
+		redisURL.Host = state.address
+		redisURL.Path = "/" + strconv.Itoa(state.db)
+	}
+	return redisURL.String()
+}
+
+// TLSConfig returns the TLS configuration for the Redis connections, loading the CA file; it returns nil when TLS
+// is disabled.
+func (cc *CacheConfig) TLSConfig() (*tls.Config, diabuddyErrors.ApiErrors) {
+	return cc.tlsConfig(cc.state.Load())
+}
+
+func (cc *CacheConfig) tlsConfig(state *cacheState) (*tls.Config, diabuddyErrors.ApiErrors) {
+	if !state.tlsEnabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile := cc.Get(envmanager.RedisTLSCAFileKey); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s points to a file that cannot be read: %s", envmanager.RedisTLSCAFileKey, caFile), diabuddyErrors.WithInternalError(err))
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, config.InvalidValueError(envmanager.RedisTLSCAFileKey, caFile, "a PEM file with CA certificates", nil)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// Options returns the cache settings as a plain struct.
+func (cc *CacheConfig) Options() (Options, diabuddyErrors.ApiErrors) {
+	state := cc.state.Load()
+	tlsConfig, err := cc.tlsConfig(state)
+	if err != nil {
+		return Options{}, err
+	}
+
+	options := Options{
+		Mode:         state.mode,
+		Addrs:        []string{state.address},
+		Username:     state.username,
+		Password:     state.password,
+		DB:           state.db,
+		TLS:          tlsConfig,
+		PoolSize:     state.poolSize,
+		MinIdleConns: state.minIdleConns,
+		KeyPrefix:    state.keyPrefix,
+	}
+	switch state.mode {
+	case Sentinel:
+		options.Addrs = append([]string(nil), state.sentinelAddresses...)
+		options.MasterName = cc.Get(envmanager.RedisSentinelMasterKey)
+		options.SentinelPassword = cc.Get(envmanager.RedisSentinelPasswordKey)
+	case Cluster:
+		options.Addrs = append([]string(nil), state.clusterAddresses...)
+	}
+	return options, nil
+}
+
+// Reload re-reads the REDIS_* values and swaps them in at once, so it may run while the section is read. On error
+// the previous values are kept.
+func (cc *CacheConfig) Reload() diabuddyErrors.ApiErrors {
+	state, err := cc.resolve()
+	if err != nil {
+		return err
+	}
+	cc.state.Store(state)
+	return nil
+}
+
+// resolve reads and parses every REDIS_* value.
+func (cc *CacheConfig) resolve() (*cacheState, diabuddyErrors.ApiErrors) {
+	state := &cacheState{}
+	tlsEnabled, err := config.Bool(cc, envmanager.RedisTLSEnabledKey)
+	if err != nil {
+		return nil, err
+	}
+	if redisURL := cc.Get(envmanager.RedisURLKey); redisURL != "" {
+		if err = cc.resolveURL(state, redisURL); err != nil {
+			return nil, err
+		}
+		state.tlsEnabled = state.tlsEnabled || tlsEnabled
+	} else {
+		port, err := config.Int(cc, envmanager.RedisPortKey)
+		if err != nil {
+			return nil, err
+		}
+		if port < 1 || port > 65535 {
+			return nil, config.InvalidValueError(envmanager.RedisPortKey, cc.Get(envmanager.RedisPortKey), "a port between 1 and 65535", nil)
+		}
+		db, err := config.Int(cc, envmanager.RedisDBKey)
+		if err != nil {
+			return nil, err
+		}
+		state.address = net.JoinHostPort(cc.Get(envmanager.RedisHostKey), strconv.Itoa(port))
+		state.username = cc.Get(envmanager.RedisUsernameKey)
+		state.password = cc.Get(envmanager.RedisPasswordKey)
+		state.db = db
+		state.tlsEnabled = tlsEnabled
+	}
+	if state.db < 0 {
+		return nil, config.InvalidValueError(envmanager.RedisDBKey, strconv.Itoa(state.db), "a database index of 0 or more", nil)
+	}
+
+	addressLists := []struct {
+		key    string
+		target *[]string
+	}{
+		{envmanager.RedisSentinelAddressesKey, &state.sentinelAddresses},
+		{envmanager.RedisClusterAddressesKey, &state.clusterAddresses},
+	}
+	for _, addressList := range addressLists {
+		addresses := cc.envManager.GetList(addressList.key)
+		for _, address := range addresses {
+			if _, port, err := net.SplitHostPort(address); err != nil || port == "" {
+				return nil, config.InvalidValueError(addressList.key, address, "a host:port address", err)
+			}
+		}
+		*addressList.target = addresses
+	}
+
+	state.mode = Standalone
+	if len(state.clusterAddresses) > 0 {
+		state.mode = Cluster
+	} else if cc.Get(envmanager.RedisSentinelMasterKey) != "" {
+		state.mode = Sentinel
+	}
+
+	poolSize, err := config.Int(cc, envmanager.RedisPoolSizeKey)
+	if err != nil {
+		return nil, err
+	}
+	if poolSize < 1 {
+		return nil, config.InvalidValueError(envmanager.RedisPoolSizeKey, cc.Get(envmanager.RedisPoolSizeKey), "a pool size of 1 or more", nil)
+	}
+	minIdleConns, err := config.Int(cc, envmanager.RedisMinIdleConnsKey)
+	if err != nil {
+		return nil, err
+	}
+	if minIdleConns < 0 {
+		return nil, config.InvalidValueError(envmanager.RedisMinIdleConnsKey, cc.Get(envmanager.RedisMinIdleConnsKey), "a number of 0 or more", nil)
+	}
+
+	state.keyPrefix = cc.Get(envmanager.RedisKeyPrefixKey, defaultKeyPrefix(cc.Get(envmanager.AppNameKey), cc.envManager.Environment().String()))
+	state.poolSize = poolSize
+	state.minIdleConns = minIdleConns
+	return state, nil
+}
+
+// resolveURL reads the address, credentials, DB and TLS setting from REDIS_URL.
+func (cc *CacheConfig) resolveURL(state *cacheState, redisURL string) diabuddyErrors.ApiErrors {
+	// The URL may hold a password, so it is left out of the error messages.
+	parsed, err := url.Parse(redisURL)
+	if err != nil || (parsed.Scheme != "redis" && parsed.Scheme != "rediss") || parsed.Hostname() == "" {
+		return config.InvalidValueError(envmanager.RedisURLKey, config.RedactedValue, "a redis:// or rediss:// URL", nil)
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	db := 0
+	if path := strings.Trim(parsed.Path, "/"); path != "" {
+		if db, err = strconv.Atoi(path); err != nil {
+			return config.InvalidValueError(envmanager.RedisURLKey, config.RedactedValue, "a URL whose path is a database index", nil)
+		}
+	}
+
+	state.address = net.JoinHostPort(parsed.Hostname(), port)
+	state.username = parsed.User.Username()
+	state.password, _ = parsed.User.Password()
+	state.db = db
+	state.tlsEnabled = parsed.Scheme == "rediss"
+	return nil
+}
+
+// defaultKeyPrefix joins the non-empty parts with colons and ends with one, e.g. diabuddy-user-api:production:.
+func defaultKeyPrefix(parts ...string) string {
+	var prefix strings.Builder
+	for _, part := range parts {
+		if part != "" {
+			prefix.WriteString(part + ":")
+		}
+	}
+	return prefix.String()
+}
+
+// Keys returns the keys owned by the cache section.
+func (cc *CacheConfig) Keys() []string {
+	return config.KeyNames(cc.Describe())
+}
+
+// RequiredKeys returns the keys Validate insists on: REDIS_SENTINEL_ADDRESSES in Sentinel mode, none otherwise.
+func (cc *CacheConfig) RequiredKeys() []string {
+	if cc.Get(envmanager.RedisSentinelMasterKey) != "" {
+		return []string{envmanager.RedisSentinelAddressesKey}
+	}
+	return nil
+}
+
+// Describe returns the definitions of the keys owned by the cache section.
+func (cc *CacheConfig) Describe() []envmanager.KeyDefinition {
+	return envmanager.SectionKeyDefinitions(envmanager.CacheSection)
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults.
+func (cc *CacheConfig) Lookup(key string) (string, bool) {
+	value, found, _ := cc.envManager.Lookup(key)
+	return value, found
+}
+
+// Snapshot returns the current values of the cache section.
+func (cc *CacheConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(cc, cc.Describe())
+}
+
+// Validate checks that the required keys are present, that Sentinel and cluster mode are not combined, that a
+// cluster uses DB 0, that the idle connections fit in the pool and that the CA file can be loaded.
+func (cc *CacheConfig) Validate() diabuddyErrors.ApiErrors {
+	state := cc.state.Load()
+	var missingKeys []string
+	for _, key := range cc.RequiredKeys() {
+		if strings.TrimSpace(cc.Get(key)) == "" {
+			missingKeys = append(missingKeys, key)
+		}
+	}
+	if len(missingKeys) > 0 {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("missing required key(s): %s", strings.Join(missingKeys, ", ")))
+	}
+
+	if state.mode == Cluster && cc.Get(envmanager.RedisSentinelMasterKey) != "" {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("set either %s or %s, not both", envmanager.RedisSentinelMasterKey, envmanager.RedisClusterAddressesKey))
+	}
+	if state.mode == Cluster && state.db != 0 {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s must be 0 in cluster mode, got %d", envmanager.RedisDBKey, state.db))
+	}
+	if state.minIdleConns > state.poolSize {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s must not exceed %s", envmanager.RedisMinIdleConnsKey, envmanager.RedisPoolSizeKey))
+	}
+	if _, err := cc.tlsConfig(state); err != nil {
+		return err
+	}
+	return nil
+}
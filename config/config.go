@@ -1,10 +1,77 @@
 package config
 
 import (
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
 	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
 )
 
+// RedactedValue replaces the value of sensitive keys in snapshots.
+const RedactedValue = "******"
+
 type Config interface {
 	Get(key string, defaultValue ...string) string
 	Validate() diabuddyErrors.ApiErrors
 }
+
+// KeyLister is implemented by sections that can list the keys they own.
+type KeyLister interface {
+	Keys() []string
+}
+
+// RequiredKeyLister is implemented by sections that can list the keys Validate insists on.
+type RequiredKeyLister interface {
+	RequiredKeys() []string
+}
+
+// Describer is implemented by sections that can describe the keys they own.
+type Describer interface {
+	Describe() []envmanager.KeyDefinition
+}
+
+// KeyLookup is implemented by sections that can tell whether a key has a value at all.
+type KeyLookup interface {
+	Lookup(key string) (string, bool)
+}
+
+// Snapshotter is implemented by sections that can dump their current values.
+type Snapshotter interface {
+	Snapshot() map[string]string
+}
+
+// Reloader is implemented by sections that parse their values once and can parse them again, e.g. after the
+// env file changed. On error the previous values are kept.
+type Reloader interface {
+	Reload() diabuddyErrors.ApiErrors
+}
+
+// Introspectable is a section that implements every optional capability.
+type Introspectable interface {
+	Config
+	KeyLister
+	RequiredKeyLister
+	Describer
+	KeyLookup
+	Snapshotter
+}
+
+// KeyNames returns the names of the given definitions in order.
+func KeyNames(definitions []envmanager.KeyDefinition) []string {
+	names := make([]string, len(definitions))
+	for i, definition := range definitions {
+		names[i] = definition.Name
+	}
+	return names
+}
+
+// SnapshotOf reads every described key from the section and redacts the values of sensitive keys.
+func SnapshotOf(section Config, definitions []envmanager.KeyDefinition) map[string]string {
+	snapshot := make(map[string]string, len(definitions))
+	for _, definition := range definitions {
+		value := section.Get(definition.Name)
+		if definition.Sensitive && value != "" {
+			value = RedactedValue
+		}
+		snapshot[definition.Name] = value
+	}
+	return snapshot
+}
@@ -0,0 +1,114 @@
+package dbconfig
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"regexp"
+	"strings"
+)
+
+// TypeKeySuffix names the per-connection key that selects the database type, e.g. DB_REPORTING_TYPE.
+const TypeKeySuffix = "TYPE"
+
+// connectionKeys are the shared database keys every named connection can override.
+var connectionKeys = []string{
+	envmanager.DbUrlKey,
+	envmanager.DbHostKey,
+	envmanager.DbPortKey,
+	envmanager.DbDatabaseKey,
+	envmanager.DbUsernameKey,
+	envmanager.DbPasswordKey,
+}
+
+var validConnectionName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// WithConnection makes the DBConfig a named connection, such as one listed in DB_CONNECTIONS. Its keys are read
+// from DB_<NAME>_* first and fall back to the shared DB_* keys; DB_<NAME>_TYPE selects the database type unless
+// WithType is given.
+func WithConnection(name string) ConfigOption {
+	return func(dbConfig *DBConfig) diabuddyErrors.ApiErrors {
+		if !validConnectionName.MatchString(name) {
+			return config.InvalidValueError(envmanager.DbConnectionsKey, name, "a connection name of letters, digits, - and _", nil)
+		}
+		dbConfig.name = name
+		return nil
+	}
+}
+
+// ConnectionKey returns the per-connection variant of a shared database key: DB_HOST becomes DB_REPORTING_HOST,
+// and DATABASE_URL becomes DB_REPORTING_URL. Names that differ only in case or in - and _ share their keys.
+func ConnectionKey(name, key string) string {
+	suffix := strings.TrimPrefix(key, "DB_")
+	if key == envmanager.DbUrlKey {
+		suffix = "URL"
+	}
+	return "DB_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_" + suffix
+}
+
+// Name returns the connection name; it is empty for the default connection.
+func (c *DBConfig) Name() string {
+	return c.name
+}
+
+// connectionKey returns the per-connection key for a shared database key of a named connection.
+func (c *DBConfig) connectionKey(key string) (string, bool) {
+	if c.name == "" {
+		return "", false
+	}
+	for _, connectionKey := range connectionKeys {
+		if connectionKey == key {
+			return ConnectionKey(c.name, key), true
+		}
+	}
+	return "", false
+}
+
+// resolveConnection declares the per-connection keys for strict mode and returns the database type: the one given
+// with WithType, else DB_<NAME>_TYPE, else postgres.
+func (c *DBConfig) resolveConnection() (string, diabuddyErrors.ApiErrors) {
+	typeKey := ConnectionKey(c.name, TypeKeySuffix)
+	namedKeys := []string{typeKey}
+	for _, key := range connectionKeys {
+		namedKeys = append(namedKeys, ConnectionKey(c.name, key))
+	}
+	c.envManager.Declare(namedKeys...)
+
+	if c.isTypeSet {
+		// WithType never changes after construction, so dbType is read without the lock.
+		return c.dbType, nil
+	}
+	dbType := strings.ToLower(c.envManager.Get(typeKey))
+	if dbType == "" {
+		return Postgres, nil
+	}
+	if !isSupportedType(dbType) {
+		return "", config.InvalidValueError(typeKey, dbType, "one of postgres, mysql, sqlserver, oracle, mongodb, redis or cassandra", nil)
+	}
+	return dbType, nil
+}
+
+// databaseURL returns the URL to build the DSN from, if any. A named connection uses DB_<NAME>_URL, or the
+// shared DATABASE_URL as long as none of its own DB_<NAME>_* keys is set.
+func (c *DBConfig) databaseURL() string {
+	if c.name == "" {
+		return c.envManager.Get(envmanager.DbUrlKey)
+	}
+	if dbUrl := c.envManager.Get(ConnectionKey(c.name, envmanager.DbUrlKey)); dbUrl != "" {
+		return dbUrl
+	}
+	for _, key := range connectionKeys {
+		if c.envManager.Get(ConnectionKey(c.name, key)) != "" {
+			return ""
+		}
+	}
+	return c.envManager.Get(envmanager.DbUrlKey)
+}
+
+func isSupportedType(dbType string) bool {
+	switch dbType {
+	case Postgres, Mysql, SqlServer, Oracle, MongoDb, Redis, Cassandra:
+		return true
+	}
+	return false
+}
@@ -2,11 +2,14 @@ package dbconfig
 
 import (
 	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
 	"github.com/hbttundar/diabuddy-api-config/config/dbconfig/dsn"
 	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
 	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"maps"
 	"net/url"
 	"strings"
+	"sync"
 )
 
 const (
@@ -26,14 +29,23 @@ const (
 	PostgresDefaultPort  = "5432"
 )
 
+// Config is the contract of a database section: a regular config.Config that can also build a connection string.
 type Config interface {
+	config.Config
 	ConnectionString() (string, diabuddyErrors.ApiErrors)
-	Validate(requiredKeys []string) diabuddyErrors.ApiErrors
-	Get(key string, defaultValue ...string) string
 }
 
+var (
+	_ Config                = (*DBConfig)(nil)
+	_ config.Introspectable = (*DBConfig)(nil)
+	_ config.Reloader       = (*DBConfig)(nil)
+)
+
 type DBConfig struct {
-	envManager  *envmanager.EnvManager
+	envManager *envmanager.EnvManager
+	name       string
+	// mu guards the DSN and the database type, which Reload replaces.
+	mu          sync.Mutex
 	dsn         *dsn.DSN
 	dbType      string
 	params      map[string]string
@@ -59,6 +71,14 @@ func NewDBConfig(envManager *envmanager.EnvManager, options ...ConfigOption) (*D
 		}
 	}
 
+	if databaseConfig.name != "" {
+		dbType, err := databaseConfig.resolveConnection()
+		if err != nil {
+			return nil, err
+		}
+		databaseConfig.dbType = dbType
+	}
+
 	// Ensure environment variables are loaded at the point of initialization
 	if err := envManager.LoadEnvironmentVariables(); err != nil {
 		return nil, diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "failed to load environment variables", diabuddyErrors.WithInternalError(err))
@@ -91,18 +111,60 @@ func WithDsnParameters(params map[string]string) ConfigOption {
 	}
 }
 
-// Get retrieves the value of an environment variable, or the default if not set.
+// Get retrieves the value of an environment variable, or the default if not set. For a named connection the
+// shared database keys resolve to the per-connection key first, so DB_HOST reads DB_REPORTING_HOST and falls
+// back to DB_HOST.
 func (c *DBConfig) Get(key string, defaultValue ...string) string {
+	if namedKey, ok := c.connectionKey(key); ok {
+		if value := c.envManager.Get(namedKey); value != "" {
+			return value
+		}
+	}
 	return c.envManager.Get(key, defaultValue...)
 }
 
+// Keys returns the keys owned by the database section.
+func (c *DBConfig) Keys() []string {
+	return config.KeyNames(c.Describe())
+}
+
+// RequiredKeys returns the keys Validate insists on for the configured database type.
+func (c *DBConfig) RequiredKeys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return getRequiredKeysForDBType(c.dbType)
+}
+
+// Describe returns the definitions of the keys owned by the database section.
+func (c *DBConfig) Describe() []envmanager.KeyDefinition {
+	return envmanager.SectionKeyDefinitions(envmanager.DbSection)
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults. Named
+// connections fall back to the shared keys like Get, also when the per-connection key is set but empty.
+func (c *DBConfig) Lookup(key string) (string, bool) {
+	if namedKey, ok := c.connectionKey(key); ok {
+		if value, found, _ := c.envManager.Lookup(namedKey); found && value != "" {
+			return value, found
+		}
+	}
+	value, found, _ := c.envManager.Lookup(key)
+	return value, found
+}
+
+// Snapshot returns the current values of the database section with sensitive values redacted.
+func (c *DBConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(c, c.Describe())
+}
+
 // Validate checks that all required environment variables are present.
 func (c *DBConfig) Validate() diabuddyErrors.ApiErrors {
-	requiredKeys := getRequiredKeysForDBType(c.dbType)
-
 	var missingKeys []string
-	for _, key := range requiredKeys {
-		if strings.TrimSpace(c.envManager.Get(key)) == "" {
+	for _, key := range c.RequiredKeys() {
+		if strings.TrimSpace(c.Get(key)) == "" {
+			if namedKey, ok := c.connectionKey(key); ok {
+				key = namedKey
+			}
 			missingKeys = append(missingKeys, key)
 		}
 	}
@@ -113,18 +175,39 @@ func (c *DBConfig) Validate() diabuddyErrors.ApiErrors {
 	return nil
 }
 
+// Reload discards the DSN, so the next ConnectionString builds it from the current keys, and reads
+// DB_<NAME>_TYPE of a named connection again unless WithType was given. It may run while the section is read. On
+// error the previous values are kept.
+func (c *DBConfig) Reload() diabuddyErrors.ApiErrors {
+	var dbType string
+	if c.name != "" {
+		resolved, err := c.resolveConnection()
+		if err != nil {
+			return err
+		}
+		dbType = resolved
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dsn = nil
+	if dbType != "" {
+		c.dbType = dbType
+	}
+	return nil
+}
+
 // loadFromEnv loads environment variables to initialize the DSN.
 func (c *DBConfig) loadFromEnv() diabuddyErrors.ApiErrors {
-	dbUrl := c.envManager.Get(envmanager.DbUrlKey)
-	if dbUrl != "" {
+	if dbUrl := c.databaseURL(); dbUrl != "" {
 		return c.parseDatabaseURL(dbUrl)
 	}
 
-	dbHost := c.envManager.Get(envmanager.DbHostKey)
-	dbPort := c.envManager.Get(envmanager.DbPortKey, getDefaultPort(c.dbType))
-	dbUsername := c.envManager.Get(envmanager.DbUsernameKey)
-	dbPassword := c.envManager.Get(envmanager.DbPasswordKey)
-	dbDatabase := c.envManager.Get(envmanager.DbDatabaseKey)
+	dbHost := c.Get(envmanager.DbHostKey)
+	dbPort := c.Get(envmanager.DbPortKey, getDefaultPort(c.dbType))
+	dbUsername := c.Get(envmanager.DbUsernameKey)
+	dbPassword := c.Get(envmanager.DbPasswordKey)
+	dbDatabase := c.Get(envmanager.DbDatabaseKey)
 
 	dsnInstance, err := dsn.NewDSN(dbHost, dbPort, dbUsername, dbPassword, dbDatabase, c.params, dsnConnectionStringClosureWithDbType(c.dbType))
 	if err != nil {
@@ -153,12 +236,15 @@ func (c *DBConfig) parseDatabaseURL(databaseUrl string) diabuddyErrors.ApiErrors
 
 	dbDatabase := strings.TrimPrefix(parsedURL.Path, "/")
 
-	// Merge existing parameters with those in the URL
+	// Merge existing parameters with those in the URL; c.params keeps the ones given as options, so a reload
+	// does not carry over the parameters of the previous URL
+	params := make(map[string]string, len(c.params))
+	maps.Copy(params, c.params)
 	for key, value := range parsedURL.Query() {
-		c.params[key] = value[0]
+		params[key] = value[0]
 	}
 
-	dsnInstance, dsnErr := dsn.NewDSN(dbHost, dbPort, dbUsername, dbPassword, dbDatabase, c.params, dsnConnectionStringClosureWithDbType(c.dbType))
+	dsnInstance, dsnErr := dsn.NewDSN(dbHost, dbPort, dbUsername, dbPassword, dbDatabase, params, dsnConnectionStringClosureWithDbType(c.dbType))
 	if dsnErr != nil {
 		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "cannot create DSN", diabuddyErrors.WithInternalError(dsnErr))
 	}
@@ -169,6 +255,8 @@ func (c *DBConfig) parseDatabaseURL(databaseUrl string) diabuddyErrors.ApiErrors
 
 // ConnectionString generates the DSN string.
 func (c *DBConfig) ConnectionString() (string, diabuddyErrors.ApiErrors) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	err := c.generateDSN()
 	if err != nil {
 		return "", err
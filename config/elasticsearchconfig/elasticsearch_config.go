@@ -0,0 +1,336 @@
+package elasticsearchconfig
+
+import (
+	"crypto/x509"
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var requiredKeys = []string{envmanager.ElasticsearchAddressesKey}
+
+var (
+	validFingerprint = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	validIndexPrefix = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	validVersion     = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-[0-9A-Za-z.]+)?$`)
+)
+
+var (
+	_ config.Introspectable = (*ElasticsearchConfig)(nil)
+	_ config.Reloader       = (*ElasticsearchConfig)(nil)
+)
+
+// Version is a parsed Elasticsearch version such as 8.14.1.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion parses versions such as 8, 8.14, 8.14.1 and 8.15.0-SNAPSHOT.
+func ParseVersion(version string) (Version, bool) {
+	matches := validVersion.FindStringSubmatch(strings.TrimSpace(version))
+	if matches == nil {
+		return Version{}, false
+	}
+	var parsed Version
+	for i, target := range []*int{&parsed.Major, &parsed.Minor, &parsed.Patch} {
+		if matches[i+1] != "" {
+			*target, _ = strconv.Atoi(matches[i+1])
+		}
+	}
+	return parsed, true
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// CompatibleWith reports whether a client built for one version can talk to a server of the other; Elasticsearch
+// clients are compatible within a major version.
+func (v Version) CompatibleWith(other Version) bool {
+	return v.Major == other.Major
+}
+
+// Settings is the client independent result of ElasticsearchConfig; its fields are named after those of the
+// official Go client's Config.
+type Settings struct {
+	Addresses []string
+	Username  string
+	Password  string
+	APIKey    string
+	// CertificateFingerprint is the hex SHA-256 fingerprint of the CA certificate, without colons.
+	CertificateFingerprint string
+	// CACert holds the PEM encoded CA certificate read from ELASTICSEARCH_CA_CERT_FILE.
+	CACert         []byte
+	IndexPrefix    string
+	RequestTimeout time.Duration
+	DialTimeout    time.Duration
+}
+
+// Index returns the full name of an index: the prefix and the name joined by a dash, e.g. production-users.
+func (s Settings) Index(name string) string {
+	if s.IndexPrefix == "" {
+		return name
+	}
+	return s.IndexPrefix + "-" + name
+}
+
+type ElasticsearchConfig struct {
+	envManager *envmanager.EnvManager
+	// state holds the parsed values; Reload replaces it as a whole, so readers never see half a reload.
+	state atomic.Pointer[elasticsearchState]
+}
+
+type elasticsearchState struct {
+	addresses              []string
+	certificateFingerprint string
+	indexPrefix            string
+	requestTimeout         time.Duration
+	dialTimeout            time.Duration
+	serverVersion          Version
+	stackVersion           *Version
+}
+
+// NewElasticsearchConfig creates an ElasticsearchConfig from the ELASTICSEARCH_* values.
+func NewElasticsearchConfig(envManager *envmanager.EnvManager) (*ElasticsearchConfig, diabuddyErrors.ApiErrors) {
+	ec := &ElasticsearchConfig{envManager: envManager}
+	state, err := ec.resolve()
+	if err != nil {
+		return nil, err
+	}
+	ec.state.Store(state)
+	return ec, nil
+}
+
+func (ec *ElasticsearchConfig) Get(key string, defaultValue ...string) string {
+	return ec.envManager.Get(key, defaultValue...)
+}
+
+// Addresses returns ELASTICSEARCH_ADDRESSES.
+func (ec *ElasticsearchConfig) Addresses() []string {
+	return append([]string(nil), ec.state.Load().addresses...)
+}
+
+// UsesAPIKey reports whether requests authenticate with ELASTICSEARCH_API_KEY rather than basic auth.
+func (ec *ElasticsearchConfig) UsesAPIKey() bool {
+	return ec.Get(envmanager.ElasticsearchAPIKeyKey) != ""
+}
+
+// CertificateFingerprint returns ELASTICSEARCH_CA_FINGERPRINT in lower case and without colons.
+func (ec *ElasticsearchConfig) CertificateFingerprint() string {
+	return ec.state.Load().certificateFingerprint
+}
+
+// IndexPrefix returns ELASTICSEARCH_INDEX_PREFIX, or the environment when it is empty.
+func (ec *ElasticsearchConfig) IndexPrefix() string {
+	return ec.state.Load().indexPrefix
+}
+
+// Index returns the full name of an index, e.g. production-users.
+func (ec *ElasticsearchConfig) Index(name string) string {
+	return Settings{IndexPrefix: ec.state.Load().indexPrefix}.Index(name)
+}
+
+// RequestTimeout returns ELASTICSEARCH_REQUEST_TIMEOUT.
+func (ec *ElasticsearchConfig) RequestTimeout() time.Duration {
+	return ec.state.Load().requestTimeout
+}
+
+// DialTimeout returns ELASTICSEARCH_DIAL_TIMEOUT.
+func (ec *ElasticsearchConfig) DialTimeout() time.Duration {
+	return ec.state.Load().dialTimeout
+}
+
+// ServerVersion returns ELASTICSEARCH_VERSION, or STACK_VERSION when it is empty.
+func (ec *ElasticsearchConfig) ServerVersion() Version {
+	return ec.state.Load().serverVersion
+}
+
+// CACert reads the PEM file in ELASTICSEARCH_CA_CERT_FILE; it returns nil when the key is empty.
+func (ec *ElasticsearchConfig) CACert() ([]byte, diabuddyErrors.ApiErrors) {
+	caFile := ec.Get(envmanager.ElasticsearchCACertFileKey)
+	if caFile == "" {
+		return nil, nil
+	}
+	caCert, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s points to a file that cannot be read: %s", envmanager.ElasticsearchCACertFileKey, caFile), diabuddyErrors.WithInternalError(err))
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(caCert) {
+		return nil, config.InvalidValueError(envmanager.ElasticsearchCACertFileKey, caFile, "a PEM file with CA certificates", nil)
+	}
+	return caCert, nil
+}
+
+// Settings returns the Elasticsearch settings as a plain struct.
+func (ec *ElasticsearchConfig) Settings() (Settings, diabuddyErrors.ApiErrors) {
+	state := ec.state.Load()
+	caCert, err := ec.CACert()
+	if err != nil {
+		return Settings{}, err
+	}
+
+	settings := Settings{
+		Addresses:              append([]string(nil), state.addresses...),
+		APIKey:                 ec.Get(envmanager.ElasticsearchAPIKeyKey),
+		CertificateFingerprint: state.certificateFingerprint,
+		CACert:                 caCert,
+		IndexPrefix:            state.indexPrefix,
+		RequestTimeout:         state.requestTimeout,
+		DialTimeout:            state.dialTimeout,
+	}
+	if settings.APIKey == "" {
+		settings.Username = ec.Get(envmanager.ElasticsearchUsernameKey)
+		settings.Password = ec.Get(envmanager.ElasticsearchPasswordKey)
+	}
+	return settings, nil
+}
+
+// Reload re-reads the Elasticsearch values and swaps them in at once, so it may run while the section is read. On
+// error the previous values are kept.
+func (ec *ElasticsearchConfig) Reload() diabuddyErrors.ApiErrors {
+	state, err := ec.resolve()
+	if err != nil {
+		return err
+	}
+	ec.state.Store(state)
+	return nil
+}
+
+// resolve reads and parses every Elasticsearch value.
+func (ec *ElasticsearchConfig) resolve() (*elasticsearchState, diabuddyErrors.ApiErrors) {
+	state := &elasticsearchState{}
+	addresses := ec.envManager.GetList(envmanager.ElasticsearchAddressesKey)
+	for _, address := range addresses {
+		parsed, err := url.Parse(address)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, config.InvalidValueError(envmanager.ElasticsearchAddressesKey, address, "an http or https URL", err)
+		}
+	}
+
+	fingerprint := strings.ToLower(strings.ReplaceAll(ec.Get(envmanager.ElasticsearchCAFingerprintKey), ":", ""))
+	if fingerprint != "" && !validFingerprint.MatchString(fingerprint) {
+		return nil, config.InvalidValueError(envmanager.ElasticsearchCAFingerprintKey, ec.Get(envmanager.ElasticsearchCAFingerprintKey), "a hex SHA-256 fingerprint", nil)
+	}
+
+	indexPrefix := ec.Get(envmanager.ElasticsearchIndexPrefixKey, ec.envManager.Environment().String())
+	if !validIndexPrefix.MatchString(indexPrefix) {
+		return nil, config.InvalidValueError(envmanager.ElasticsearchIndexPrefixKey, indexPrefix, "lower case letters, digits, - and _", nil)
+	}
+
+	durations := []struct {
+		key    string
+		target *time.Duration
+	}{
+		{envmanager.ElasticsearchRequestTimeoutKey, &state.requestTimeout},
+		{envmanager.ElasticsearchDialTimeoutKey, &state.dialTimeout},
+	}
+	for _, duration := range durations {
+		value, err := config.Duration(ec, duration.key)
+		if err != nil {
+			return nil, err
+		}
+		if value < 0 {
+			return nil, config.InvalidValueError(duration.key, ec.Get(duration.key), "a duration of zero or more", nil)
+		}
+		*duration.target = value
+	}
+
+	var stackVersion *Version
+	if value := ec.Get(envmanager.StackVersionKey); value != "" {
+		parsed, ok := ParseVersion(value)
+		if !ok {
+			return nil, config.InvalidValueError(envmanager.StackVersionKey, value, "a version such as 8.14.1", nil)
+		}
+		stackVersion = &parsed
+	}
+	var serverVersion Version
+	if value := ec.Get(envmanager.ElasticsearchVersionKey); value != "" {
+		parsed, ok := ParseVersion(value)
+		if !ok {
+			return nil, config.InvalidValueError(envmanager.ElasticsearchVersionKey, value, "a version such as 8.14.1", nil)
+		}
+		serverVersion = parsed
+	} else if stackVersion != nil {
+		serverVersion = *stackVersion
+	}
+
+	state.addresses = addresses
+	state.certificateFingerprint = fingerprint
+	state.indexPrefix = indexPrefix
+	state.serverVersion = serverVersion
+	state.stackVersion = stackVersion
+	return state, nil
+}
+
+// Keys returns the keys owned by the Elasticsearch section.
+func (ec *ElasticsearchConfig) Keys() []string {
+	return config.KeyNames(ec.Describe())
+}
+
+// RequiredKeys returns the keys Validate insists on; ELASTICSEARCH_PASSWORD joins them when a user name is set.
+func (ec *ElasticsearchConfig) RequiredKeys() []string {
+	keys := append([]string(nil), requiredKeys...)
+	if ec.Get(envmanager.ElasticsearchUsernameKey) != "" {
+		keys = append(keys, envmanager.ElasticsearchPasswordKey)
+	}
+	return keys
+}
+
+// Describe returns the definitions of the keys owned by the Elasticsearch section.
+func (ec *ElasticsearchConfig) Describe() []envmanager.KeyDefinition {
+	return envmanager.SectionKeyDefinitions(envmanager.ElasticsearchSection)
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults.
+func (ec *ElasticsearchConfig) Lookup(key string) (string, bool) {
+	value, found, _ := ec.envManager.Lookup(key)
+	return value, found
+}
+
+// Snapshot returns the current values of the Elasticsearch section.
+func (ec *ElasticsearchConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(ec, ec.Describe())
+}
+
+// Validate checks that the required keys are present, that a single auth method and a single way of trusting the
+// CA are configured, that the CA file can be loaded and that ELASTICSEARCH_VERSION shares its major version with
+// STACK_VERSION.
+func (ec *ElasticsearchConfig) Validate() diabuddyErrors.ApiErrors {
+	state := ec.state.Load()
+	var missingKeys []string
+	for _, key := range ec.RequiredKeys() {
+		if strings.TrimSpace(ec.Get(key)) == "" {
+			missingKeys = append(missingKeys, key)
+		}
+	}
+	if len(missingKeys) > 0 {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("missing required key(s): %s", strings.Join(missingKeys, ", ")))
+	}
+
+	if ec.UsesAPIKey() && (ec.Get(envmanager.ElasticsearchUsernameKey) != "" || ec.Get(envmanager.ElasticsearchPasswordKey) != "") {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s cannot be combined with %s and %s", envmanager.ElasticsearchAPIKeyKey, envmanager.ElasticsearchUsernameKey, envmanager.ElasticsearchPasswordKey))
+	}
+	if ec.Get(envmanager.ElasticsearchPasswordKey) != "" && ec.Get(envmanager.ElasticsearchUsernameKey) == "" {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s and %s must be set together", envmanager.ElasticsearchUsernameKey, envmanager.ElasticsearchPasswordKey))
+	}
+	if state.certificateFingerprint != "" && ec.Get(envmanager.ElasticsearchCACertFileKey) != "" {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("set either %s or %s, not both", envmanager.ElasticsearchCAFingerprintKey, envmanager.ElasticsearchCACertFileKey))
+	}
+	if _, err := ec.CACert(); err != nil {
+		return err
+	}
+	if state.stackVersion != nil && !state.serverVersion.CompatibleWith(*state.stackVersion) {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s %s is not compatible with %s %s: the major versions differ", envmanager.ElasticsearchVersionKey, state.serverVersion, envmanager.StackVersionKey, state.stackVersion))
+	}
+	return nil
+}
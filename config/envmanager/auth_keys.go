@@ -0,0 +1,34 @@
+package envmanager
+
+const (
+	AuthAlgorithmKey       = "AUTH_ALGORITHM"
+	AuthKeyIDKey           = "AUTH_KEY_ID"
+	AuthPrivateKeyKey      = "AUTH_PRIVATE_KEY"
+	AuthPrivateKeyFileKey  = "AUTH_PRIVATE_KEY_FILE"
+	AuthPublicKeyKey       = "AUTH_PUBLIC_KEY"
+	AuthPublicKeyFileKey   = "AUTH_PUBLIC_KEY_FILE"
+	AuthPreviousKeysKey    = "AUTH_PREVIOUS_KEYS"
+	AuthIssuerKey          = "AUTH_ISSUER"
+	AuthAudienceKey        = "AUTH_AUDIENCE"
+	AuthAccessTokenTTLKey  = "AUTH_ACCESS_TOKEN_TTL"
+	AuthRefreshTokenTTLKey = "AUTH_REFRESH_TOKEN_TTL"
+	AuthClockSkewKey       = "AUTH_CLOCK_SKEW"
+)
+
+func authKeyDefinitions() []KeyDefinition {
+	return []KeyDefinition{
+		{Name: AuthAlgorithmKey, Section: AuthSection, Enum: []string{"HS256", "RS256", "EdDSA"}, Default: "HS256", Required: true, Description: "Algorithm used to sign authentication tokens."},
+		{Name: AuthSecretKey, Section: AuthSection, Sensitive: true, Description: "HS256 signing secret of at least 32 bytes, raw or written as base64:<secret>."},
+		{Name: AuthKeyIDKey, Section: AuthSection, Description: "Key ID (kid) of the current key; derived from the key when empty."},
+		{Name: AuthPrivateKeyKey, Section: AuthSection, Sensitive: true, Description: "PEM private key for RS256 or EdDSA; only needed by services that issue tokens."},
+		{Name: AuthPrivateKeyFileKey, Section: AuthSection, Description: "Path to the PEM private key; alternative to AUTH_PRIVATE_KEY."},
+		{Name: AuthPublicKeyKey, Section: AuthSection, Description: "PEM public key or certificate for RS256 or EdDSA; derived from the private key when empty."},
+		{Name: AuthPublicKeyFileKey, Section: AuthSection, Description: "Path to the PEM public key or certificate; alternative to AUTH_PUBLIC_KEY."},
+		{Name: AuthPreviousKeysKey, Section: AuthSection, Type: ListKey, Sensitive: true, Description: "Comma separated kid=key pairs of retired keys still accepted for verification: secrets for HS256, PEM public key files otherwise."},
+		{Name: AuthIssuerKey, Section: AuthSection, Description: "Expected iss claim of the tokens."},
+		{Name: AuthAudienceKey, Section: AuthSection, Type: ListKey, Description: "Comma separated accepted aud claims."},
+		{Name: AuthAccessTokenTTLKey, Section: AuthSection, Type: DurationKey, Default: "15m", Description: "Lifetime of access tokens."},
+		{Name: AuthRefreshTokenTTLKey, Section: AuthSection, Type: DurationKey, Default: "720h", Description: "Lifetime of refresh tokens."},
+		{Name: AuthClockSkewKey, Section: AuthSection, Type: DurationKey, Default: "30s", Description: "Tolerated clock difference when checking exp, nbf and iat."},
+	}
+}
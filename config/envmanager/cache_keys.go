@@ -0,0 +1,39 @@
+package envmanager
+
+const (
+	RedisURLKey               = "REDIS_URL"
+	RedisHostKey              = "REDIS_HOST"
+	RedisPortKey              = "REDIS_PORT"
+	RedisUsernameKey          = "REDIS_USERNAME"
+	RedisPasswordKey          = "REDIS_PASSWORD"
+	RedisDBKey                = "REDIS_DB"
+	RedisTLSEnabledKey        = "REDIS_TLS_ENABLED"
+	RedisTLSCAFileKey         = "REDIS_TLS_CA_FILE"
+	RedisSentinelMasterKey    = "REDIS_SENTINEL_MASTER"
+	RedisSentinelAddressesKey = "REDIS_SENTINEL_ADDRESSES"
+	RedisSentinelPasswordKey  = "REDIS_SENTINEL_PASSWORD"
+	RedisClusterAddressesKey  = "REDIS_CLUSTER_ADDRESSES"
+	RedisKeyPrefixKey         = "REDIS_KEY_PREFIX"
+	RedisPoolSizeKey          = "REDIS_POOL_SIZE"
+	RedisMinIdleConnsKey      = "REDIS_MIN_IDLE_CONNS"
+)
+
+func cacheKeyDefinitions() []KeyDefinition {
+	return []KeyDefinition{
+		{Name: RedisURLKey, Section: CacheSection, Sensitive: true, Description: "redis:// or rediss:// URL; replaces the host, port, credentials, DB and TLS keys when set."},
+		{Name: RedisHostKey, Section: CacheSection, Default: "localhost", Description: "Host of a standalone Redis server."},
+		{Name: RedisPortKey, Section: CacheSection, Type: IntegerKey, Default: "6379", Description: "Port of a standalone Redis server."},
+		{Name: RedisUsernameKey, Section: CacheSection, Description: "ACL user name; empty uses the default user."},
+		{Name: RedisPasswordKey, Section: CacheSection, Sensitive: true, Description: "Password of the Redis user."},
+		{Name: RedisDBKey, Section: CacheSection, Type: IntegerKey, Default: "0", Description: "Logical database index; must be 0 with a cluster."},
+		{Name: RedisTLSEnabledKey, Section: CacheSection, Type: BooleanKey, Default: "false", Description: "Connects to Redis over TLS."},
+		{Name: RedisTLSCAFileKey, Section: CacheSection, Description: "PEM file with the CA certificates that sign the server certificates; empty uses the system pool."},
+		{Name: RedisSentinelMasterKey, Section: CacheSection, Description: "Name of the master monitored by Sentinel; enables Sentinel mode."},
+		{Name: RedisSentinelAddressesKey, Section: CacheSection, Type: ListKey, Description: "Comma separated host:port addresses of the Sentinels."},
+		{Name: RedisSentinelPasswordKey, Section: CacheSection, Sensitive: true, Description: "Password of the Sentinels, if they require one."},
+		{Name: RedisClusterAddressesKey, Section: CacheSection, Type: ListKey, Description: "Comma separated host:port seed addresses of a Redis Cluster; enables cluster mode."},
+		{Name: RedisKeyPrefixKey, Section: CacheSection, Description: "Prefix of every cache key; defaults to APP_NAME:APP_ENV:."},
+		{Name: RedisPoolSizeKey, Section: CacheSection, Type: IntegerKey, Default: "10", Description: "Maximum number of connections per node."},
+		{Name: RedisMinIdleConnsKey, Section: CacheSection, Type: IntegerKey, Default: "0", Description: "Number of idle connections kept open per node."},
+	}
+}
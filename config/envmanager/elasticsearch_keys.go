@@ -0,0 +1,31 @@
+package envmanager
+
+const (
+	ElasticsearchAddressesKey      = "ELASTICSEARCH_ADDRESSES"
+	ElasticsearchUsernameKey       = "ELASTICSEARCH_USERNAME"
+	ElasticsearchPasswordKey       = "ELASTICSEARCH_PASSWORD"
+	ElasticsearchAPIKeyKey         = "ELASTICSEARCH_API_KEY"
+	ElasticsearchCAFingerprintKey  = "ELASTICSEARCH_CA_FINGERPRINT"
+	ElasticsearchCACertFileKey     = "ELASTICSEARCH_CA_CERT_FILE"
+	ElasticsearchIndexPrefixKey    = "ELASTICSEARCH_INDEX_PREFIX"
+	ElasticsearchRequestTimeoutKey = "ELASTICSEARCH_REQUEST_TIMEOUT"
+	ElasticsearchDialTimeoutKey    = "ELASTICSEARCH_DIAL_TIMEOUT"
+	ElasticsearchVersionKey        = "ELASTICSEARCH_VERSION"
+	StackVersionKey                = "STACK_VERSION"
+)
+
+func elasticsearchKeyDefinitions() []KeyDefinition {
+	return []KeyDefinition{
+		{Name: ElasticsearchAddressesKey, Section: ElasticsearchSection, Type: ListKey, Description: "Comma separated URLs of the Elasticsearch nodes; required by the Elasticsearch section."},
+		{Name: ElasticsearchUsernameKey, Section: ElasticsearchSection, Description: "User name for basic auth."},
+		{Name: ElasticsearchPasswordKey, Section: ElasticsearchSection, Sensitive: true, Description: "Password for basic auth."},
+		{Name: ElasticsearchAPIKeyKey, Section: ElasticsearchSection, Sensitive: true, Description: "Base64 encoded API key; cannot be combined with basic auth."},
+		{Name: ElasticsearchCAFingerprintKey, Section: ElasticsearchSection, Description: "Hex SHA-256 fingerprint of the CA certificate that signed the node certificates."},
+		{Name: ElasticsearchCACertFileKey, Section: ElasticsearchSection, Description: "PEM file with the CA certificate; an alternative to the fingerprint."},
+		{Name: ElasticsearchIndexPrefixKey, Section: ElasticsearchSection, Description: "Prefix of every index name; defaults to APP_ENV."},
+		{Name: ElasticsearchRequestTimeoutKey, Section: ElasticsearchSection, Type: DurationKey, Default: "30s", Description: "Maximum duration of a request, including retries."},
+		{Name: ElasticsearchDialTimeoutKey, Section: ElasticsearchSection, Type: DurationKey, Default: "5s", Description: "Maximum duration for connecting to a node."},
+		{Name: ElasticsearchVersionKey, Section: ElasticsearchSection, Description: "Version of the Elasticsearch servers; its major version must match STACK_VERSION. Defaults to STACK_VERSION."},
+		{Name: StackVersionKey, Section: ElasticsearchSection, Description: "Version of the Elastic stack the service is built against, as used by the local Docker stack."},
+	}
+}
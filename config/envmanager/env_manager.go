@@ -5,48 +5,109 @@ import (
 	"github.com/hbttundar/diabuddy-api-config/util/resolver/rootpath"
 	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
 	"github.com/joho/godotenv"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 const (
-	AppNameKey           = "APP_NAME"
-	AppEnvKey            = "APP_ENV"
-	AppEncryptionKey     = "APP_KEY"
-	AppDebugKey          = "APP_DEBUG"
-	AppUrlKey            = "APP_URL"
-	AppTimezoneKey       = "APP_TIMEZONE"
-	AppLocaleKey         = "APP_LOCALE"
-	AppFallbackLocaleKey = "APP_FALLBACK_LOCALE"
-	AppCipherKey         = "APP_CIPHER"
-	AuthSecretKey        = "AUTH_SECRET"
-	DbUrlKey             = "DATABASE_URL"
-	DbHostKey            = "DB_HOST"
-	DbPortKey            = "DB_PORT"
-	DbDatabaseKey        = "DB_DATABASE"
-	DbUsernameKey        = "DB_USERNAME"
-	DbPasswordKey        = "DB_PASSWORD"
-	DbSslModeKey         = "SSL_MODE"
+	AppNameKey             = "APP_NAME"
+	AppEnvKey              = "APP_ENV"
+	AppEncryptionKey       = "APP_KEY"
+	AppPreviousKeysKey     = "APP_PREVIOUS_KEYS"
+	AppDebugKey            = "APP_DEBUG"
+	AppUrlKey              = "APP_URL"
+	AppTimezoneKey         = "APP_TIMEZONE"
+	AppLocaleKey           = "APP_LOCALE"
+	AppFallbackLocaleKey   = "APP_FALLBACK_LOCALE"
+	AppSupportedLocalesKey = "APP_SUPPORTED_LOCALES"
+	AppCipherKey           = "APP_CIPHER"
+	AppBasePathKey         = "APP_BASE_PATH"
+	AuthSecretKey          = "AUTH_SECRET"
+	DbUrlKey               = "DATABASE_URL"
+	DbHostKey              = "DB_HOST"
+	DbPortKey              = "DB_PORT"
+	DbDatabaseKey          = "DB_DATABASE"
+	DbUsernameKey          = "DB_USERNAME"
+	DbPasswordKey          = "DB_PASSWORD"
+	DbSslModeKey           = "SSL_MODE"
+	DbConnectionsKey       = "DB_CONNECTIONS"
 )
 
+// Sources reported by Lookup.
+const (
+	SourceEnvironment = "environment"
+	SourceEnvFile     = "env_file"
+	SourceDefault     = "default"
+)
+
+// EmptyValuePolicy decides what an environment variable that is set to an empty string means.
+type EmptyValuePolicy int
+
+const (
+	// EmptyMeansUnset treats an empty value like a missing one, so defaults still apply.
+	EmptyMeansUnset EmptyValuePolicy = iota
+	// EmptyOverridesDefault treats an empty value as a deliberate value that wins over every default.
+	EmptyOverridesDefault
+)
+
+// StrictMode decides what happens when code reads a key that was never registered or declared.
+type StrictMode int
+
+const (
+	// StrictOff allows reading any key.
+	StrictOff StrictMode = iota
+	// StrictPanic panics on the first read of an undeclared key.
+	StrictPanic
+	// StrictRecord records undeclared reads so they can be reported later, e.g. when a test ends.
+	StrictRecord
+	// StrictWarn only counts undeclared reads and logs each key once. Every strict mode degrades to
+	// StrictWarn in production.
+	StrictWarn
+)
+
+// TestingT is the subset of testing.TB used to report undeclared reads when a test ends.
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...any)
+}
+
 type EnvManager struct {
-	useDefaults  bool
-	useCache     bool
-	environment  string
-	cache        sync.Map
-	defaults     map[string]string
-	pathResolver *rootpath.RootPathResolver
+	useDefaults      bool
+	useCache         bool
+	environment      Environment
+	environmentSet   bool
+	emptyValuePolicy EmptyValuePolicy
+	strictMode       StrictMode
+	cache            sync.Map
+	fileValues       sync.Map
+	declared         sync.Map
+	undeclared       sync.Map
+	undeclaredReads  atomic.Uint64
+	defaults         map[string]string
+	pathResolver     *rootpath.RootPathResolver
+}
+
+type lookupResult struct {
+	value  string
+	found  bool
+	source string
 }
 
 type EnvOption func(*EnvManager) diabuddyErrors.ApiErrors
 
 type DefaultExtender func(map[string]string)
 
-// WithEnvironment sets the environment for loading the corresponding .env resolver
+// WithEnvironment sets the environment, which wins over APP_ENV and decides which .env file is loaded
 func WithEnvironment(environment string) EnvOption {
 	return func(em *EnvManager) diabuddyErrors.ApiErrors {
-		em.environment = environment
+		em.environment = NormalizeEnvironment(environment)
+		em.environmentSet = em.environment != ""
 		return nil
 	}
 }
@@ -67,6 +128,30 @@ func WithUseCache(useCache bool) EnvOption {
 	}
 }
 
+// WithEmptyValuePolicy sets how explicitly empty environment variables are treated
+func WithEmptyValuePolicy(policy EmptyValuePolicy) EnvOption {
+	return func(em *EnvManager) diabuddyErrors.ApiErrors {
+		em.emptyValuePolicy = policy
+		return nil
+	}
+}
+
+// WithStrictMode sets what happens when an undeclared key is read
+func WithStrictMode(mode StrictMode) EnvOption {
+	return func(em *EnvManager) diabuddyErrors.ApiErrors {
+		em.strictMode = mode
+		return nil
+	}
+}
+
+// WithDeclaredKeys declares keys that are neither registered nor defaulted, so strict mode accepts them
+func WithDeclaredKeys(keys ...string) EnvOption {
+	return func(em *EnvManager) diabuddyErrors.ApiErrors {
+		em.Declare(keys...)
+		return nil
+	}
+}
+
 // WithExtendedDefaults allows extending the default values during initialization
 func WithExtendedDefaults(extender DefaultExtender) EnvOption {
 	return func(em *EnvManager) diabuddyErrors.ApiErrors {
@@ -80,7 +165,6 @@ func NewEnvManager(options ...EnvOption) (*EnvManager, diabuddyErrors.ApiErrors)
 	em := &EnvManager{
 		useDefaults:  true,
 		useCache:     false,
-		environment:  "production",
 		defaults:     defaultValues(),
 		pathResolver: rootpath.NewRootPathResolver(),
 	}
@@ -92,6 +176,15 @@ func NewEnvManager(options ...EnvOption) (*EnvManager, diabuddyErrors.ApiErrors)
 		}
 	}
 
+	if !em.environmentSet {
+		environment, err := em.resolveEnvironment()
+		if err != nil {
+			return nil, err
+		}
+		em.environment = environment
+	}
+	em.defaults[AppEnvKey] = string(em.environment)
+
 	// Load environment variables from file
 	err := em.LoadEnvironmentVariables()
 	if err != nil {
@@ -101,6 +194,31 @@ func NewEnvManager(options ...EnvOption) (*EnvManager, diabuddyErrors.ApiErrors)
 	return em, nil
 }
 
+// Environment returns the resolved environment. It is the single source of truth for APP_ENV: the
+// WithEnvironment option wins, then APP_ENV from the process environment, then APP_ENV from the base .env
+// file, and finally DefaultEnvironment.
+func (em *EnvManager) Environment() Environment {
+	return em.environment
+}
+
+func (em *EnvManager) resolveEnvironment() (Environment, diabuddyErrors.ApiErrors) {
+	if environment := NormalizeEnvironment(os.Getenv(AppEnvKey)); environment != "" {
+		return environment, nil
+	}
+
+	envDir, err := em.envDir()
+	if err != nil {
+		return "", err
+	}
+	if envMaps, readErr := godotenv.Read(filepath.Join(envDir, ".env")); readErr == nil {
+		if environment := NormalizeEnvironment(envMaps[AppEnvKey]); environment != "" {
+			return environment, nil
+		}
+	}
+
+	return DefaultEnvironment, nil
+}
+
 // LoadEnvironmentVariables loads environment variables from the appropriate .env file based on the environment
 func (em *EnvManager) LoadEnvironmentVariables() diabuddyErrors.ApiErrors {
 	envFilepath, apiError := em.getEnvFilePath()
@@ -108,29 +226,44 @@ func (em *EnvManager) LoadEnvironmentVariables() diabuddyErrors.ApiErrors {
 		return apiError
 	}
 
-	err := godotenv.Load(envFilepath)
+	envMaps, err := godotenv.Read(envFilepath)
 	if err != nil {
 		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("failed to load environment variables from: %s file.", envFilepath), diabuddyErrors.WithInternalError(err))
 	}
 
+	// Like godotenv.Load, never override variables that are already present in the process environment.
+	for key, value := range envMaps {
+		em.fileValues.Store(key, value)
+		if _, ok := os.LookupEnv(key); !ok {
+			_ = os.Setenv(key, value)
+		}
+	}
+
 	return nil
 }
 
+// getEnvFilePath returns .env.<environment> if that file exists and .env otherwise.
 func (em *EnvManager) getEnvFilePath() (string, diabuddyErrors.ApiErrors) {
-	basePath, err := filepath.Abs("./")
-	if err != nil {
-		return "", diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "could not find appconfig root directory", diabuddyErrors.WithInternalError(err))
+	envDir, apiError := em.envDir()
+	if apiError != nil {
+		return "", apiError
 	}
-	envDir, resolveError := em.pathResolver.Resolve(basePath)
-	if resolveError != nil {
-		return "", resolveError
+
+	if em.environment != "" {
+		environmentFile := filepath.Join(envDir, ".env."+string(em.environment))
+		if info, err := os.Stat(environmentFile); err == nil && !info.IsDir() {
+			return environmentFile, nil
+		}
 	}
+	return filepath.Join(envDir, ".env"), nil
+}
 
-	envFileName := ".env"
-	if em.environment == "test" {
-		envFileName = ".env.test"
+func (em *EnvManager) envDir() (string, diabuddyErrors.ApiErrors) {
+	basePath, err := filepath.Abs("./")
+	if err != nil {
+		return "", diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "could not find appconfig root directory", diabuddyErrors.WithInternalError(err))
 	}
-	return filepath.Join(envDir, envFileName), nil
+	return em.pathResolver.Resolve(basePath)
 }
 
 func (em *EnvManager) ReadEnvironmentVariables() (map[string]string, diabuddyErrors.ApiErrors) {
@@ -148,73 +281,215 @@ func (em *EnvManager) ReadEnvironmentVariables() (map[string]string, diabuddyErr
 
 }
 
-// Get retrieves an environment variable value. If it's not set, it will use the default value if enabled.
+// Get retrieves an environment variable value. If it's not set, the call-site default wins over the registered
+// default, which is only used if enabled. APP_ENV is always the resolved environment.
 func (em *EnvManager) Get(key string, defaultValue ...string) string {
+	value, found, source := em.Lookup(key)
+	if key != AppEnvKey && len(defaultValue) > 0 && defaultValue[0] != "" && (!found || source == SourceDefault) {
+		return defaultValue[0]
+	}
+	return value
+}
+
+// GetList retrieves a comma separated environment variable as a list, trimming every item and dropping empty ones.
+func (em *EnvManager) GetList(key string, defaultValue ...string) []string {
+	return SplitList(em.Get(key, defaultValue...))
+}
+
+// SplitList splits a comma separated value the way GetList does.
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Lookup retrieves an environment variable value and reports whether it was found and where it came from:
+// SourceEnvironment, SourceEnvFile or SourceDefault. An empty value only counts as found under the
+// EmptyOverridesDefault policy. APP_ENV is reported as the resolved environment, see Environment.
+func (em *EnvManager) Lookup(key string) (string, bool, string) {
+	em.checkDeclared(key)
+	if key == AppEnvKey {
+		return em.lookupEnvironment()
+	}
+
 	// First, attempt to retrieve from cache
-	if val, ok := em.getFromCache(key); ok {
-		return val
+	if result, ok := em.getFromCache(key); ok {
+		return result.value, result.found, result.source
 	}
 
-	// Retrieve from environment
-	val := os.Getenv(key)
-	if val == "" && len(defaultValue) > 0 {
-		val = defaultValue[0]
+	result := em.lookup(key)
+
+	// Store in cache for future reference
+	em.storeInCache(key, result)
+	return result.value, result.found, result.source
+}
+
+func (em *EnvManager) lookup(key string) lookupResult {
+	if value, ok := os.LookupEnv(key); ok && (value != "" || em.emptyValuePolicy == EmptyOverridesDefault) {
+		source := SourceEnvironment
+		if fileValue, loaded := em.fileValues.Load(key); loaded && fileValue.(string) == value {
+			source = SourceEnvFile
+		}
+		return lookupResult{value: value, found: true, source: source}
 	}
 
-	// Retrieve from defaults if necessary
-	if val == "" && em.useDefaults {
-		if defVal, ok := em.defaults[key]; ok {
-			val = defVal
+	if em.useDefaults {
+		if value, ok := em.defaults[key]; ok && value != "" {
+			return lookupResult{value: value, found: true, source: SourceDefault}
 		}
 	}
 
-	// Store in cache for future reference
-	em.storeInCache(key, val)
-	return val
+	return lookupResult{}
+}
+
+// lookupEnvironment returns the resolved environment as the value of APP_ENV. The source is where APP_ENV was
+// read from, or SourceDefault when WithEnvironment or DefaultEnvironment decided it.
+func (em *EnvManager) lookupEnvironment() (string, bool, string) {
+	value := string(em.environment)
+	if result := em.lookup(AppEnvKey); result.found && NormalizeEnvironment(result.value) == em.environment {
+		return value, true, result.source
+	}
+	return value, value != "", SourceDefault
+}
+
+// Declare marks keys as known to strict mode.
+func (em *EnvManager) Declare(keys ...string) {
+	for _, key := range keys {
+		em.declared.Store(key, struct{}{})
+	}
 }
 
-// Defaults provides default values for environment variables.
+// IsDeclared reports whether a key is registered, has a default or was declared on this EnvManager.
+func (em *EnvManager) IsDeclared(key string) bool {
+	if _, ok := LookupKeyDefinition(key); ok {
+		return true
+	}
+	if _, ok := em.defaults[key]; ok {
+		return true
+	}
+	_, ok := em.declared.Load(key)
+	return ok
+}
+
+// UndeclaredReads returns the sorted undeclared keys that have been read so far.
+func (em *EnvManager) UndeclaredReads() []string {
+	var keys []string
+	em.undeclared.Range(func(key, _ any) bool {
+		keys = append(keys, key.(string))
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// UndeclaredReadCount returns how many reads of undeclared keys happened, counting repeated reads.
+func (em *EnvManager) UndeclaredReadCount() uint64 {
+	return em.undeclaredReads.Load()
+}
+
+// ReportUndeclaredReads fails the test when it ends if any undeclared key was read in the meantime.
+func (em *EnvManager) ReportUndeclaredReads(t TestingT) {
+	t.Helper()
+	t.Cleanup(func() {
+		if keys := em.UndeclaredReads(); len(keys) > 0 {
+			t.Errorf("envmanager: undeclared environment variable(s) read: %s", strings.Join(keys, ", "))
+		}
+	})
+}
+
+func (em *EnvManager) effectiveStrictMode() StrictMode {
+	if em.strictMode != StrictOff && em.environment.IsProduction() {
+		return StrictWarn
+	}
+	return em.strictMode
+}
+
+func (em *EnvManager) checkDeclared(key string) {
+	mode := em.effectiveStrictMode()
+	if mode == StrictOff || em.IsDeclared(key) {
+		return
+	}
+
+	em.undeclaredReads.Add(1)
+	switch mode {
+	case StrictPanic:
+		panic(fmt.Sprintf("envmanager: read of undeclared environment variable %q", key))
+	case StrictRecord:
+		em.undeclared.Store(key, struct{}{})
+	case StrictWarn:
+		if _, seen := em.undeclared.LoadOrStore(key, struct{}{}); !seen {
+			log.Printf("envmanager: read of undeclared environment variable %q", key)
+		}
+	}
+}
+
+// Defaults provides default values for environment variables, taken from the key registry.
 func defaultValues() map[string]string {
-	return map[string]string{
-		AppNameKey:           "default_app",
-		AppEnvKey:            "local",
-		AppEncryptionKey:     "",
-		AppDebugKey:          "false",
-		AppUrlKey:            "http://localhost",
-		AppTimezoneKey:       "UTC",
-		AppLocaleKey:         "en",
-		AppFallbackLocaleKey: "en",
-		AppCipherKey:         "AES-256-CBC",
-		AuthSecretKey:        "my_default_secret",
-		DbHostKey:            "127.0.0.1",
-		DbPortKey:            "5432",
-		DbDatabaseKey:        "default_db",
-		DbUsernameKey:        "default_user",
-		DbPasswordKey:        "default_pass",
-		DbSslModeKey:         "disable",
+	defaults := make(map[string]string)
+	for _, definition := range KeyDefinitions() {
+		defaults[definition.Name] = definition.Default
 	}
+	return defaults
 }
 
 // Private method to get value from cache
-func (em *EnvManager) getFromCache(key string) (string, bool) {
+func (em *EnvManager) getFromCache(key string) (lookupResult, bool) {
 	if em.useCache {
 		if val, ok := em.cache.Load(key); ok {
-			return val.(string), true
+			return val.(lookupResult), true
 		}
 	}
-	return "", false
+	return lookupResult{}, false
 }
 
 // Private method to store value in cache
-func (em *EnvManager) storeInCache(key, value string) {
+func (em *EnvManager) storeInCache(key string, result lookupResult) {
 	if em.useCache {
-		em.cache.Store(key, value)
+		em.cache.Store(key, result)
 	}
 }
 
 // ClearCache clears all the cached environment variables.
 func (em *EnvManager) ClearCache() {
 	if em.useCache {
-		em.cache = sync.Map{}
+		em.cache.Range(func(key, _ any) bool {
+			em.cache.Delete(key)
+			return true
+		})
+	}
+}
+
+// Reload re-reads the env file. Values that were loaded from the file are replaced, or removed when the file no
+// longer sets them; values set in the process environment are kept. The cache is cleared.
+func (em *EnvManager) Reload() diabuddyErrors.ApiErrors {
+	envMaps, apiError := em.ReadEnvironmentVariables()
+	if apiError != nil {
+		return apiError
 	}
+
+	em.fileValues.Range(func(key, previous any) bool {
+		name := key.(string)
+		if current, ok := os.LookupEnv(name); ok && current == previous.(string) {
+			if value, stillSet := envMaps[name]; stillSet {
+				_ = os.Setenv(name, value)
+			} else {
+				_ = os.Unsetenv(name)
+			}
+		}
+		em.fileValues.Delete(key)
+		return true
+	})
+	for key, value := range envMaps {
+		em.fileValues.Store(key, value)
+		if _, ok := os.LookupEnv(key); !ok {
+			_ = os.Setenv(key, value)
+		}
+	}
+
+	em.ClearCache()
+	return nil
 }
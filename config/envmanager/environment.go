@@ -0,0 +1,67 @@
+package envmanager
+
+import "strings"
+
+// Environment is a normalized application environment.
+type Environment string
+
+const (
+	Production Environment = "production"
+	Staging    Environment = "staging"
+	Local      Environment = "local"
+	Test       Environment = "test"
+)
+
+// DefaultEnvironment is used when neither WithEnvironment nor APP_ENV name an environment.
+const DefaultEnvironment = Local
+
+var environmentAliases = map[string]Environment{
+	"production":  Production,
+	"prod":        Production,
+	"staging":     Staging,
+	"stage":       Staging,
+	"local":       Local,
+	"dev":         Local,
+	"development": Local,
+	"test":        Test,
+	"testing":     Test,
+}
+
+// NormalizeEnvironment trims and lowercases the value and resolves known aliases such as prod and dev.
+// Unknown values are kept, so services can still use environments of their own.
+func NormalizeEnvironment(value string) Environment {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	if environment, ok := environmentAliases[normalized]; ok {
+		return environment
+	}
+	return Environment(normalized)
+}
+
+// IsKnown reports whether the environment is one of the environments defined by this package.
+func (e Environment) IsKnown() bool {
+	switch e {
+	case Production, Staging, Local, Test:
+		return true
+	}
+	return false
+}
+
+func (e Environment) IsProduction() bool {
+	return e == Production
+}
+
+func (e Environment) IsStaging() bool {
+	return e == Staging
+}
+
+func (e Environment) IsLocal() bool {
+	return e == Local
+}
+
+func (e Environment) IsTest() bool {
+	return e == Test
+}
+
+func (e Environment) String() string {
+	return string(e)
+}
@@ -0,0 +1,35 @@
+package envmanager
+
+const (
+	KafkaBrokersKey               = "KAFKA_BROKERS"
+	KafkaClientIDKey              = "KAFKA_CLIENT_ID"
+	KafkaSASLMechanismKey         = "KAFKA_SASL_MECHANISM"
+	KafkaSASLUsernameKey          = "KAFKA_SASL_USERNAME"
+	KafkaSASLPasswordKey          = "KAFKA_SASL_PASSWORD"
+	KafkaTLSEnabledKey            = "KAFKA_TLS_ENABLED"
+	KafkaTLSCAFileKey             = "KAFKA_TLS_CA_FILE"
+	KafkaTLSCertFileKey           = "KAFKA_TLS_CERT_FILE"
+	KafkaTLSKeyFileKey            = "KAFKA_TLS_KEY_FILE"
+	KafkaTLSInsecureSkipVerifyKey = "KAFKA_TLS_INSECURE_SKIP_VERIFY"
+	KafkaConsumerGroupKey         = "KAFKA_CONSUMER_GROUP"
+	KafkaTopicPrefixKey           = "KAFKA_TOPIC_PREFIX"
+	KafkaTopicSeparatorKey        = "KAFKA_TOPIC_SEPARATOR"
+)
+
+func kafkaKeyDefinitions() []KeyDefinition {
+	return []KeyDefinition{
+		{Name: KafkaBrokersKey, Section: KafkaSection, Type: ListKey, Description: "Comma separated host:port addresses of the Kafka brokers; required by the Kafka section."},
+		{Name: KafkaClientIDKey, Section: KafkaSection, Description: "Client ID sent to the brokers; defaults to APP_NAME."},
+		{Name: KafkaSASLMechanismKey, Section: KafkaSection, Enum: []string{"PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512"}, Description: "SASL mechanism; empty disables SASL."},
+		{Name: KafkaSASLUsernameKey, Section: KafkaSection, Description: "SASL user name."},
+		{Name: KafkaSASLPasswordKey, Section: KafkaSection, Sensitive: true, Description: "SASL password."},
+		{Name: KafkaTLSEnabledKey, Section: KafkaSection, Type: BooleanKey, Default: "false", Description: "Connects to the brokers over TLS."},
+		{Name: KafkaTLSCAFileKey, Section: KafkaSection, Description: "PEM file with the CA certificates that sign the broker certificates; empty uses the system pool."},
+		{Name: KafkaTLSCertFileKey, Section: KafkaSection, Description: "Client certificate for mutual TLS."},
+		{Name: KafkaTLSKeyFileKey, Section: KafkaSection, Description: "Private key of the client certificate."},
+		{Name: KafkaTLSInsecureSkipVerifyKey, Section: KafkaSection, Type: BooleanKey, Default: "false", Description: "Skips verification of the broker certificates; rejected in production."},
+		{Name: KafkaConsumerGroupKey, Section: KafkaSection, Description: "Consumer group ID; defaults to the client ID."},
+		{Name: KafkaTopicPrefixKey, Section: KafkaSection, Description: "Prefix of every topic name; defaults to APP_ENV."},
+		{Name: KafkaTopicSeparatorKey, Section: KafkaSection, Default: ".", Description: "Separator between the topic prefix and the topic name."},
+	}
+}
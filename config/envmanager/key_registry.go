@@ -0,0 +1,147 @@
+package envmanager
+
+import (
+	"sort"
+	"sync"
+)
+
+// Section names used to group the keys registered by this package.
+const (
+	AppSection           = "app"
+	AuthSection          = "auth"
+	CacheSection         = "cache"
+	DbSection            = "db"
+	ElasticsearchSection = "elasticsearch"
+	KafkaSection         = "kafka"
+	LogSection           = "log"
+	MailSection          = "mail"
+	ObservabilitySection = "observability"
+	SecuritySection      = "security"
+	ServerSection        = "server"
+	StorageSection       = "storage"
+)
+
+// KeyType describes how the value of a key is interpreted.
+type KeyType string
+
+const (
+	StringKey   KeyType = "string"
+	IntegerKey  KeyType = "integer"
+	NumberKey   KeyType = "number"
+	BooleanKey  KeyType = "boolean"
+	DurationKey KeyType = "duration"
+	ListKey     KeyType = "list"
+)
+
+// KeyDefinition describes an environment variable the library knows about.
+// An empty Type is treated as StringKey; Enum, when set, lists the only accepted values.
+type KeyDefinition struct {
+	Name        string
+	Section     string
+	Type        KeyType
+	Enum        []string
+	Default     string
+	Description string
+	Required    bool
+	Sensitive   bool
+}
+
+// ValueType returns the type of the key, defaulting to StringKey.
+func (kd KeyDefinition) ValueType() KeyType {
+	if kd.Type == "" {
+		return StringKey
+	}
+	return kd.Type
+}
+
+var (
+	registryMu    sync.RWMutex
+	registry      = map[string]KeyDefinition{}
+	registryOrder []string
+)
+
+func init() {
+	RegisterKeys(coreKeyDefinitions()...)
+	RegisterKeys(serverKeyDefinitions()...)
+	RegisterKeys(authKeyDefinitions()...)
+	RegisterKeys(logKeyDefinitions()...)
+	RegisterKeys(securityKeyDefinitions()...)
+	RegisterKeys(kafkaKeyDefinitions()...)
+	RegisterKeys(elasticsearchKeyDefinitions()...)
+	RegisterKeys(cacheKeyDefinitions()...)
+	RegisterKeys(mailKeyDefinitions()...)
+	RegisterKeys(storageKeyDefinitions()...)
+	RegisterKeys(observabilityKeyDefinitions()...)
+}
+
+// RegisterKeys adds key definitions to the global registry. Registering a key twice replaces its definition
+// but keeps its original position.
+func RegisterKeys(definitions ...KeyDefinition) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, definition := range definitions {
+		if _, ok := registry[definition.Name]; !ok {
+			registryOrder = append(registryOrder, definition.Name)
+		}
+		registry[definition.Name] = definition
+	}
+}
+
+// LookupKeyDefinition returns the registered definition for the given key.
+func LookupKeyDefinition(name string) (KeyDefinition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	definition, ok := registry[name]
+	return definition, ok
+}
+
+// KeyDefinitions returns all registered keys ordered by section name and then by registration order,
+// so that anything generated from it is stable between runs.
+func KeyDefinitions() []KeyDefinition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	definitions := make([]KeyDefinition, 0, len(registryOrder))
+	for _, name := range registryOrder {
+		definitions = append(definitions, registry[name])
+	}
+	sort.SliceStable(definitions, func(i, j int) bool {
+		return definitions[i].Section < definitions[j].Section
+	})
+	return definitions
+}
+
+// SectionKeyDefinitions returns the registered keys of a single section in registration order.
+func SectionKeyDefinitions(section string) []KeyDefinition {
+	var definitions []KeyDefinition
+	for _, definition := range KeyDefinitions() {
+		if definition.Section == section {
+			definitions = append(definitions, definition)
+		}
+	}
+	return definitions
+}
+
+func coreKeyDefinitions() []KeyDefinition {
+	return []KeyDefinition{
+		{Name: AppNameKey, Section: AppSection, Default: "default_app", Required: true, Description: "Application name, used in logs and as the default client identifier."},
+		{Name: AppEnvKey, Section: AppSection, Default: "local", Required: true, Description: "Application environment such as local, test or production."},
+		{Name: AppEncryptionKey, Section: AppSection, Sensitive: true, Description: "Application encryption key of 32 bytes, usually written as base64:<key>; required outside local and test."},
+		{Name: AppPreviousKeysKey, Section: AppSection, Type: ListKey, Sensitive: true, Description: "Comma separated retired encryption keys that are still accepted for decryption."},
+		{Name: AppDebugKey, Section: AppSection, Type: BooleanKey, Default: "false", Required: true, Description: "Enables debug behaviour when set to true."},
+		{Name: AppUrlKey, Section: AppSection, Default: "http://localhost", Required: true, Description: "Public base URL of the application."},
+		{Name: AppTimezoneKey, Section: AppSection, Default: "UTC", Description: "IANA time zone used by the application."},
+		{Name: AppLocaleKey, Section: AppSection, Default: "en", Description: "Default locale."},
+		{Name: AppFallbackLocaleKey, Section: AppSection, Default: "en", Description: "Locale used when a translation is missing in the default locale."},
+		{Name: AppSupportedLocalesKey, Section: AppSection, Type: ListKey, Description: "Comma separated locales the application serves; empty means APP_LOCALE and APP_FALLBACK_LOCALE."},
+		{Name: AppCipherKey, Section: AppSection, Enum: []string{"AES-256-CBC", "AES-256-GCM"}, Default: "AES-256-CBC", Description: "Cipher used together with APP_KEY."},
+		{Name: AppBasePathKey, Section: AppSection, Description: "Application root directory; when empty the module root or the executable's directory is used."},
+		{Name: DbUrlKey, Section: DbSection, Description: "Full database URL; takes precedence over the individual DB_* keys."},
+		{Name: DbHostKey, Section: DbSection, Default: "127.0.0.1", Required: true, Description: "Database host."},
+		{Name: DbPortKey, Section: DbSection, Type: IntegerKey, Default: "5432", Description: "Database port; defaults to the standard port of the database type."},
+		{Name: DbDatabaseKey, Section: DbSection, Default: "default_db", Required: true, Description: "Database name."},
+		{Name: DbUsernameKey, Section: DbSection, Default: "default_user", Required: true, Description: "Database user."},
+		{Name: DbPasswordKey, Section: DbSection, Default: "default_pass", Required: true, Sensitive: true, Description: "Database password."},
+		{Name: DbSslModeKey, Section: DbSection, Default: "disable", Description: "SSL mode passed to the database driver."},
+		{Name: DbConnectionsKey, Section: DbSection, Type: ListKey, Description: "Comma separated names of additional connections; DB_<NAME>_HOST and friends fall back to the shared DB_* keys."},
+	}
+}
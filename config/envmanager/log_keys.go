@@ -0,0 +1,15 @@
+package envmanager
+
+const (
+	LogLevelKey  = "LOG_LEVEL"
+	LogFormatKey = "LOG_FORMAT"
+	LogOutputKey = "LOG_OUTPUT"
+)
+
+func logKeyDefinitions() []KeyDefinition {
+	return []KeyDefinition{
+		{Name: LogLevelKey, Section: LogSection, Enum: []string{"debug", "info", "warn", "error"}, Default: "info", Description: "Minimum log level; APP_DEBUG=true lowers it to debug."},
+		{Name: LogFormatKey, Section: LogSection, Enum: []string{"json", "text"}, Default: "json", Description: "Log record format."},
+		{Name: LogOutputKey, Section: LogSection, Default: "stdout", Description: "Log destination: stdout, stderr or a file path that is appended to."},
+	}
+}
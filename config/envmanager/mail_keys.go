@@ -0,0 +1,29 @@
+package envmanager
+
+const (
+	MailDriverKey      = "MAIL_DRIVER"
+	MailHostKey        = "MAIL_HOST"
+	MailPortKey        = "MAIL_PORT"
+	MailEncryptionKey  = "MAIL_ENCRYPTION"
+	MailUsernameKey    = "MAIL_USERNAME"
+	MailPasswordKey    = "MAIL_PASSWORD"
+	MailFromAddressKey = "MAIL_FROM_ADDRESS"
+	MailFromNameKey    = "MAIL_FROM_NAME"
+	MailDialTimeoutKey = "MAIL_DIAL_TIMEOUT"
+	MailSendTimeoutKey = "MAIL_SEND_TIMEOUT"
+)
+
+func mailKeyDefinitions() []KeyDefinition {
+	return []KeyDefinition{
+		{Name: MailDriverKey, Section: MailSection, Enum: []string{"smtp", "log", "array"}, Default: "smtp", Description: "How mail is delivered; log and array are rejected in production."},
+		{Name: MailHostKey, Section: MailSection, Description: "Host of the SMTP server; required by the smtp driver."},
+		{Name: MailPortKey, Section: MailSection, Type: IntegerKey, Description: "Port of the SMTP server; defaults to 465 with tls, 587 with starttls and 25 with none."},
+		{Name: MailEncryptionKey, Section: MailSection, Enum: []string{"starttls", "tls", "none"}, Default: "starttls", Description: "How the SMTP connection is encrypted; none is rejected in production when credentials are set."},
+		{Name: MailUsernameKey, Section: MailSection, Description: "SMTP user name; empty disables authentication."},
+		{Name: MailPasswordKey, Section: MailSection, Sensitive: true, Description: "SMTP password."},
+		{Name: MailFromAddressKey, Section: MailSection, Description: "Sender address of outgoing mail; required by the smtp driver."},
+		{Name: MailFromNameKey, Section: MailSection, Description: "Sender name of outgoing mail; defaults to APP_NAME."},
+		{Name: MailDialTimeoutKey, Section: MailSection, Type: DurationKey, Default: "10s", Description: "Maximum duration for connecting to the SMTP server."},
+		{Name: MailSendTimeoutKey, Section: MailSection, Type: DurationKey, Default: "30s", Description: "Maximum duration of the SMTP conversation for one message."},
+	}
+}
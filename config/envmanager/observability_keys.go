@@ -0,0 +1,35 @@
+package envmanager
+
+const (
+	OtelSDKDisabledKey             = "OTEL_SDK_DISABLED"
+	OtelServiceNameKey             = "OTEL_SERVICE_NAME"
+	OtelResourceAttributesKey      = "OTEL_RESOURCE_ATTRIBUTES"
+	OtelTracesSamplerKey           = "OTEL_TRACES_SAMPLER"
+	OtelTracesSamplerArgKey        = "OTEL_TRACES_SAMPLER_ARG"
+	OtelTracesExporterKey          = "OTEL_TRACES_EXPORTER"
+	OtelMetricsExporterKey         = "OTEL_METRICS_EXPORTER"
+	OtelExporterOTLPEndpointKey    = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	OtelExporterOTLPProtocolKey    = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	OtelExporterOTLPHeadersKey     = "OTEL_EXPORTER_OTLP_HEADERS"
+	OtelExporterOTLPTimeoutKey     = "OTEL_EXPORTER_OTLP_TIMEOUT"
+	OtelExporterOTLPCompressionKey = "OTEL_EXPORTER_OTLP_COMPRESSION"
+	OtelMetricExportIntervalKey    = "OTEL_METRIC_EXPORT_INTERVAL"
+)
+
+func observabilityKeyDefinitions() []KeyDefinition {
+	return []KeyDefinition{
+		{Name: OtelSDKDisabledKey, Section: ObservabilitySection, Type: BooleanKey, Default: "false", Description: "Disables tracing and metrics."},
+		{Name: OtelServiceNameKey, Section: ObservabilitySection, Description: "Value of the service.name resource attribute; defaults to APP_NAME."},
+		{Name: OtelResourceAttributesKey, Section: ObservabilitySection, Description: "Comma separated key=value resource attributes with percent-encoded values; deployment.environment.name defaults to APP_ENV."},
+		{Name: OtelTracesSamplerKey, Section: ObservabilitySection, Enum: []string{"always_on", "always_off", "traceidratio", "parentbased_always_on", "parentbased_always_off", "parentbased_traceidratio"}, Default: "parentbased_always_on", Description: "Sampler of new traces."},
+		{Name: OtelTracesSamplerArgKey, Section: ObservabilitySection, Type: NumberKey, Description: "Sampling ratio between 0 and 1 for the traceidratio samplers; defaults to 1."},
+		{Name: OtelTracesExporterKey, Section: ObservabilitySection, Enum: []string{"otlp", "console", "none"}, Default: "otlp", Description: "Exporter of traces."},
+		{Name: OtelMetricsExporterKey, Section: ObservabilitySection, Enum: []string{"otlp", "console", "none"}, Default: "otlp", Description: "Exporter of metrics."},
+		{Name: OtelExporterOTLPEndpointKey, Section: ObservabilitySection, Description: "Base URL of the OTLP receiver; defaults to http://localhost:4317 for grpc and http://localhost:4318 otherwise."},
+		{Name: OtelExporterOTLPProtocolKey, Section: ObservabilitySection, Enum: []string{"grpc", "http/protobuf", "http/json"}, Default: "http/protobuf", Description: "Transport of the OTLP exporters."},
+		{Name: OtelExporterOTLPHeadersKey, Section: ObservabilitySection, Sensitive: true, Description: "Comma separated key=value headers sent with every export, e.g. an API key."},
+		{Name: OtelExporterOTLPTimeoutKey, Section: ObservabilitySection, Type: IntegerKey, Default: "10000", Description: "Maximum duration of one export in milliseconds."},
+		{Name: OtelExporterOTLPCompressionKey, Section: ObservabilitySection, Enum: []string{"gzip", "none"}, Default: "none", Description: "Compression of the exported data."},
+		{Name: OtelMetricExportIntervalKey, Section: ObservabilitySection, Type: IntegerKey, Default: "60000", Description: "Interval between metric exports in milliseconds."},
+	}
+}
@@ -0,0 +1,29 @@
+package envmanager
+
+const (
+	CorsAllowedOriginsKey            = "CORS_ALLOWED_ORIGINS"
+	CorsAllowedMethodsKey            = "CORS_ALLOWED_METHODS"
+	CorsAllowedHeadersKey            = "CORS_ALLOWED_HEADERS"
+	CorsAllowCredentialsKey          = "CORS_ALLOW_CREDENTIALS"
+	CorsMaxAgeKey                    = "CORS_MAX_AGE"
+	SecurityHSTSMaxAgeKey            = "SECURITY_HSTS_MAX_AGE"
+	SecurityHSTSIncludeSubdomainsKey = "SECURITY_HSTS_INCLUDE_SUBDOMAINS"
+	SecurityCSPKey                   = "SECURITY_CSP"
+	SecurityFrameOptionsKey          = "SECURITY_FRAME_OPTIONS"
+)
+
+// securityKeyDefinitions registers the locked-down production defaults; local and test environments get the
+// permissive defaults of securityconfig instead.
+func securityKeyDefinitions() []KeyDefinition {
+	return []KeyDefinition{
+		{Name: CorsAllowedOriginsKey, Section: SecuritySection, Type: ListKey, Description: "Comma separated origins allowed to make cross-origin requests; * matches any subdomain part, e.g. https://*.example.com. APP_URL is always allowed. Local and test default to *."},
+		{Name: CorsAllowedMethodsKey, Section: SecuritySection, Type: ListKey, Default: "GET,POST,PUT,PATCH,DELETE,OPTIONS", Description: "Comma separated methods allowed in cross-origin requests."},
+		{Name: CorsAllowedHeadersKey, Section: SecuritySection, Type: ListKey, Default: "Accept,Authorization,Content-Type,X-Requested-With", Description: "Comma separated request headers allowed in cross-origin requests; * allows any. Local and test default to *."},
+		{Name: CorsAllowCredentialsKey, Section: SecuritySection, Type: BooleanKey, Default: "false", Description: "Whether cross-origin requests may carry cookies and authorization headers."},
+		{Name: CorsMaxAgeKey, Section: SecuritySection, Type: DurationKey, Default: "10m", Description: "How long browsers may cache a preflight response."},
+		{Name: SecurityHSTSMaxAgeKey, Section: SecuritySection, Type: DurationKey, Default: "8760h", Description: "Max age of the Strict-Transport-Security header; 0 disables it. Local and test default to 0."},
+		{Name: SecurityHSTSIncludeSubdomainsKey, Section: SecuritySection, Type: BooleanKey, Default: "true", Description: "Whether HSTS also covers subdomains."},
+		{Name: SecurityCSPKey, Section: SecuritySection, Default: "default-src 'none'; frame-ancestors 'none'", Description: "Content-Security-Policy header; none disables it. Local and test default to no header."},
+		{Name: SecurityFrameOptionsKey, Section: SecuritySection, Enum: []string{"DENY", "SAMEORIGIN", "none"}, Default: "DENY", Description: "X-Frame-Options header; none disables it. Local and test default to SAMEORIGIN."},
+	}
+}
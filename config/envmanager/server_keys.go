@@ -0,0 +1,31 @@
+package envmanager
+
+const (
+	ServerHostKey              = "SERVER_HOST"
+	ServerPortKey              = "APP_PORT"
+	ServerReadTimeoutKey       = "SERVER_READ_TIMEOUT"
+	ServerReadHeaderTimeoutKey = "SERVER_READ_HEADER_TIMEOUT"
+	ServerWriteTimeoutKey      = "SERVER_WRITE_TIMEOUT"
+	ServerIdleTimeoutKey       = "SERVER_IDLE_TIMEOUT"
+	ServerMaxHeaderBytesKey    = "SERVER_MAX_HEADER_BYTES"
+	ServerShutdownTimeoutKey   = "SERVER_SHUTDOWN_TIMEOUT"
+	ServerTrustedProxiesKey    = "SERVER_TRUSTED_PROXIES"
+	ServerTLSCertFileKey       = "SERVER_TLS_CERT_FILE"
+	ServerTLSKeyFileKey        = "SERVER_TLS_KEY_FILE"
+)
+
+func serverKeyDefinitions() []KeyDefinition {
+	return []KeyDefinition{
+		{Name: ServerHostKey, Section: ServerSection, Description: "Interface the HTTP server listens on; empty means all interfaces."},
+		{Name: ServerPortKey, Section: ServerSection, Type: IntegerKey, Default: "8080", Required: true, Description: "Port the HTTP server listens on."},
+		{Name: ServerReadTimeoutKey, Section: ServerSection, Type: DurationKey, Default: "15s", Description: "Maximum duration for reading an entire request, including the body."},
+		{Name: ServerReadHeaderTimeoutKey, Section: ServerSection, Type: DurationKey, Default: "5s", Description: "Maximum duration for reading the request headers."},
+		{Name: ServerWriteTimeoutKey, Section: ServerSection, Type: DurationKey, Default: "15s", Description: "Maximum duration before timing out writes of the response."},
+		{Name: ServerIdleTimeoutKey, Section: ServerSection, Type: DurationKey, Default: "60s", Description: "Maximum time to wait for the next request on a keep-alive connection."},
+		{Name: ServerMaxHeaderBytesKey, Section: ServerSection, Type: IntegerKey, Default: "1048576", Description: "Maximum size of the request headers in bytes."},
+		{Name: ServerShutdownTimeoutKey, Section: ServerSection, Type: DurationKey, Default: "10s", Description: "Time given to in-flight requests during a graceful shutdown."},
+		{Name: ServerTrustedProxiesKey, Section: ServerSection, Type: ListKey, Description: "Comma separated IPs or CIDRs of proxies whose forwarding headers are trusted."},
+		{Name: ServerTLSCertFileKey, Section: ServerSection, Description: "Path to the TLS certificate; TLS is enabled when both certificate and key are set."},
+		{Name: ServerTLSKeyFileKey, Section: ServerSection, Description: "Path to the TLS private key."},
+	}
+}
@@ -0,0 +1,13 @@
+package envmanager
+
+const (
+	StorageDisksKey       = "STORAGE_DISKS"
+	StorageDefaultDiskKey = "STORAGE_DEFAULT_DISK"
+)
+
+func storageKeyDefinitions() []KeyDefinition {
+	return []KeyDefinition{
+		{Name: StorageDisksKey, Section: StorageSection, Type: ListKey, Default: "local", Description: "Comma separated names of the storage disks; each is configured with STORAGE_<NAME>_DRIVER and friends."},
+		{Name: StorageDefaultDiskKey, Section: StorageSection, Description: "Disk used when none is named; defaults to the first disk in STORAGE_DISKS."},
+	}
+}
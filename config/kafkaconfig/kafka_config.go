@@ -0,0 +1,292 @@
+package kafkaconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// SASL mechanisms accepted in KAFKA_SASL_MECHANISM.
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+var requiredKeys = []string{envmanager.KafkaBrokersKey}
+
+var (
+	_ config.Introspectable = (*KafkaConfig)(nil)
+	_ config.Reloader       = (*KafkaConfig)(nil)
+)
+
+// SASL holds the SASL settings of Settings.
+type SASL struct {
+	Mechanism string
+	Username  string
+	Password  string
+}
+
+// Settings is the client independent result of KafkaConfig, meant to be mapped onto the options of whichever
+// Kafka client a service uses.
+type Settings struct {
+	Brokers       []string
+	ClientID      string
+	ConsumerGroup string
+	TopicPrefix   string
+	// TopicSeparator joins TopicPrefix and a topic name.
+	TopicSeparator string
+	// SASL is nil when SASL is disabled.
+	SASL *SASL
+	// TLS is nil when TLS is disabled.
+	TLS *tls.Config
+}
+
+// Topic returns the full name of a topic: the prefix, the separator and the name, e.g. production.user-created.
+func (s Settings) Topic(name string) string {
+	if s.TopicPrefix == "" {
+		return name
+	}
+	return s.TopicPrefix + s.TopicSeparator + name
+}
+
+type KafkaConfig struct {
+	envManager *envmanager.EnvManager
+	// state holds the parsed values; Reload replaces it as a whole, so readers never see half a reload.
+	state atomic.Pointer[kafkaState]
+}
+
+type kafkaState struct {
+	brokers               []string
+	clientID              string
+	consumerGroup         string
+	topicPrefix           string
+	topicSeparator        string
+	saslMechanism         string
+	tlsEnabled            bool
+	tlsInsecureSkipVerify bool
+}
+
+// NewKafkaConfig creates a KafkaConfig from the KAFKA_* values. An unknown SASL mechanism fails here.
+func NewKafkaConfig(envManager *envmanager.EnvManager) (*KafkaConfig, diabuddyErrors.ApiErrors) {
+	kc := &KafkaConfig{envManager: envManager}
+	state, err := kc.resolve()
+	if err != nil {
+		return nil, err
+	}
+	kc.state.Store(state)
+	return kc, nil
+}
+
+func (kc *KafkaConfig) Get(key string, defaultValue ...string) string {
+	return kc.envManager.Get(key, defaultValue...)
+}
+
+// Brokers returns KAFKA_BROKERS.
+func (kc *KafkaConfig) Brokers() []string {
+	return append([]string(nil), kc.state.Load().brokers...)
+}
+
+// ClientID returns KAFKA_CLIENT_ID, or APP_NAME when it is empty.
+func (kc *KafkaConfig) ClientID() string {
+	return kc.state.Load().clientID
+}
+
+// ConsumerGroup returns KAFKA_CONSUMER_GROUP, or the client ID when it is empty.
+func (kc *KafkaConfig) ConsumerGroup() string {
+	return kc.state.Load().consumerGroup
+}
+
+// TopicPrefix returns KAFKA_TOPIC_PREFIX, or the environment when it is empty.
+func (kc *KafkaConfig) TopicPrefix() string {
+	return kc.state.Load().topicPrefix
+}
+
+// Topic returns the full name of a topic, e.g. production.user-created.
+func (kc *KafkaConfig) Topic(name string) string {
+	state := kc.state.Load()
+	return Settings{TopicPrefix: state.topicPrefix, TopicSeparator: state.topicSeparator}.Topic(name)
+}
+
+// SASLMechanism returns KAFKA_SASL_MECHANISM; empty means SASL is disabled.
+func (kc *KafkaConfig) SASLMechanism() string {
+	return kc.state.Load().saslMechanism
+}
+
+// TLSEnabled returns KAFKA_TLS_ENABLED.
+func (kc *KafkaConfig) TLSEnabled() bool {
+	return kc.state.Load().tlsEnabled
+}
+
+// TLSConfig returns the TLS configuration for the broker connections, loading the CA and client certificate
+// files; it returns nil when TLS is disabled.
+func (kc *KafkaConfig) TLSConfig() (*tls.Config, diabuddyErrors.ApiErrors) {
+	return kc.tlsConfig(kc.state.Load())
+}
+
+func (kc *KafkaConfig) tlsConfig(state *kafkaState) (*tls.Config, diabuddyErrors.ApiErrors) {
+	if !state.tlsEnabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: state.tlsInsecureSkipVerify}
+	if caFile := kc.Get(envmanager.KafkaTLSCAFileKey); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, unreadableFileError(envmanager.KafkaTLSCAFileKey, caFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, config.InvalidValueError(envmanager.KafkaTLSCAFileKey, caFile, "a PEM file with CA certificates", nil)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if certFile, keyFile := kc.Get(envmanager.KafkaTLSCertFileKey), kc.Get(envmanager.KafkaTLSKeyFileKey); certFile != "" && keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s and %s must hold a matching PEM certificate and key", envmanager.KafkaTLSCertFileKey, envmanager.KafkaTLSKeyFileKey), diabuddyErrors.WithInternalError(err))
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// Settings returns the Kafka settings as a plain struct.
+func (kc *KafkaConfig) Settings() (Settings, diabuddyErrors.ApiErrors) {
+	state := kc.state.Load()
+	tlsConfig, err := kc.tlsConfig(state)
+	if err != nil {
+		return Settings{}, err
+	}
+
+	settings := Settings{
+		Brokers:        append([]string(nil), state.brokers...),
+		ClientID:       state.clientID,
+		ConsumerGroup:  state.consumerGroup,
+		TopicPrefix:    state.topicPrefix,
+		TopicSeparator: state.topicSeparator,
+		TLS:            tlsConfig,
+	}
+	if state.saslMechanism != "" {
+		settings.SASL = &SASL{
+			Mechanism: state.saslMechanism,
+			Username:  kc.Get(envmanager.KafkaSASLUsernameKey),
+			Password:  kc.Get(envmanager.KafkaSASLPasswordKey),
+		}
+	}
+	return settings, nil
+}
+
+// Reload re-reads the Kafka values and swaps them in at once, so it may run while the section is read. On error
+// the previous values are kept.
+func (kc *KafkaConfig) Reload() diabuddyErrors.ApiErrors {
+	state, err := kc.resolve()
+	if err != nil {
+		return err
+	}
+	kc.state.Store(state)
+	return nil
+}
+
+// resolve reads and parses every Kafka value.
+func (kc *KafkaConfig) resolve() (*kafkaState, diabuddyErrors.ApiErrors) {
+	state := &kafkaState{}
+	brokers := kc.envManager.GetList(envmanager.KafkaBrokersKey)
+	for _, broker := range brokers {
+		if _, port, err := net.SplitHostPort(broker); err != nil || port == "" {
+			return nil, config.InvalidValueError(envmanager.KafkaBrokersKey, broker, "a host:port address", err)
+		}
+	}
+
+	saslMechanism := strings.ToUpper(kc.Get(envmanager.KafkaSASLMechanismKey))
+	if saslMechanism != "" && saslMechanism != SASLPlain && saslMechanism != SASLScramSHA256 && saslMechanism != SASLScramSHA512 {
+		return nil, config.InvalidValueError(envmanager.KafkaSASLMechanismKey, saslMechanism, "PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512", nil)
+	}
+
+	tlsEnabled, err := config.Bool(kc, envmanager.KafkaTLSEnabledKey)
+	if err != nil {
+		return nil, err
+	}
+	tlsInsecureSkipVerify, err := config.Bool(kc, envmanager.KafkaTLSInsecureSkipVerifyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID := kc.Get(envmanager.KafkaClientIDKey, kc.Get(envmanager.AppNameKey))
+	state.brokers = brokers
+	state.clientID = clientID
+	state.consumerGroup = kc.Get(envmanager.KafkaConsumerGroupKey, clientID)
+	state.topicPrefix = kc.Get(envmanager.KafkaTopicPrefixKey, kc.envManager.Environment().String())
+	state.topicSeparator = kc.Get(envmanager.KafkaTopicSeparatorKey)
+	state.saslMechanism = saslMechanism
+	state.tlsEnabled = tlsEnabled
+	state.tlsInsecureSkipVerify = tlsInsecureSkipVerify
+	return state, nil
+}
+
+func unreadableFileError(key, path string, err error) diabuddyErrors.ApiErrors {
+	return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s points to a file that cannot be read: %s", key, path), diabuddyErrors.WithInternalError(err))
+}
+
+// Keys returns the keys owned by the Kafka section.
+func (kc *KafkaConfig) Keys() []string {
+	return config.KeyNames(kc.Describe())
+}
+
+// RequiredKeys returns the keys Validate insists on; the SASL credentials join them when a mechanism is set.
+func (kc *KafkaConfig) RequiredKeys() []string {
+	keys := append([]string(nil), requiredKeys...)
+	if kc.state.Load().saslMechanism != "" {
+		keys = append(keys, envmanager.KafkaSASLUsernameKey, envmanager.KafkaSASLPasswordKey)
+	}
+	return keys
+}
+
+// Describe returns the definitions of the keys owned by the Kafka section.
+func (kc *KafkaConfig) Describe() []envmanager.KeyDefinition {
+	return envmanager.SectionKeyDefinitions(envmanager.KafkaSection)
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults.
+func (kc *KafkaConfig) Lookup(key string) (string, bool) {
+	value, found, _ := kc.envManager.Lookup(key)
+	return value, found
+}
+
+// Snapshot returns the current values of the Kafka section.
+func (kc *KafkaConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(kc, kc.Describe())
+}
+
+// Validate checks that the required keys are present, that the client certificate and key are set together and
+// that the TLS files can be loaded. Skipping certificate verification is rejected in production.
+func (kc *KafkaConfig) Validate() diabuddyErrors.ApiErrors {
+	var missingKeys []string
+	for _, key := range kc.RequiredKeys() {
+		if strings.TrimSpace(kc.Get(key)) == "" {
+			missingKeys = append(missingKeys, key)
+		}
+	}
+	if len(missingKeys) > 0 {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("missing required key(s): %s", strings.Join(missingKeys, ", ")))
+	}
+
+	if (kc.Get(envmanager.KafkaTLSCertFileKey) == "") != (kc.Get(envmanager.KafkaTLSKeyFileKey) == "") {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s and %s must be set together", envmanager.KafkaTLSCertFileKey, envmanager.KafkaTLSKeyFileKey))
+	}
+	state := kc.state.Load()
+	if state.tlsInsecureSkipVerify && kc.envManager.Environment().IsProduction() {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s must not be true in production", envmanager.KafkaTLSInsecureSkipVerifyKey))
+	}
+	if _, err := kc.tlsConfig(state); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package localeconfig
+
+import (
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	localeKeys   = []string{envmanager.AppLocaleKey, envmanager.AppFallbackLocaleKey, envmanager.AppSupportedLocalesKey}
+	requiredKeys = []string{envmanager.AppLocaleKey, envmanager.AppFallbackLocaleKey}
+	validLocale  = regexp.MustCompile(`^[A-Za-z]{2,8}(-[A-Za-z0-9]{1,8})*$`)
+)
+
+var (
+	_ config.Introspectable = (*LocaleConfig)(nil)
+	_ config.Reloader       = (*LocaleConfig)(nil)
+)
+
+type LocaleConfig struct {
+	envManager *envmanager.EnvManager
+	// state holds the parsed values; Reload replaces it as a whole, so readers never see half a reload.
+	state atomic.Pointer[localeState]
+}
+
+type localeState struct {
+	locale         string
+	fallbackLocale string
+	supported      []string
+}
+
+// NewLocaleConfig creates a LocaleConfig. Without APP_SUPPORTED_LOCALES, it supports APP_LOCALE and APP_FALLBACK_LOCALE.
+func NewLocaleConfig(envManager *envmanager.EnvManager) (*LocaleConfig, diabuddyErrors.ApiErrors) {
+	lc := &LocaleConfig{envManager: envManager}
+	state, err := lc.resolve()
+	if err != nil {
+		return nil, err
+	}
+	lc.state.Store(state)
+	return lc, nil
+}
+
+func (lc *LocaleConfig) Get(key string, defaultValue ...string) string {
+	return lc.envManager.Get(key, defaultValue...)
+}
+
+// Locale returns the normalized APP_LOCALE.
+func (lc *LocaleConfig) Locale() string {
+	return lc.state.Load().locale
+}
+
+// FallbackLocale returns the normalized APP_FALLBACK_LOCALE.
+func (lc *LocaleConfig) FallbackLocale() string {
+	return lc.state.Load().fallbackLocale
+}
+
+// SupportedLocales returns APP_SUPPORTED_LOCALES, or APP_LOCALE and APP_FALLBACK_LOCALE when it is empty.
+func (lc *LocaleConfig) SupportedLocales() []string {
+	return append([]string(nil), lc.state.Load().supported...)
+}
+
+// IsSupported reports whether the locale, in any spelling such as de_de or de-DE, is supported.
+func (lc *LocaleConfig) IsSupported(locale string) bool {
+	return lc.state.Load().supportedLocale(Normalize(locale)) != ""
+}
+
+// FallbackChain returns the supported locales to try, in order, for the given locale: the locale itself,
+// its parents (de-AT, then de), then APP_FALLBACK_LOCALE and its parents. An empty locale means APP_LOCALE.
+func (lc *LocaleConfig) FallbackChain(locale string) []string {
+	state := lc.state.Load()
+	if locale == "" {
+		locale = state.locale
+	}
+
+	var chain []string
+	seen := make(map[string]bool)
+	for _, start := range []string{Normalize(locale), state.fallbackLocale} {
+		for _, candidate := range parents(start) {
+			if supported := state.supportedLocale(candidate); supported != "" && !seen[supported] {
+				seen[supported] = true
+				chain = append(chain, supported)
+			}
+		}
+	}
+	return chain
+}
+
+// Negotiate returns the supported locale that best matches an Accept-Language header. Languages are tried by
+// descending quality; for each one an exact or parent match wins over a supported locale that only shares
+// the language (en-US for en). APP_LOCALE is returned when nothing matches.
+func (lc *LocaleConfig) Negotiate(acceptLanguage string) string {
+	state := lc.state.Load()
+	preferences := parseAcceptLanguage(acceptLanguage)
+	for _, preference := range preferences {
+		if preference == "*" {
+			return state.locale
+		}
+		for _, candidate := range parents(preference) {
+			if supported := state.supportedLocale(candidate); supported != "" {
+				return supported
+			}
+		}
+		language := baseLanguage(preference)
+		for _, supported := range state.supported {
+			if baseLanguage(supported) == language {
+				return supported
+			}
+		}
+	}
+	return state.locale
+}
+
+// Normalize returns the locale as a BCP 47 style tag: de_de becomes de-DE and zh-hant-tw becomes zh-Hant-TW.
+func Normalize(locale string) string {
+	subtags := strings.Split(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+	for i, subtag := range subtags {
+		switch {
+		case i == 0:
+			subtags[i] = strings.ToLower(subtag)
+		case len(subtag) == 2:
+			subtags[i] = strings.ToUpper(subtag)
+		case len(subtag) == 4:
+			subtags[i] = strings.ToUpper(subtag[:1]) + strings.ToLower(subtag[1:])
+		default:
+			subtags[i] = strings.ToLower(subtag)
+		}
+	}
+	return strings.Join(subtags, "-")
+}
+
+func (state *localeState) supportedLocale(locale string) string {
+	for _, supported := range state.supported {
+		if strings.EqualFold(supported, locale) {
+			return supported
+		}
+	}
+	return ""
+}
+
+// parents returns the locale followed by its truncations: de-CH-1996, de-CH, de.
+func parents(locale string) []string {
+	if locale == "" {
+		return nil
+	}
+	subtags := strings.Split(locale, "-")
+	chain := make([]string, 0, len(subtags))
+	for i := len(subtags); i > 0; i-- {
+		chain = append(chain, strings.Join(subtags[:i], "-"))
+	}
+	return chain
+}
+
+func baseLanguage(locale string) string {
+	language, _, _ := strings.Cut(locale, "-")
+	return strings.ToLower(language)
+}
+
+// parseAcceptLanguage returns the normalized languages of the header ordered by descending quality; languages
+// with q=0 or an invalid tag are dropped.
+func parseAcceptLanguage(header string) []string {
+	type preference struct {
+		locale  string
+		quality float64
+	}
+
+	var preferences []preference
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(name) == "q" {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					parsed = 0
+				}
+				quality = parsed
+			}
+		}
+		if quality <= 0 || (tag != "*" && !validLocale.MatchString(strings.ReplaceAll(tag, "_", "-"))) {
+			continue
+		}
+		if tag != "*" {
+			tag = Normalize(tag)
+		}
+		preferences = append(preferences, preference{locale: tag, quality: quality})
+	}
+
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].quality > preferences[j].quality
+	})
+	locales := make([]string, len(preferences))
+	for i, preference := range preferences {
+		locales[i] = preference.locale
+	}
+	return locales
+}
+
+// Reload re-reads the configured locales and swaps them in at once, so it may run while the section is read. On
+// error the previous values are kept.
+func (lc *LocaleConfig) Reload() diabuddyErrors.ApiErrors {
+	state, err := lc.resolve()
+	if err != nil {
+		return err
+	}
+	lc.state.Store(state)
+	return nil
+}
+
+// resolve reads and normalizes the configured locales.
+func (lc *LocaleConfig) resolve() (*localeState, diabuddyErrors.ApiErrors) {
+	state := &localeState{}
+	locale, err := parseLocale(envmanager.AppLocaleKey, lc.Get(envmanager.AppLocaleKey))
+	if err != nil {
+		return nil, err
+	}
+	fallbackLocale, err := parseLocale(envmanager.AppFallbackLocaleKey, lc.Get(envmanager.AppFallbackLocaleKey))
+	if err != nil {
+		return nil, err
+	}
+
+	var supported []string
+	for _, value := range lc.envManager.GetList(envmanager.AppSupportedLocalesKey) {
+		supportedLocale, err := parseLocale(envmanager.AppSupportedLocalesKey, value)
+		if err != nil {
+			return nil, err
+		}
+		supported = append(supported, supportedLocale)
+	}
+	if len(supported) == 0 {
+		for _, configured := range []string{locale, fallbackLocale} {
+			if configured != "" && (len(supported) == 0 || supported[0] != configured) {
+				supported = append(supported, configured)
+			}
+		}
+	}
+
+	state.locale = locale
+	state.fallbackLocale = fallbackLocale
+	state.supported = supported
+	return state, nil
+}
+
+func parseLocale(key, value string) (string, diabuddyErrors.ApiErrors) {
+	if value == "" {
+		return "", nil
+	}
+	if !validLocale.MatchString(strings.ReplaceAll(strings.TrimSpace(value), "_", "-")) {
+		return "", config.InvalidValueError(key, value, "a locale such as en or de-DE", nil)
+	}
+	return Normalize(value), nil
+}
+
+// Keys returns the locale keys.
+func (lc *LocaleConfig) Keys() []string {
+	return append([]string(nil), localeKeys...)
+}
+
+// RequiredKeys returns the keys Validate insists on.
+func (lc *LocaleConfig) RequiredKeys() []string {
+	return append([]string(nil), requiredKeys...)
+}
+
+// Describe returns the definitions of the locale keys, which belong to the app section.
+func (lc *LocaleConfig) Describe() []envmanager.KeyDefinition {
+	definitions := make([]envmanager.KeyDefinition, 0, len(localeKeys))
+	for _, key := range localeKeys {
+		if definition, ok := envmanager.LookupKeyDefinition(key); ok {
+			definitions = append(definitions, definition)
+		}
+	}
+	return definitions
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults.
+func (lc *LocaleConfig) Lookup(key string) (string, bool) {
+	value, found, _ := lc.envManager.Lookup(key)
+	return value, found
+}
+
+// Snapshot returns the current values of the locale keys.
+func (lc *LocaleConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(lc, lc.Describe())
+}
+
+// Validate checks that APP_LOCALE and APP_FALLBACK_LOCALE are set and among the supported locales.
+func (lc *LocaleConfig) Validate() diabuddyErrors.ApiErrors {
+	var missingKeys []string
+	for _, key := range requiredKeys {
+		if strings.TrimSpace(lc.Get(key)) == "" {
+			missingKeys = append(missingKeys, key)
+		}
+	}
+	if len(missingKeys) > 0 {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("missing required key(s): %s", strings.Join(missingKeys, ", ")))
+	}
+
+	state := lc.state.Load()
+	for _, configured := range []struct{ key, locale string }{{envmanager.AppLocaleKey, state.locale}, {envmanager.AppFallbackLocaleKey, state.fallbackLocale}} {
+		if state.supportedLocale(configured.locale) == "" {
+			return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s %q is not one of %s: %s", configured.key, configured.locale, envmanager.AppSupportedLocalesKey, strings.Join(state.supported, ", ")))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package logconfig
+
+import (
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Formats accepted in LOG_FORMAT.
+const (
+	JSONFormat = "json"
+	TextFormat = "text"
+)
+
+// Outputs accepted in LOG_OUTPUT besides a file path.
+const (
+	StdoutOutput = "stdout"
+	StderrOutput = "stderr"
+)
+
+var (
+	_ config.Introspectable = (*LogConfig)(nil)
+	_ config.Reloader       = (*LogConfig)(nil)
+)
+
+type LogConfig struct {
+	envManager *envmanager.EnvManager
+	writer     io.Writer
+	// mu guards the parsed values and the opened log file, which Reload replaces.
+	mu     sync.Mutex
+	level  slog.Level
+	format string
+	output string
+	file   *os.File
+}
+
+// ConfigOption Option function type for configuring LogConfig.
+type ConfigOption func(*LogConfig) diabuddyErrors.ApiErrors
+
+// WithWriter writes log records to the writer instead of LOG_OUTPUT.
+func WithWriter(writer io.Writer) ConfigOption {
+	return func(lc *LogConfig) diabuddyErrors.ApiErrors {
+		lc.writer = writer
+		return nil
+	}
+}
+
+// NewLogConfig creates a LogConfig with provided options. The log file is only opened by NewLogger.
+func NewLogConfig(envManager *envmanager.EnvManager, options ...ConfigOption) (*LogConfig, diabuddyErrors.ApiErrors) {
+	lc := &LogConfig{envManager: envManager}
+	for _, option := range options {
+		if err := option(lc); err != nil {
+			return nil, err
+		}
+	}
+	if err := lc.resolve(); err != nil {
+		return nil, err
+	}
+	return lc, nil
+}
+
+func (lc *LogConfig) Get(key string, defaultValue ...string) string {
+	return lc.envManager.Get(key, defaultValue...)
+}
+
+// Level returns LOG_LEVEL, or debug when APP_DEBUG is true.
+func (lc *LogConfig) Level() slog.Level {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return lc.level
+}
+
+// Format returns LOG_FORMAT.
+func (lc *LogConfig) Format() string {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return lc.format
+}
+
+// Output returns LOG_OUTPUT.
+func (lc *LogConfig) Output() string {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return lc.output
+}
+
+// NewLogger returns a logger that writes in the configured format and level, redacts sensitive attributes and
+// carries app and env attributes taken from APP_NAME and the resolved environment. Loggers share one opened
+// log file, which Close releases.
+func (lc *LogConfig) NewLogger() (*slog.Logger, diabuddyErrors.ApiErrors) {
+	lc.mu.Lock()
+	level, format := lc.level, lc.format
+	writer, err := lc.openWriter()
+	lc.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if format == TextFormat {
+		handler = slog.NewTextHandler(writer, options)
+	} else {
+		handler = slog.NewJSONHandler(writer, options)
+	}
+
+	return slog.New(NewRedactingHandler(handler)).With(
+		slog.String("app", lc.Get(envmanager.AppNameKey)),
+		slog.String("env", lc.envManager.Environment().String()),
+	), nil
+}
+
+// Close closes the log file opened by NewLogger, if any.
+func (lc *LogConfig) Close() error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.file == nil {
+		return nil
+	}
+	err := lc.file.Close()
+	lc.file = nil
+	return err
+}
+
+// openWriter must be called with lc.mu held.
+func (lc *LogConfig) openWriter() (io.Writer, diabuddyErrors.ApiErrors) {
+	if lc.writer != nil {
+		return lc.writer, nil
+	}
+	switch lc.output {
+	case StdoutOutput:
+		return os.Stdout, nil
+	case StderrOutput:
+		return os.Stderr, nil
+	}
+
+	if lc.file == nil {
+		file, err := os.OpenFile(lc.output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("could not open log file %s", lc.output), diabuddyErrors.WithInternalError(err))
+		}
+		lc.file = file
+	}
+	return lc.file, nil
+}
+
+// Reload re-reads the log values; on error the previous values are kept. Loggers created before the reload
+// keep their level and format. When LOG_OUTPUT changes, the open log file is closed, so loggers writing to it
+// must be created again.
+func (lc *LogConfig) Reload() diabuddyErrors.ApiErrors {
+	reloaded := &LogConfig{envManager: lc.envManager}
+	if err := reloaded.resolve(); err != nil {
+		return err
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	var closeErr error
+	previousOutput := lc.output
+	if reloaded.output != previousOutput && lc.file != nil {
+		closeErr = lc.file.Close()
+		lc.file = nil
+	}
+	lc.level = reloaded.level
+	lc.format = reloaded.format
+	lc.output = reloaded.output
+	if closeErr != nil {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("could not close log file %s", previousOutput), diabuddyErrors.WithInternalError(closeErr))
+	}
+	return nil
+}
+
+// resolve reads and parses every log value.
+func (lc *LogConfig) resolve() diabuddyErrors.ApiErrors {
+	rawLevel := lc.Get(envmanager.LogLevelKey, "info")
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(rawLevel)); err != nil {
+		return config.InvalidValueError(envmanager.LogLevelKey, rawLevel, "debug, info, warn or error", err)
+	}
+	debug, err := config.Bool(lc, envmanager.AppDebugKey)
+	if err != nil {
+		return err
+	}
+	if debug && level > slog.LevelDebug {
+		level = slog.LevelDebug
+	}
+
+	format := strings.ToLower(lc.Get(envmanager.LogFormatKey, JSONFormat))
+	if format != JSONFormat && format != TextFormat {
+		return config.InvalidValueError(envmanager.LogFormatKey, format, "json or text", nil)
+	}
+
+	lc.level = level
+	lc.format = format
+	lc.output = lc.Get(envmanager.LogOutputKey, StdoutOutput)
+	return nil
+}
+
+// Keys returns the keys owned by the log section.
+func (lc *LogConfig) Keys() []string {
+	return config.KeyNames(lc.Describe())
+}
+
+// RequiredKeys returns the keys Validate insists on; every log key has a default.
+func (lc *LogConfig) RequiredKeys() []string {
+	return nil
+}
+
+// Describe returns the definitions of the keys owned by the log section.
+func (lc *LogConfig) Describe() []envmanager.KeyDefinition {
+	return envmanager.SectionKeyDefinitions(envmanager.LogSection)
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults.
+func (lc *LogConfig) Lookup(key string) (string, bool) {
+	value, found, _ := lc.envManager.Lookup(key)
+	return value, found
+}
+
+// Snapshot returns the current values of the log section.
+func (lc *LogConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(lc, lc.Describe())
+}
+
+// Validate has nothing left to check: NewLogConfig already rejects a malformed LOG_LEVEL or LOG_FORMAT.
+func (lc *LogConfig) Validate() diabuddyErrors.ApiErrors {
+	return nil
+}
@@ -0,0 +1,73 @@
+package logconfig
+
+import (
+	"context"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"log/slog"
+	"strings"
+)
+
+// RedactingHandler replaces the values of attributes whose key names a sensitive key, e.g. a DB_PASSWORD
+// attribute, with config.RedactedValue before passing records on. Keys are compared case-insensitively, also
+// inside groups.
+type RedactingHandler struct {
+	next      slog.Handler
+	sensitive map[string]bool
+}
+
+// NewRedactingHandler wraps the handler and redacts every key registered as sensitive plus the extra keys.
+func NewRedactingHandler(next slog.Handler, extraKeys ...string) *RedactingHandler {
+	sensitive := make(map[string]bool)
+	for _, definition := range envmanager.KeyDefinitions() {
+		if definition.Sensitive {
+			sensitive[strings.ToLower(definition.Name)] = true
+		}
+	}
+	for _, key := range extraKeys {
+		sensitive[strings.ToLower(key)] = true
+	}
+	return &RedactingHandler{next: next, sensitive: sensitive}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redact(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redact(attr)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(redacted), sensitive: h.sensitive}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name), sensitive: h.sensitive}
+}
+
+func (h *RedactingHandler) redact(attr slog.Attr) slog.Attr {
+	if h.sensitive[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, config.RedactedValue)
+	}
+
+	value := attr.Value.Resolve()
+	if value.Kind() != slog.KindGroup {
+		return slog.Attr{Key: attr.Key, Value: value}
+	}
+	group := value.Group()
+	redacted := make([]slog.Attr, len(group))
+	for i, member := range group {
+		redacted[i] = h.redact(member)
+	}
+	return slog.Attr{Key: attr.Key, Value: slog.GroupValue(redacted...)}
+}
@@ -0,0 +1,288 @@
+package mailconfig
+
+import (
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"log/slog"
+	"net"
+	"net/mail"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Drivers accepted in MAIL_DRIVER.
+const (
+	SMTPDriver  = "smtp"
+	LogDriver   = "log"
+	ArrayDriver = "array"
+)
+
+// Encryptions accepted in MAIL_ENCRYPTION.
+const (
+	StartTLSEncryption = "starttls"
+	TLSEncryption      = "tls"
+	NoEncryption       = "none"
+)
+
+var (
+	_ config.Introspectable = (*MailConfig)(nil)
+	_ config.Reloader       = (*MailConfig)(nil)
+)
+
+type MailConfig struct {
+	envManager *envmanager.EnvManager
+	logger     *slog.Logger
+	// state holds the parsed values; Reload replaces it as a whole, so readers never see half a reload.
+	state atomic.Pointer[mailState]
+}
+
+type mailState struct {
+	driver      string
+	port        int
+	encryption  string
+	from        mail.Address
+	dialTimeout time.Duration
+	sendTimeout time.Duration
+}
+
+// ConfigOption Option function type for configuring MailConfig.
+type ConfigOption func(*MailConfig) diabuddyErrors.ApiErrors
+
+// WithLogger makes the log driver write to the logger instead of slog.Default.
+func WithLogger(logger *slog.Logger) ConfigOption {
+	return func(mc *MailConfig) diabuddyErrors.ApiErrors {
+		mc.logger = logger
+		return nil
+	}
+}
+
+// NewMailConfig creates a MailConfig with provided options for the configured MAIL_DRIVER.
+func NewMailConfig(envManager *envmanager.EnvManager, options ...ConfigOption) (*MailConfig, diabuddyErrors.ApiErrors) {
+	mc := &MailConfig{envManager: envManager}
+	for _, option := range options {
+		if err := option(mc); err != nil {
+			return nil, err
+		}
+	}
+	state, err := mc.resolve()
+	if err != nil {
+		return nil, err
+	}
+	mc.state.Store(state)
+	return mc, nil
+}
+
+func (mc *MailConfig) Get(key string, defaultValue ...string) string {
+	return mc.envManager.Get(key, defaultValue...)
+}
+
+// Driver returns MAIL_DRIVER.
+func (mc *MailConfig) Driver() string {
+	return mc.state.Load().driver
+}
+
+// Host returns MAIL_HOST.
+func (mc *MailConfig) Host() string {
+	return mc.Get(envmanager.MailHostKey)
+}
+
+// Port returns MAIL_PORT, or the usual port of the encryption when it is empty.
+func (mc *MailConfig) Port() int {
+	return mc.state.Load().port
+}
+
+// Encryption returns MAIL_ENCRYPTION.
+func (mc *MailConfig) Encryption() string {
+	return mc.state.Load().encryption
+}
+
+// From returns the sender built from MAIL_FROM_ADDRESS and MAIL_FROM_NAME, which defaults to APP_NAME.
+func (mc *MailConfig) From() mail.Address {
+	return mc.state.Load().from
+}
+
+// DialTimeout returns MAIL_DIAL_TIMEOUT.
+func (mc *MailConfig) DialTimeout() time.Duration {
+	return mc.state.Load().dialTimeout
+}
+
+// SendTimeout returns MAIL_SEND_TIMEOUT.
+func (mc *MailConfig) SendTimeout() time.Duration {
+	return mc.state.Load().sendTimeout
+}
+
+// NewSender returns the Sender of the configured driver.
+func (mc *MailConfig) NewSender() Sender {
+	state := mc.state.Load()
+	switch state.driver {
+	case LogDriver:
+		return NewLogSender(mc.logger, state.from)
+	case ArrayDriver:
+		return NewArraySender()
+	default:
+		return &SMTPSender{
+			Host:        mc.Host(),
+			Port:        state.port,
+			Encryption:  state.encryption,
+			Username:    mc.Get(envmanager.MailUsernameKey),
+			Password:    mc.Get(envmanager.MailPasswordKey),
+			From:        state.from,
+			DialTimeout: state.dialTimeout,
+			SendTimeout: state.sendTimeout,
+		}
+	}
+}
+
+// Reload re-reads the mail values and swaps them in at once, so it may run while the section is read. On error
+// the previous values are kept.
+func (mc *MailConfig) Reload() diabuddyErrors.ApiErrors {
+	state, err := mc.resolve()
+	if err != nil {
+		return err
+	}
+	mc.state.Store(state)
+	return nil
+}
+
+// resolve reads and parses every mail value.
+func (mc *MailConfig) resolve() (*mailState, diabuddyErrors.ApiErrors) {
+	state := &mailState{}
+	driver := strings.ToLower(mc.Get(envmanager.MailDriverKey))
+	if driver != SMTPDriver && driver != LogDriver && driver != ArrayDriver {
+		return nil, config.InvalidValueError(envmanager.MailDriverKey, driver, "smtp, log or array", nil)
+	}
+	encryption := strings.ToLower(mc.Get(envmanager.MailEncryptionKey))
+	if encryption != StartTLSEncryption && encryption != TLSEncryption && encryption != NoEncryption {
+		return nil, config.InvalidValueError(envmanager.MailEncryptionKey, encryption, "starttls, tls or none", nil)
+	}
+
+	port, err := config.Int(mc, envmanager.MailPortKey)
+	if err != nil {
+		return nil, err
+	}
+	if port == 0 {
+		port = defaultPort(encryption)
+	}
+	if port < 1 || port > 65535 {
+		return nil, config.InvalidValueError(envmanager.MailPortKey, mc.Get(envmanager.MailPortKey), "a port between 1 and 65535", nil)
+	}
+
+	var from mail.Address
+	if fromAddress := mc.Get(envmanager.MailFromAddressKey); fromAddress != "" {
+		parsed, err := mail.ParseAddress(fromAddress)
+		if err != nil || parsed.Name != "" {
+			return nil, config.InvalidValueError(envmanager.MailFromAddressKey, fromAddress, "a plain e-mail address", err)
+		}
+		from.Address = parsed.Address
+	}
+	from.Name = mc.Get(envmanager.MailFromNameKey, mc.Get(envmanager.AppNameKey))
+
+	durations := []struct {
+		key    string
+		target *time.Duration
+	}{
+		{envmanager.MailDialTimeoutKey, &state.dialTimeout},
+		{envmanager.MailSendTimeoutKey, &state.sendTimeout},
+	}
+	for _, duration := range durations {
+		value, err := config.Duration(mc, duration.key)
+		if err != nil {
+			return nil, err
+		}
+		if value < 0 {
+			return nil, config.InvalidValueError(duration.key, mc.Get(duration.key), "a duration of zero or more", nil)
+		}
+		*duration.target = value
+	}
+
+	state.driver = driver
+	state.port = port
+	state.encryption = encryption
+	state.from = from
+	return state, nil
+}
+
+func defaultPort(encryption string) int {
+	switch encryption {
+	case TLSEncryption:
+		return 465
+	case StartTLSEncryption:
+		return 587
+	}
+	return 25
+}
+
+// Keys returns the keys owned by the mail section.
+func (mc *MailConfig) Keys() []string {
+	return config.KeyNames(mc.Describe())
+}
+
+// RequiredKeys returns the keys Validate insists on: the host and sender address for the smtp driver, joined by
+// MAIL_PASSWORD when a user name is set. The log and array drivers need none.
+func (mc *MailConfig) RequiredKeys() []string {
+	if mc.state.Load().driver != SMTPDriver {
+		return nil
+	}
+	keys := []string{envmanager.MailHostKey, envmanager.MailFromAddressKey}
+	if mc.Get(envmanager.MailUsernameKey) != "" {
+		keys = append(keys, envmanager.MailPasswordKey)
+	}
+	return keys
+}
+
+// Describe returns the definitions of the keys owned by the mail section.
+func (mc *MailConfig) Describe() []envmanager.KeyDefinition {
+	return envmanager.SectionKeyDefinitions(envmanager.MailSection)
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults.
+func (mc *MailConfig) Lookup(key string) (string, bool) {
+	value, found, _ := mc.envManager.Lookup(key)
+	return value, found
+}
+
+// Snapshot returns the current values of the mail section.
+func (mc *MailConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(mc, mc.Describe())
+}
+
+// Validate checks that the required keys are present and rejects credentials sent over an unencrypted connection
+// to a host other than a loopback address. In production it also rejects the log and array drivers, which never
+// deliver mail.
+func (mc *MailConfig) Validate() diabuddyErrors.ApiErrors {
+	state := mc.state.Load()
+	var missingKeys []string
+	for _, key := range mc.RequiredKeys() {
+		if strings.TrimSpace(mc.Get(key)) == "" {
+			missingKeys = append(missingKeys, key)
+		}
+	}
+	if len(missingKeys) > 0 {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("missing required key(s): %s", strings.Join(missingKeys, ", ")))
+	}
+
+	if state.driver == SMTPDriver && state.encryption == NoEncryption && mc.Get(envmanager.MailUsernameKey) != "" && !isLoopback(mc.Get(envmanager.MailHostKey)) {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s must not be none when %s is set, unless %s is a loopback address", envmanager.MailEncryptionKey, envmanager.MailUsernameKey, envmanager.MailHostKey))
+	}
+
+	if !mc.envManager.Environment().IsProduction() {
+		return nil
+	}
+	if state.driver != SMTPDriver {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s must be smtp in production, got %s", envmanager.MailDriverKey, state.driver))
+	}
+	return nil
+}
+
+// isLoopback reports whether the host is localhost or a loopback IP address, the only hosts net/smtp sends PLAIN
+// credentials to without TLS.
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
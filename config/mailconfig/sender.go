@@ -0,0 +1,148 @@
+package mailconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"log/slog"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is an e-mail to send; the sender is taken from the MailConfig.
+type Message struct {
+	To      []string
+	Subject string
+	// Body is sent as text/plain, or as text/html when HTML is true.
+	Body string
+	HTML bool
+}
+
+// Sender delivers messages. MailConfig.NewSender returns the Sender of MAIL_DRIVER, so services depend on this
+// interface and tests can swap in an ArraySender.
+type Sender interface {
+	Send(ctx context.Context, message Message) diabuddyErrors.ApiErrors
+}
+
+var (
+	_ Sender = (*SMTPSender)(nil)
+	_ Sender = (*LogSender)(nil)
+	_ Sender = (*ArraySender)(nil)
+)
+
+// LogSender writes messages to a logger instead of delivering them; it backs the log driver.
+type LogSender struct {
+	logger *slog.Logger
+	from   mail.Address
+}
+
+// NewLogSender creates a LogSender; a nil logger means slog.Default.
+func NewLogSender(logger *slog.Logger, from mail.Address) *LogSender {
+	return &LogSender{logger: logger, from: from}
+}
+
+func (ls *LogSender) Send(ctx context.Context, message Message) diabuddyErrors.ApiErrors {
+	if _, err := recipients(message); err != nil {
+		return err
+	}
+	logger := ls.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.InfoContext(ctx, "mail sent", "from", ls.from.String(), "to", message.To, "subject", message.Subject, "body", message.Body)
+	return nil
+}
+
+// ArraySender keeps messages in memory instead of delivering them; it backs the array driver, so tests can assert
+// on what a service sent.
+type ArraySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewArraySender creates an empty ArraySender.
+func NewArraySender() *ArraySender {
+	return &ArraySender{}
+}
+
+func (as *ArraySender) Send(_ context.Context, message Message) diabuddyErrors.ApiErrors {
+	if _, err := recipients(message); err != nil {
+		return err
+	}
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	message.To = append([]string(nil), message.To...)
+	as.messages = append(as.messages, message)
+	return nil
+}
+
+// Messages returns the messages sent so far.
+func (as *ArraySender) Messages() []Message {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return append([]Message(nil), as.messages...)
+}
+
+// Reset forgets the messages sent so far.
+func (as *ArraySender) Reset() {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.messages = nil
+}
+
+// recipients checks that the message has at least one valid recipient and returns the parsed addresses.
+func recipients(message Message) ([]*mail.Address, diabuddyErrors.ApiErrors) {
+	if len(message.To) == 0 {
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, "a message needs at least one recipient")
+	}
+	addresses := make([]*mail.Address, 0, len(message.To))
+	for _, to := range message.To {
+		address, err := mail.ParseAddress(to)
+		if err != nil {
+			return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%q is not a valid recipient", to), diabuddyErrors.WithInternalError(err))
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// encode renders the message as a MIME message with a quoted-printable body.
+func encode(from mail.Address, to []*mail.Address, message Message, date time.Time) []byte {
+	contentType := "text/plain"
+	if message.HTML {
+		contentType = "text/html"
+	}
+
+	var buffer bytes.Buffer
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", joinAddresses(to)},
+		{"Subject", mime.QEncoding.Encode("utf-8", message.Subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", contentType + "; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	for _, header := range headers {
+		buffer.WriteString(header[0] + ": " + header[1] + "\r\n")
+	}
+	buffer.WriteString("\r\n")
+
+	body := quotedprintable.NewWriter(&buffer)
+	_, _ = body.Write([]byte(message.Body))
+	_ = body.Close()
+	return buffer.Bytes()
+}
+
+func joinAddresses(addresses []*mail.Address) string {
+	formatted := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		formatted = append(formatted, address.String())
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,114 @@
+package mailconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPSender delivers messages to an SMTP server; it backs the smtp driver. Each Send opens its own connection.
+type SMTPSender struct {
+	Host string
+	Port int
+	// Encryption is one of StartTLSEncryption, TLSEncryption or NoEncryption.
+	Encryption string
+	// Username enables PLAIN authentication when it is not empty.
+	Username    string
+	Password    string
+	From        mail.Address
+	DialTimeout time.Duration
+	// SendTimeout bounds the SMTP conversation of one message; the deadline of the context applies as well.
+	SendTimeout time.Duration
+}
+
+func (ss *SMTPSender) Send(ctx context.Context, message Message) diabuddyErrors.ApiErrors {
+	to, apiErr := recipients(message)
+	if apiErr != nil {
+		return apiErr
+	}
+	address := net.JoinHostPort(ss.Host, strconv.Itoa(ss.Port))
+	if err := ss.send(ctx, address, to, encode(ss.From, to, message, time.Now())); err != nil {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("sending mail through %s failed", address), diabuddyErrors.WithInternalError(err))
+	}
+	return nil
+}
+
+func (ss *SMTPSender) send(ctx context.Context, address string, to []*mail.Address, data []byte) error {
+	tlsConfig := &tls.Config{ServerName: ss.Host, MinVersion: tls.VersionTLS12}
+	dialCtx := ctx
+	if ss.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, ss.DialTimeout)
+		defer cancel()
+	}
+
+	var conn net.Conn
+	var err error
+	if ss.Encryption == TLSEncryption {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(dialCtx, "tcp", address)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(dialCtx, "tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if ss.SendTimeout > 0 {
+		if err = conn.SetDeadline(time.Now().Add(ss.SendTimeout)); err != nil {
+			return err
+		}
+	}
+	// Cancelling the context aborts the conversation by expiring the connection deadline.
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	client, err := smtp.NewClient(conn, ss.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ss.Encryption == StartTLSEncryption {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("the server does not support STARTTLS")
+		}
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if ss.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", ss.Username, ss.Password, ss.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(ss.From.Address); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err = client.Rcpt(recipient.Address); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(data); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,194 @@
+// Package smtptest provides an in-process SMTP server for tests, in the spirit of net/http/httptest. It accepts
+// every message and any PLAIN credentials, and does not offer STARTTLS, so senders must use MAIL_ENCRYPTION=none.
+package smtptest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Message is a message received by the Server.
+type Message struct {
+	// Username is the user name the client authenticated with, if any.
+	Username string
+	From     string
+	To       []string
+	// Data is the raw message, headers included, with CRLF line endings.
+	Data string
+}
+
+type Server struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	messages []Message
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a Server on a free port of 127.0.0.1. It panics when it cannot listen, like
+// httptest.NewServer.
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("smtptest: failed to listen: %v", err))
+	}
+	server := &Server{listener: listener, conns: make(map[net.Conn]struct{})}
+	server.wg.Add(1)
+	go server.serve()
+	return server
+}
+
+// Host returns the host the Server listens on, for MAIL_HOST.
+func (s *Server) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port returns the port the Server listens on, for MAIL_PORT.
+func (s *Server) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Messages returns the messages received so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Close stops the Server and closes the open connections.
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			_ = conn.Close()
+		}()
+	}
+}
+
+// handle runs the SMTP conversation of one connection until QUIT or an error.
+func (s *Server) handle(conn net.Conn) {
+	text := textproto.NewConn(conn)
+	var session Message
+	reply := func(code int, message string) bool {
+		return text.PrintfLine("%d %s", code, message) == nil
+	}
+	if !reply(220, "smtptest ESMTP ready") {
+		return
+	}
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, argument, _ := strings.Cut(line, " ")
+		ok := true
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ok = text.PrintfLine("250-smtptest") == nil && reply(250, "AUTH PLAIN")
+		case "HELO", "NOOP":
+			ok = reply(250, "OK")
+		case "AUTH":
+			username, valid := plainUsername(argument)
+			if !valid {
+				ok = reply(501, "only AUTH PLAIN with an initial response is supported")
+				break
+			}
+			session.Username = username
+			ok = reply(235, "authenticated")
+		case "MAIL":
+			session.From = path(argument)
+			session.To = nil
+			ok = reply(250, "OK")
+		case "RCPT":
+			session.To = append(session.To, path(argument))
+			ok = reply(250, "OK")
+		case "DATA":
+			if session.From == "" || len(session.To) == 0 {
+				ok = reply(503, "MAIL and RCPT must come first")
+				break
+			}
+			if !reply(354, "end data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := text.ReadDotLines()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, Message{
+				Username: session.Username,
+				From:     session.From,
+				To:       session.To,
+				Data:     strings.Join(data, "\r\n"),
+			})
+			s.mu.Unlock()
+			session = Message{Username: session.Username}
+			ok = reply(250, "OK: queued as "+strconv.Itoa(len(s.Messages())))
+		case "RSET":
+			session = Message{Username: session.Username}
+			ok = reply(250, "OK")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			ok = reply(502, "command not implemented")
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// path returns the address of a MAIL FROM:<address> or RCPT TO:<address> argument.
+func path(argument string) string {
+	_, address, _ := strings.Cut(argument, ":")
+	address, _, _ = strings.Cut(strings.TrimSpace(address), " ")
+	return strings.Trim(address, "<>")
+}
+
+// plainUsername decodes the user name of a PLAIN initial response.
+func plainUsername(argument string) (string, bool) {
+	mechanism, response, _ := strings.Cut(argument, " ")
+	if !strings.EqualFold(mechanism, "PLAIN") {
+		return "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(response)
+	if err != nil {
+		return "", false
+	}
+	parts := strings.Split(string(decoded), "\x00")
+	if len(parts) != 3 {
+		return "", false
+	}
+	return parts[1], true
+}
@@ -0,0 +1,336 @@
+package observabilityconfig
+
+import (
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"maps"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Samplers accepted in OTEL_TRACES_SAMPLER.
+const (
+	AlwaysOnSampler                = "always_on"
+	AlwaysOffSampler               = "always_off"
+	TraceIDRatioSampler            = "traceidratio"
+	ParentBasedAlwaysOnSampler     = "parentbased_always_on"
+	ParentBasedAlwaysOffSampler    = "parentbased_always_off"
+	ParentBasedTraceIDRatioSampler = "parentbased_traceidratio"
+)
+
+// Protocols accepted in OTEL_EXPORTER_OTLP_PROTOCOL.
+const (
+	GRPCProtocol         = "grpc"
+	HTTPProtobufProtocol = "http/protobuf"
+	HTTPJSONProtocol     = "http/json"
+)
+
+// Exporters accepted in OTEL_TRACES_EXPORTER and OTEL_METRICS_EXPORTER.
+const (
+	OTLPExporter    = "otlp"
+	ConsoleExporter = "console"
+	NoneExporter    = "none"
+)
+
+// Resource attributes filled in from the app keys.
+const (
+	ServiceNameAttribute           = "service.name"
+	DeploymentEnvironmentAttribute = "deployment.environment.name"
+)
+
+var (
+	_ config.Introspectable = (*ObservabilityConfig)(nil)
+	_ config.Reloader       = (*ObservabilityConfig)(nil)
+)
+
+// ExporterOptions is the client independent result of ObservabilityConfig for the OTLP exporters.
+type ExporterOptions struct {
+	// Endpoint is the base URL of the receiver, e.g. http://localhost:4318.
+	Endpoint string
+	// Host is the host:port of Endpoint, as expected by the WithEndpoint options of the Go exporters.
+	Host string
+	// Insecure is true when Endpoint uses http, so gRPC exporters must connect without TLS.
+	Insecure    bool
+	Protocol    string
+	Headers     map[string]string
+	Timeout     time.Duration
+	Compression string
+}
+
+type ObservabilityConfig struct {
+	envManager *envmanager.EnvManager
+	// state holds the parsed values; Reload replaces it as a whole, so readers never see half a reload.
+	state atomic.Pointer[observabilityState]
+}
+
+type observabilityState struct {
+	disabled           bool
+	resourceAttributes map[string]string
+	sampler            string
+	samplingRatio      float64
+	tracesExporter     string
+	metricsExporter    string
+	exporterOptions    ExporterOptions
+	metricsInterval    time.Duration
+}
+
+// NewObservabilityConfig creates an ObservabilityConfig from the OTEL_* values.
+func NewObservabilityConfig(envManager *envmanager.EnvManager) (*ObservabilityConfig, diabuddyErrors.ApiErrors) {
+	oc := &ObservabilityConfig{envManager: envManager}
+	state, err := oc.resolve()
+	if err != nil {
+		return nil, err
+	}
+	oc.state.Store(state)
+	return oc, nil
+}
+
+func (oc *ObservabilityConfig) Get(key string, defaultValue ...string) string {
+	return oc.envManager.Get(key, defaultValue...)
+}
+
+// Enabled reports whether OTEL_SDK_DISABLED is false.
+func (oc *ObservabilityConfig) Enabled() bool {
+	return !oc.state.Load().disabled
+}
+
+// ServiceName returns OTEL_SERVICE_NAME, the service.name in OTEL_RESOURCE_ATTRIBUTES or APP_NAME, in that order.
+func (oc *ObservabilityConfig) ServiceName() string {
+	return oc.state.Load().resourceAttributes[ServiceNameAttribute]
+}
+
+// ResourceAttributes returns the resource attributes: OTEL_RESOURCE_ATTRIBUTES completed with service.name and
+// deployment.environment.name, which defaults to APP_ENV.
+func (oc *ObservabilityConfig) ResourceAttributes() map[string]string {
+	return maps.Clone(oc.state.Load().resourceAttributes)
+}
+
+// Sampler returns OTEL_TRACES_SAMPLER.
+func (oc *ObservabilityConfig) Sampler() string {
+	return oc.state.Load().sampler
+}
+
+// SamplingRatio returns OTEL_TRACES_SAMPLER_ARG for the traceidratio samplers; it is 1 when the key is empty and
+// for the other samplers.
+func (oc *ObservabilityConfig) SamplingRatio() float64 {
+	return oc.state.Load().samplingRatio
+}
+
+// TracesExporter returns OTEL_TRACES_EXPORTER, or none when the SDK is disabled.
+func (oc *ObservabilityConfig) TracesExporter() string {
+	state := oc.state.Load()
+	if state.disabled {
+		return NoneExporter
+	}
+	return state.tracesExporter
+}
+
+// MetricsExporter returns OTEL_METRICS_EXPORTER, or none when the SDK is disabled.
+func (oc *ObservabilityConfig) MetricsExporter() string {
+	state := oc.state.Load()
+	if state.disabled {
+		return NoneExporter
+	}
+	return state.metricsExporter
+}
+
+// MetricsInterval returns OTEL_METRIC_EXPORT_INTERVAL.
+func (oc *ObservabilityConfig) MetricsInterval() time.Duration {
+	return oc.state.Load().metricsInterval
+}
+
+// ExporterOptions returns the settings shared by the OTLP trace and metric exporters.
+func (oc *ObservabilityConfig) ExporterOptions() ExporterOptions {
+	options := oc.state.Load().exporterOptions
+	options.Headers = maps.Clone(options.Headers)
+	return options
+}
+
+// Reload re-reads the OTEL_* values and swaps them in at once, so it may run while the section is read. On error
+// the previous values are kept.
+func (oc *ObservabilityConfig) Reload() diabuddyErrors.ApiErrors {
+	state, err := oc.resolve()
+	if err != nil {
+		return err
+	}
+	oc.state.Store(state)
+	return nil
+}
+
+// resolve reads and parses every OTEL_* value.
+func (oc *ObservabilityConfig) resolve() (*observabilityState, diabuddyErrors.ApiErrors) {
+	state := &observabilityState{}
+	disabled, err := config.Bool(oc, envmanager.OtelSDKDisabledKey)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceAttributes, err := parseKeyValues(envmanager.OtelResourceAttributesKey, oc.Get(envmanager.OtelResourceAttributesKey), false)
+	if err != nil {
+		return nil, err
+	}
+	if serviceName := oc.Get(envmanager.OtelServiceNameKey); serviceName != "" {
+		resourceAttributes[ServiceNameAttribute] = serviceName
+	} else if resourceAttributes[ServiceNameAttribute] == "" {
+		resourceAttributes[ServiceNameAttribute] = oc.Get(envmanager.AppNameKey)
+	}
+	if resourceAttributes[DeploymentEnvironmentAttribute] == "" {
+		resourceAttributes[DeploymentEnvironmentAttribute] = oc.envManager.Environment().String()
+	}
+
+	sampler := strings.ToLower(oc.Get(envmanager.OtelTracesSamplerKey))
+	switch sampler {
+	case AlwaysOnSampler, AlwaysOffSampler, TraceIDRatioSampler, ParentBasedAlwaysOnSampler, ParentBasedAlwaysOffSampler, ParentBasedTraceIDRatioSampler:
+	default:
+		return nil, config.InvalidValueError(envmanager.OtelTracesSamplerKey, sampler, "one of always_on, always_off, traceidratio, parentbased_always_on, parentbased_always_off or parentbased_traceidratio", nil)
+	}
+	samplingRatio := 1.0
+	if value := oc.Get(envmanager.OtelTracesSamplerArgKey); value != "" && (sampler == TraceIDRatioSampler || sampler == ParentBasedTraceIDRatioSampler) {
+		ratio, parseErr := strconv.ParseFloat(value, 64)
+		if parseErr != nil || ratio < 0 || ratio > 1 {
+			return nil, config.InvalidValueError(envmanager.OtelTracesSamplerArgKey, value, "a ratio between 0 and 1", parseErr)
+		}
+		samplingRatio = ratio
+	}
+
+	exporters := []struct {
+		key    string
+		target *string
+	}{
+		{envmanager.OtelTracesExporterKey, &state.tracesExporter},
+		{envmanager.OtelMetricsExporterKey, &state.metricsExporter},
+	}
+	for _, exporter := range exporters {
+		value := strings.ToLower(oc.Get(exporter.key))
+		if value != OTLPExporter && value != ConsoleExporter && value != NoneExporter {
+			return nil, config.InvalidValueError(exporter.key, value, "otlp, console or none", nil)
+		}
+		*exporter.target = value
+	}
+
+	exporterOptions, err := oc.resolveExporterOptions()
+	if err != nil {
+		return nil, err
+	}
+	metricsInterval, err := milliseconds(oc, envmanager.OtelMetricExportIntervalKey)
+	if err != nil {
+		return nil, err
+	}
+
+	state.disabled = disabled
+	state.resourceAttributes = resourceAttributes
+	state.sampler = sampler
+	state.samplingRatio = samplingRatio
+	state.exporterOptions = exporterOptions
+	state.metricsInterval = metricsInterval
+	return state, nil
+}
+
+func (oc *ObservabilityConfig) resolveExporterOptions() (ExporterOptions, diabuddyErrors.ApiErrors) {
+	protocol := strings.ToLower(oc.Get(envmanager.OtelExporterOTLPProtocolKey))
+	if protocol != GRPCProtocol && protocol != HTTPProtobufProtocol && protocol != HTTPJSONProtocol {
+		return ExporterOptions{}, config.InvalidValueError(envmanager.OtelExporterOTLPProtocolKey, protocol, "grpc, http/protobuf or http/json", nil)
+	}
+	defaultEndpoint := "http://localhost:4318"
+	if protocol == GRPCProtocol {
+		defaultEndpoint = "http://localhost:4317"
+	}
+	endpoint := oc.Get(envmanager.OtelExporterOTLPEndpointKey, defaultEndpoint)
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ExporterOptions{}, config.InvalidValueError(envmanager.OtelExporterOTLPEndpointKey, endpoint, "an http or https URL", err)
+	}
+
+	headers, apiErr := parseKeyValues(envmanager.OtelExporterOTLPHeadersKey, oc.Get(envmanager.OtelExporterOTLPHeadersKey), true)
+	if apiErr != nil {
+		return ExporterOptions{}, apiErr
+	}
+	timeout, apiErr := milliseconds(oc, envmanager.OtelExporterOTLPTimeoutKey)
+	if apiErr != nil {
+		return ExporterOptions{}, apiErr
+	}
+	compression := strings.ToLower(oc.Get(envmanager.OtelExporterOTLPCompressionKey))
+	if compression != "gzip" && compression != "none" {
+		return ExporterOptions{}, config.InvalidValueError(envmanager.OtelExporterOTLPCompressionKey, compression, "gzip or none", nil)
+	}
+
+	return ExporterOptions{
+		Endpoint:    strings.TrimSuffix(endpoint, "/"),
+		Host:        parsed.Host,
+		Insecure:    parsed.Scheme == "http",
+		Protocol:    protocol,
+		Headers:     headers,
+		Timeout:     timeout,
+		Compression: compression,
+	}, nil
+}
+
+// milliseconds reads a key holding a number of milliseconds, the unit the OpenTelemetry spec uses for durations.
+func milliseconds(section config.Config, key string) (time.Duration, diabuddyErrors.ApiErrors) {
+	value, err := config.Int(section, key)
+	if err != nil {
+		return 0, err
+	}
+	if value < 0 {
+		return 0, config.InvalidValueError(key, section.Get(key), "zero or more milliseconds", nil)
+	}
+	return time.Duration(value) * time.Millisecond, nil
+}
+
+// parseKeyValues parses a comma separated list of key=value pairs with percent-encoded values. Sensitive values
+// are left out of the errors.
+func parseKeyValues(key, value string, sensitive bool) (map[string]string, diabuddyErrors.ApiErrors) {
+	pairs := make(map[string]string)
+	for _, pair := range envmanager.SplitList(value) {
+		name, encoded, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		decoded, err := url.PathUnescape(strings.TrimSpace(encoded))
+		if !ok || name == "" || err != nil {
+			if sensitive {
+				pair = config.RedactedValue
+			}
+			return nil, config.InvalidValueError(key, pair, "a list of key=value pairs", nil)
+		}
+		pairs[name] = decoded
+	}
+	return pairs, nil
+}
+
+// Keys returns the keys owned by the observability section.
+func (oc *ObservabilityConfig) Keys() []string {
+	return config.KeyNames(oc.Describe())
+}
+
+// RequiredKeys returns the keys Validate insists on; none, as OTEL_SERVICE_NAME falls back to APP_NAME.
+func (oc *ObservabilityConfig) RequiredKeys() []string {
+	return nil
+}
+
+// Describe returns the definitions of the keys owned by the observability section.
+func (oc *ObservabilityConfig) Describe() []envmanager.KeyDefinition {
+	return envmanager.SectionKeyDefinitions(envmanager.ObservabilitySection)
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults.
+func (oc *ObservabilityConfig) Lookup(key string) (string, bool) {
+	value, found, _ := oc.envManager.Lookup(key)
+	return value, found
+}
+
+// Snapshot returns the current values of the observability section.
+func (oc *ObservabilityConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(oc, oc.Describe())
+}
+
+// Validate checks that the service has a name, so that its traces can be told apart from those of other services.
+func (oc *ObservabilityConfig) Validate() diabuddyErrors.ApiErrors {
+	if strings.TrimSpace(oc.ServiceName()) == "" {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("missing required key(s): %s", envmanager.OtelServiceNameKey))
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package schema
+
+import (
+	"encoding/json"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"sort"
+)
+
+const (
+	Draft = "https://json-schema.org/draft/2020-12/schema"
+	ID    = "https://github.com/hbttundar/diabuddy-api-config/config.schema.json"
+	Title = "Diabuddy API configuration"
+)
+
+// Patterns used for values that are allowed to be written as strings. Every pattern accepts the empty string,
+// because an empty environment variable is treated as unset.
+const (
+	integerPattern  = `^(-?[0-9]+)?$`
+	numberPattern   = `^(-?[0-9]+(\.[0-9]+)?)?$`
+	durationPattern = `^([-+]?(0|([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+))?$`
+)
+
+// booleanValues mirrors what strconv.ParseBool accepts, plus the empty string.
+var booleanValues = []any{true, false, "", "1", "0", "t", "f", "T", "F", "true", "false", "TRUE", "FALSE", "True", "False"}
+
+// Schema is a JSON Schema document describing the flat map of configuration keys.
+type Schema struct {
+	Schema               string               `json:"$schema"`
+	ID                   string               `json:"$id"`
+	Title                string               `json:"title"`
+	Type                 string               `json:"type"`
+	Properties           map[string]*Property `json:"properties"`
+	Required             []string             `json:"required,omitempty"`
+	AdditionalProperties bool                 `json:"additionalProperties"`
+}
+
+// Property describes a single configuration key. Section is exported as the x-section extension keyword.
+type Property struct {
+	Type        Types  `json:"type"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Enum        []any  `json:"enum,omitempty"`
+	Pattern     string `json:"pattern,omitempty"`
+	MinLength   int    `json:"minLength,omitempty"`
+	WriteOnly   bool   `json:"writeOnly,omitempty"`
+	Section     string `json:"x-section,omitempty"`
+}
+
+// Types holds the JSON types a property accepts; it is encoded as a string when there is only one.
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*t = multiple
+	return nil
+}
+
+// Generate builds the schema for every key currently registered with envmanager.
+func Generate() *Schema {
+	return GenerateFor(envmanager.KeyDefinitions())
+}
+
+// GenerateFor builds the schema for the given key definitions. Keys are required only when they are marked as
+// required and have no default, since a default always satisfies them at runtime.
+func GenerateFor(definitions []envmanager.KeyDefinition) *Schema {
+	s := &Schema{
+		Schema:               Draft,
+		ID:                   ID,
+		Title:                Title,
+		Type:                 "object",
+		Properties:           make(map[string]*Property, len(definitions)),
+		AdditionalProperties: true,
+	}
+
+	for _, definition := range definitions {
+		property := propertyFor(definition)
+		if definition.Required && definition.Default == "" {
+			property.MinLength = 1
+			s.Required = append(s.Required, definition.Name)
+		}
+		s.Properties[definition.Name] = property
+	}
+	sort.Strings(s.Required)
+
+	return s
+}
+
+// Parse reads a schema document previously produced by JSON.
+func Parse(data []byte) (*Schema, diabuddyErrors.ApiErrors) {
+	s := &Schema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "invalid configuration schema", diabuddyErrors.WithInternalError(err))
+	}
+	return s, nil
+}
+
+// JSON returns the indented schema document.
+func (s *Schema) JSON() ([]byte, diabuddyErrors.ApiErrors) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "failed to encode configuration schema", diabuddyErrors.WithInternalError(err))
+	}
+	return append(data, '\n'), nil
+}
+
+func propertyFor(definition envmanager.KeyDefinition) *Property {
+	property := &Property{
+		Type:        Types{"string"},
+		Description: definition.Description,
+		WriteOnly:   definition.Sensitive,
+		Section:     definition.Section,
+	}
+	if !definition.Sensitive {
+		property.Default = definition.Default
+	}
+
+	switch definition.ValueType() {
+	case envmanager.IntegerKey:
+		property.Type = Types{"integer", "string"}
+		property.Pattern = integerPattern
+	case envmanager.NumberKey:
+		property.Type = Types{"number", "string"}
+		property.Pattern = numberPattern
+	case envmanager.BooleanKey:
+		property.Type = Types{"boolean", "string"}
+		property.Enum = booleanValues
+	case envmanager.DurationKey:
+		property.Pattern = durationPattern
+	}
+
+	if len(definition.Enum) > 0 {
+		property.Enum = []any{""}
+		for _, value := range definition.Enum {
+			property.Enum = append(property.Enum, value)
+		}
+	}
+
+	return property
+}
@@ -0,0 +1,316 @@
+package schema
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var dotenvKeyPattern = regexp.MustCompile(`^\s*(?:export\s+)?([A-Za-z_][A-Za-z0-9_.]*)\s*[=:]`)
+
+// Violation is a single mismatch between a configuration source and the schema.
+// Line and Column are 1-based; they are zero when the violation concerns the source as a whole.
+type Violation struct {
+	Source  string
+	Key     string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (v Violation) String() string {
+	position := v.Source
+	if v.Line > 0 {
+		position = fmt.Sprintf("%s:%d:%d", v.Source, v.Line, v.Column)
+	}
+	return fmt.Sprintf("%s: %s %s", position, v.Key, v.Message)
+}
+
+// Violations is the result of validating a configuration source.
+type Violations []Violation
+
+// Err returns nil when there are no violations, otherwise a single error listing all of them.
+func (vs Violations) Err() diabuddyErrors.ApiErrors {
+	if len(vs) == 0 {
+		return nil
+	}
+	messages := make([]string, len(vs))
+	for i, v := range vs {
+		messages[i] = v.String()
+	}
+	return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, "configuration does not match schema: "+strings.Join(messages, "; "))
+}
+
+type entry struct {
+	key    string
+	value  any
+	line   int
+	column int
+}
+
+// ValidateFile validates a JSON, YAML or dotenv file, chosen by its extension. Anything that is not .json,
+// .yaml or .yml is read as a dotenv file.
+func (s *Schema) ValidateFile(path string) (Violations, diabuddyErrors.ApiErrors) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("failed to read configuration file: %s", path), diabuddyErrors.WithInternalError(err))
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return s.ValidateJSON(path, data)
+	case ".yaml", ".yml":
+		return s.ValidateYAML(path, data)
+	default:
+		return s.ValidateDotenv(path, data)
+	}
+}
+
+// ValidateJSON validates a JSON document whose top level is an object of configuration keys.
+func (s *Schema) ValidateJSON(source string, data []byte) (Violations, diabuddyErrors.ApiErrors) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, parseError(source, data, decoder.InputOffset(), "expected a JSON object", err)
+	}
+
+	var entries []entry
+	for decoder.More() {
+		keyStart := skipSeparators(data, decoder.InputOffset())
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, parseError(source, data, decoder.InputOffset(), "invalid JSON", err)
+		}
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return nil, parseError(source, data, decoder.InputOffset(), "invalid JSON", err)
+		}
+		line, column := position(data, keyStart)
+		entries = append(entries, entry{key: token.(string), value: value, line: line, column: column})
+	}
+
+	return s.validate(source, entries), nil
+}
+
+// ValidateYAML validates a YAML document whose top level is a mapping of configuration keys.
+func (s *Schema) ValidateYAML(source string, data []byte) (Violations, diabuddyErrors.ApiErrors) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s: invalid YAML", source), diabuddyErrors.WithInternalError(err))
+	}
+	if len(root.Content) == 0 {
+		return s.validate(source, nil), nil
+	}
+
+	document := root.Content[0]
+	if document.Kind != yaml.MappingNode {
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s:%d:%d: expected a YAML mapping", source, document.Line, document.Column))
+	}
+
+	var entries []entry
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		keyNode, valueNode := document.Content[i], document.Content[i+1]
+		var value any
+		if err := valueNode.Decode(&value); err != nil {
+			return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s:%d:%d: invalid YAML value", source, valueNode.Line, valueNode.Column), diabuddyErrors.WithInternalError(err))
+		}
+		entries = append(entries, entry{key: keyNode.Value, value: value, line: keyNode.Line, column: keyNode.Column})
+	}
+
+	return s.validate(source, entries), nil
+}
+
+// ValidateDotenv validates a dotenv file. Values are parsed with the same parser EnvManager uses.
+func (s *Schema) ValidateDotenv(source string, data []byte) (Violations, diabuddyErrors.ApiErrors) {
+	values, err := godotenv.UnmarshalBytes(data)
+	if err != nil {
+		return nil, diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s: invalid dotenv file", source), diabuddyErrors.WithInternalError(err))
+	}
+
+	var entries []entry
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		match := dotenvKeyPattern.FindStringSubmatchIndex(scanner.Text())
+		if match == nil {
+			continue
+		}
+		key := scanner.Text()[match[2]:match[3]]
+		value, ok := values[key]
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		entries = append(entries, entry{key: key, value: value, line: line, column: match[2] + 1})
+	}
+
+	return s.validate(source, entries), nil
+}
+
+// ValidateMap validates a plain key/value map such as one returned by EnvManager.ReadEnvironmentVariables.
+func (s *Schema) ValidateMap(source string, values map[string]string) Violations {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]entry, len(keys))
+	for i, key := range keys {
+		entries[i] = entry{key: key, value: values[key]}
+	}
+	return s.validate(source, entries)
+}
+
+func (s *Schema) validate(source string, entries []entry) Violations {
+	var violations Violations
+	present := make(map[string]entry, len(entries))
+
+	for _, e := range entries {
+		present[e.key] = e
+		property, ok := s.Properties[e.key]
+		if !ok {
+			if !s.AdditionalProperties {
+				violations = append(violations, Violation{Source: source, Key: e.key, Line: e.line, Column: e.column, Message: "is not a known configuration key"})
+			}
+			continue
+		}
+		if message := property.check(e.value); message != "" {
+			violations = append(violations, Violation{Source: source, Key: e.key, Line: e.line, Column: e.column, Message: message})
+		}
+	}
+
+	// Present but empty required keys are already reported by minLength, with their position.
+	for _, key := range s.Required {
+		if _, ok := present[key]; !ok {
+			violations = append(violations, Violation{Source: source, Key: key, Message: "is required"})
+		}
+	}
+
+	return violations
+}
+
+// check applies the keywords this package generates to a single decoded value and describes the first mismatch.
+func (p *Property) check(value any) string {
+	valueType := jsonType(value)
+	if !p.accepts(valueType) {
+		return fmt.Sprintf("must be of type %s, got %s", strings.Join(p.Type, " or "), valueType)
+	}
+
+	if text, ok := value.(string); ok {
+		if p.MinLength > 0 && len([]rune(text)) < p.MinLength {
+			return "must not be empty"
+		}
+		if p.Pattern != "" {
+			pattern, err := regexp.Compile(p.Pattern)
+			if err == nil && !pattern.MatchString(text) {
+				return fmt.Sprintf("must match pattern %s", p.Pattern)
+			}
+		}
+	}
+
+	if len(p.Enum) > 0 && !inEnum(p.Enum, value) {
+		allowed := make([]string, 0, len(p.Enum))
+		for _, candidate := range p.Enum {
+			if candidate != "" {
+				allowed = append(allowed, fmt.Sprint(candidate))
+			}
+		}
+		return fmt.Sprintf("must be one of: %s", strings.Join(allowed, ", "))
+	}
+
+	return ""
+}
+
+func (p *Property) accepts(valueType string) bool {
+	for _, allowed := range p.Type {
+		if allowed == valueType || (allowed == "number" && valueType == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func inEnum(enum []any, value any) bool {
+	for _, candidate := range enum {
+		if fmt.Sprint(candidate) == fmt.Sprint(value) && jsonType(candidate) == jsonType(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseError(source string, data []byte, offset int64, message string, err error) diabuddyErrors.ApiErrors {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	}
+	line, column := position(data, int(offset))
+	if err == nil {
+		err = errors.New(message)
+	}
+	return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s:%d:%d: %s", source, line, column, message), diabuddyErrors.WithInternalError(err))
+}
+
+// skipSeparators moves past whitespace and the comma between two members of a JSON object.
+func skipSeparators(data []byte, offset int64) int {
+	i := int(offset)
+	for i < len(data) && strings.ContainsRune(" \t\r\n,", rune(data[i])) {
+		i++
+	}
+	return i
+}
+
+// position converts a byte offset into a 1-based line and column.
+func position(data []byte, offset int) (int, int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return line, column
+}
@@ -0,0 +1,104 @@
+package securityconfig
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Middleware returns a handler that sets the security headers on every response and answers CORS requests
+// according to the configuration. Preflight requests are answered directly: with 204 when the origin, method
+// and headers are allowed, with 403 otherwise. Other requests from origins that are not allowed are passed on
+// without CORS headers, so the browser blocks the response. The handler keeps the values the section had when
+// Middleware was called; a later Reload does not change it.
+func (sc *SecurityConfig) Middleware(next http.Handler) http.Handler {
+	state := sc.state.Load()
+	securityHeaders := state.securityHeaders()
+	allowedMethods := strings.Join(state.methods, ", ")
+	allowedHeaders := strings.Join(state.headers, ", ")
+	maxAge := strconv.Itoa(int(state.maxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		for name, value := range securityHeaders {
+			header.Set(name, value)
+		}
+
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		header.Add("Vary", "Origin")
+
+		requestMethod := r.Header.Get("Access-Control-Request-Method")
+		preflight := r.Method == http.MethodOptions && requestMethod != ""
+		if !state.isAllowedOrigin(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header.Set("Access-Control-Allow-Origin", origin)
+		if state.allowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+		if !state.isAllowedMethod(requestMethod) || !state.allowsHeaders(requestHeaders) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		header.Set("Access-Control-Allow-Methods", allowedMethods)
+		if state.anyHeader {
+			if requestHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", requestHeaders)
+			}
+		} else if allowedHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowedHeaders)
+		}
+		if state.maxAge > 0 {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// allowsHeaders reports whether every header of an Access-Control-Request-Headers value is allowed.
+func (state *securityState) allowsHeaders(requestHeaders string) bool {
+	for _, header := range strings.Split(requestHeaders, ",") {
+		if header = strings.TrimSpace(header); header != "" && !state.isAllowedHeader(header) {
+			return false
+		}
+	}
+	return true
+}
+
+// securityHeaders returns the headers set on every response.
+func (state *securityState) securityHeaders() map[string]string {
+	headers := map[string]string{"X-Content-Type-Options": "nosniff"}
+	if state.hstsMaxAge > 0 {
+		hsts := "max-age=" + strconv.Itoa(int(state.hstsMaxAge.Seconds()))
+		if state.hstsIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		headers["Strict-Transport-Security"] = hsts
+	}
+	if state.csp != "" {
+		headers["Content-Security-Policy"] = state.csp
+	}
+	if state.frameOptions != "" {
+		headers["X-Frame-Options"] = state.frameOptions
+	}
+	return headers
+}
@@ -0,0 +1,359 @@
+package securityconfig
+
+import (
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// AnyOrigin and AnyHeader allow every origin or request header. Disabled turns off the Content-Security-Policy
+// or X-Frame-Options header; an empty value falls back to the default like any other key.
+const (
+	AnyOrigin = "*"
+	AnyHeader = "*"
+	Disabled  = "none"
+)
+
+// permissiveDefaults replace the registered, locked-down defaults in local and test environments, so that a
+// frontend on any dev server can call the API.
+var permissiveDefaults = map[string]string{
+	envmanager.CorsAllowedOriginsKey:   AnyOrigin,
+	envmanager.CorsAllowedHeadersKey:   AnyHeader,
+	envmanager.SecurityHSTSMaxAgeKey:   "0s",
+	envmanager.SecurityCSPKey:          "",
+	envmanager.SecurityFrameOptionsKey: "SAMEORIGIN",
+}
+
+var validMethod = regexp.MustCompile(`^[A-Z]+$`)
+
+var (
+	_ config.Introspectable = (*SecurityConfig)(nil)
+	_ config.Reloader       = (*SecurityConfig)(nil)
+)
+
+type SecurityConfig struct {
+	envManager *envmanager.EnvManager
+	defaults   map[string]string
+	// state holds the parsed values; Reload replaces it as a whole, so readers never see half a reload.
+	state atomic.Pointer[securityState]
+}
+
+type securityState struct {
+	origins               []string
+	originPatterns        []*regexp.Regexp
+	anyOrigin             bool
+	methods               []string
+	headers               []string
+	anyHeader             bool
+	allowCredentials      bool
+	maxAge                time.Duration
+	hstsMaxAge            time.Duration
+	hstsIncludeSubdomains bool
+	csp                   string
+	frameOptions          string
+}
+
+// NewSecurityConfig creates a SecurityConfig. Local and test environments get permissive CORS defaults.
+func NewSecurityConfig(envManager *envmanager.EnvManager) (*SecurityConfig, diabuddyErrors.ApiErrors) {
+	sc := &SecurityConfig{envManager: envManager}
+	if environment := envManager.Environment(); environment.IsLocal() || environment.IsTest() {
+		sc.defaults = permissiveDefaults
+	}
+	state, err := sc.resolve()
+	if err != nil {
+		return nil, err
+	}
+	sc.state.Store(state)
+	return sc, nil
+}
+
+// Get retrieves the value of an environment variable; in local and test environments the permissive defaults
+// win over the registered ones.
+func (sc *SecurityConfig) Get(key string, defaultValue ...string) string {
+	if len(defaultValue) == 0 || defaultValue[0] == "" {
+		if _, ok := sc.defaults[key]; ok {
+			value, _ := sc.Lookup(key)
+			return value
+		}
+	}
+	return sc.envManager.Get(key, defaultValue...)
+}
+
+// AllowedOrigins returns CORS_ALLOWED_ORIGINS plus the origin of APP_URL.
+func (sc *SecurityConfig) AllowedOrigins() []string {
+	return append([]string(nil), sc.state.Load().origins...)
+}
+
+// AllowedMethods returns CORS_ALLOWED_METHODS.
+func (sc *SecurityConfig) AllowedMethods() []string {
+	return append([]string(nil), sc.state.Load().methods...)
+}
+
+// AllowedHeaders returns CORS_ALLOWED_HEADERS.
+func (sc *SecurityConfig) AllowedHeaders() []string {
+	return append([]string(nil), sc.state.Load().headers...)
+}
+
+// AllowCredentials returns CORS_ALLOW_CREDENTIALS.
+func (sc *SecurityConfig) AllowCredentials() bool {
+	return sc.state.Load().allowCredentials
+}
+
+// MaxAge returns CORS_MAX_AGE.
+func (sc *SecurityConfig) MaxAge() time.Duration {
+	return sc.state.Load().maxAge
+}
+
+// HSTSMaxAge returns SECURITY_HSTS_MAX_AGE; 0 means no Strict-Transport-Security header.
+func (sc *SecurityConfig) HSTSMaxAge() time.Duration {
+	return sc.state.Load().hstsMaxAge
+}
+
+// HSTSIncludeSubdomains returns SECURITY_HSTS_INCLUDE_SUBDOMAINS.
+func (sc *SecurityConfig) HSTSIncludeSubdomains() bool {
+	return sc.state.Load().hstsIncludeSubdomains
+}
+
+// ContentSecurityPolicy returns SECURITY_CSP; empty means no Content-Security-Policy header, i.e. it is none.
+func (sc *SecurityConfig) ContentSecurityPolicy() string {
+	return sc.state.Load().csp
+}
+
+// FrameOptions returns SECURITY_FRAME_OPTIONS; empty means no X-Frame-Options header, i.e. it is none.
+func (sc *SecurityConfig) FrameOptions() string {
+	return sc.state.Load().frameOptions
+}
+
+// IsAllowedOrigin reports whether cross-origin requests from the origin are allowed. Origins are compared
+// case-insensitively; a * in a configured origin matches one or more characters other than / and :.
+func (sc *SecurityConfig) IsAllowedOrigin(origin string) bool {
+	return sc.state.Load().isAllowedOrigin(origin)
+}
+
+func (state *securityState) isAllowedOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if state.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, pattern := range state.originPatterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAllowedMethod reports whether the method may be used in cross-origin requests.
+func (sc *SecurityConfig) IsAllowedMethod(method string) bool {
+	return sc.state.Load().isAllowedMethod(method)
+}
+
+func (state *securityState) isAllowedMethod(method string) bool {
+	for _, allowed := range state.methods {
+		if allowed == strings.ToUpper(method) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAllowedHeader reports whether the request header may be sent in cross-origin requests.
+func (sc *SecurityConfig) IsAllowedHeader(header string) bool {
+	return sc.state.Load().isAllowedHeader(header)
+}
+
+func (state *securityState) isAllowedHeader(header string) bool {
+	if state.anyHeader {
+		return true
+	}
+	for _, allowed := range state.headers {
+		if strings.EqualFold(allowed, header) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload re-reads the CORS and security header values and swaps them in at once, so it may run while the section
+// is read. On error the previous values are kept.
+func (sc *SecurityConfig) Reload() diabuddyErrors.ApiErrors {
+	state, err := sc.resolve()
+	if err != nil {
+		return err
+	}
+	sc.state.Store(state)
+	return nil
+}
+
+// resolve reads and parses every CORS and security header value.
+func (sc *SecurityConfig) resolve() (*securityState, diabuddyErrors.ApiErrors) {
+	state := &securityState{}
+	origins := envmanager.SplitList(sc.Get(envmanager.CorsAllowedOriginsKey))
+	if appOrigin, err := originOf(sc.Get(envmanager.AppUrlKey)); err != nil {
+		return nil, err
+	} else if appOrigin != "" && !containsFold(origins, appOrigin) {
+		origins = append(origins, appOrigin)
+	}
+	var patterns []*regexp.Regexp
+	for _, origin := range origins {
+		if origin == AnyOrigin {
+			state.anyOrigin = true
+			continue
+		}
+		pattern, err := compileOrigin(origin)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	var methods []string
+	for _, method := range envmanager.SplitList(sc.Get(envmanager.CorsAllowedMethodsKey)) {
+		methods = append(methods, strings.ToUpper(method))
+	}
+	headers := envmanager.SplitList(sc.Get(envmanager.CorsAllowedHeadersKey))
+	for _, header := range headers {
+		if header == AnyHeader {
+			state.anyHeader = true
+		}
+	}
+
+	allowCredentials, err := config.Bool(sc, envmanager.CorsAllowCredentialsKey)
+	if err != nil {
+		return nil, err
+	}
+	hstsIncludeSubdomains, err := config.Bool(sc, envmanager.SecurityHSTSIncludeSubdomainsKey)
+	if err != nil {
+		return nil, err
+	}
+	durations := []struct {
+		key    string
+		target *time.Duration
+	}{
+		{envmanager.CorsMaxAgeKey, &state.maxAge},
+		{envmanager.SecurityHSTSMaxAgeKey, &state.hstsMaxAge},
+	}
+	for _, duration := range durations {
+		value, err := config.Duration(sc, duration.key)
+		if err != nil {
+			return nil, err
+		}
+		if value < 0 {
+			return nil, config.InvalidValueError(duration.key, sc.Get(duration.key), "a duration of zero or more", nil)
+		}
+		*duration.target = value
+	}
+
+	frameOptions := strings.ToUpper(sc.Get(envmanager.SecurityFrameOptionsKey))
+	if strings.EqualFold(frameOptions, Disabled) {
+		frameOptions = ""
+	}
+	if frameOptions != "" && frameOptions != "DENY" && frameOptions != "SAMEORIGIN" {
+		return nil, config.InvalidValueError(envmanager.SecurityFrameOptionsKey, frameOptions, "DENY, SAMEORIGIN or "+Disabled, nil)
+	}
+	csp := sc.Get(envmanager.SecurityCSPKey)
+	if strings.EqualFold(csp, Disabled) {
+		csp = ""
+	}
+
+	state.origins = origins
+	state.originPatterns = patterns
+	state.methods = methods
+	state.headers = headers
+	state.allowCredentials = allowCredentials
+	state.hstsIncludeSubdomains = hstsIncludeSubdomains
+	state.csp = csp
+	state.frameOptions = frameOptions
+	return state, nil
+}
+
+// originOf returns the scheme://host[:port] origin of APP_URL.
+func originOf(appUrl string) (string, diabuddyErrors.ApiErrors) {
+	if appUrl == "" {
+		return "", nil
+	}
+	parsed, err := url.Parse(appUrl)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return "", config.InvalidValueError(envmanager.AppUrlKey, appUrl, "an absolute URL", err)
+	}
+	return strings.ToLower(parsed.Scheme + "://" + parsed.Host), nil
+}
+
+// compileOrigin turns an origin such as https://*.example.com into a regular expression.
+func compileOrigin(origin string) (*regexp.Regexp, diabuddyErrors.ApiErrors) {
+	normalized := strings.ToLower(strings.TrimSuffix(origin, "/"))
+	scheme, host, ok := strings.Cut(normalized, "://")
+	if !ok || (scheme != "http" && scheme != "https") || host == "" || strings.Contains(host, "/") {
+		return nil, config.InvalidValueError(envmanager.CorsAllowedOriginsKey, origin, "an origin such as https://app.example.com or https://*.example.com", nil)
+	}
+	parts := strings.Split(normalized, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, "[^/:]+") + "$"), nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(strings.TrimSuffix(candidate, "/"), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys returns the keys owned by the security section.
+func (sc *SecurityConfig) Keys() []string {
+	return config.KeyNames(sc.Describe())
+}
+
+// RequiredKeys returns the keys Validate insists on; every security key has a default.
+func (sc *SecurityConfig) RequiredKeys() []string {
+	return nil
+}
+
+// Describe returns the definitions of the keys owned by the security section.
+func (sc *SecurityConfig) Describe() []envmanager.KeyDefinition {
+	return envmanager.SectionKeyDefinitions(envmanager.SecuritySection)
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults.
+func (sc *SecurityConfig) Lookup(key string) (string, bool) {
+	value, found, source := sc.envManager.Lookup(key)
+	if environmentDefault, ok := sc.defaults[key]; ok && (!found || source == envmanager.SourceDefault) {
+		return environmentDefault, environmentDefault != ""
+	}
+	return value, found
+}
+
+// Snapshot returns the current values of the security section.
+func (sc *SecurityConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(sc, sc.Describe())
+}
+
+// Validate rejects combinations browsers refuse or that open production up: credentials with a * origin,
+// and a * origin in production.
+func (sc *SecurityConfig) Validate() diabuddyErrors.ApiErrors {
+	state := sc.state.Load()
+	if state.anyOrigin && state.allowCredentials {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s cannot be true while %s contains %s", envmanager.CorsAllowCredentialsKey, envmanager.CorsAllowedOriginsKey, AnyOrigin))
+	}
+	if state.anyOrigin && sc.envManager.Environment().IsProduction() {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s must list explicit origins in production, got %s", envmanager.CorsAllowedOriginsKey, AnyOrigin))
+	}
+	for _, method := range state.methods {
+		if !validMethod.MatchString(method) {
+			return config.InvalidValueError(envmanager.CorsAllowedMethodsKey, method, "an HTTP method", nil)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package serverconfig
+
+import (
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var requiredKeys = []string{envmanager.ServerPortKey}
+
+var (
+	_ config.Introspectable = (*ServerConfig)(nil)
+	_ config.Reloader       = (*ServerConfig)(nil)
+)
+
+type ServerConfig struct {
+	envManager *envmanager.EnvManager
+	defaults   map[string]string
+	// state holds the parsed values; Reload replaces it as a whole, so readers never see half a reload.
+	state atomic.Pointer[serverState]
+}
+
+type serverState struct {
+	host              string
+	port              int
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxHeaderBytes    int
+	shutdownTimeout   time.Duration
+	trustedProxies    []*net.IPNet
+	tlsCertFile       string
+	tlsKeyFile        string
+}
+
+// ConfigOption Option function type for configuring ServerConfig.
+type ConfigOption func(*ServerConfig) diabuddyErrors.ApiErrors
+
+// WithDefault overrides the registered default of a server key for this service. Values set in the
+// environment still win.
+func WithDefault(key, value string) ConfigOption {
+	return func(sc *ServerConfig) diabuddyErrors.ApiErrors {
+		if definition, ok := envmanager.LookupKeyDefinition(key); !ok || definition.Section != envmanager.ServerSection {
+			return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("%s is not a server key", key))
+		}
+		sc.defaults[key] = value
+		return nil
+	}
+}
+
+// NewServerConfig creates a ServerConfig with provided options. A malformed port or timeout fails here.
+func NewServerConfig(envManager *envmanager.EnvManager, options ...ConfigOption) (*ServerConfig, diabuddyErrors.ApiErrors) {
+	sc := &ServerConfig{
+		envManager: envManager,
+		defaults:   make(map[string]string),
+	}
+	for _, option := range options {
+		if err := option(sc); err != nil {
+			return nil, err
+		}
+	}
+	state, err := sc.resolve()
+	if err != nil {
+		return nil, err
+	}
+	sc.state.Store(state)
+	return sc, nil
+}
+
+// Get retrieves the value of an environment variable; service defaults given with WithDefault win over the
+// registered ones.
+func (sc *ServerConfig) Get(key string, defaultValue ...string) string {
+	if len(defaultValue) == 0 || defaultValue[0] == "" {
+		if serviceDefault, ok := sc.defaults[key]; ok {
+			defaultValue = []string{serviceDefault}
+		}
+	}
+	return sc.envManager.Get(key, defaultValue...)
+}
+
+// Host returns SERVER_HOST; empty means all interfaces.
+func (sc *ServerConfig) Host() string {
+	return sc.state.Load().host
+}
+
+// Port returns APP_PORT.
+func (sc *ServerConfig) Port() int {
+	return sc.state.Load().port
+}
+
+// Addr returns the listen address built from host and port.
+func (sc *ServerConfig) Addr() string {
+	state := sc.state.Load()
+	return net.JoinHostPort(state.host, strconv.Itoa(state.port))
+}
+
+// ReadTimeout returns SERVER_READ_TIMEOUT.
+func (sc *ServerConfig) ReadTimeout() time.Duration {
+	return sc.state.Load().readTimeout
+}
+
+// ReadHeaderTimeout returns SERVER_READ_HEADER_TIMEOUT.
+func (sc *ServerConfig) ReadHeaderTimeout() time.Duration {
+	return sc.state.Load().readHeaderTimeout
+}
+
+// WriteTimeout returns SERVER_WRITE_TIMEOUT.
+func (sc *ServerConfig) WriteTimeout() time.Duration {
+	return sc.state.Load().writeTimeout
+}
+
+// IdleTimeout returns SERVER_IDLE_TIMEOUT.
+func (sc *ServerConfig) IdleTimeout() time.Duration {
+	return sc.state.Load().idleTimeout
+}
+
+// MaxHeaderBytes returns SERVER_MAX_HEADER_BYTES.
+func (sc *ServerConfig) MaxHeaderBytes() int {
+	return sc.state.Load().maxHeaderBytes
+}
+
+// ShutdownTimeout returns SERVER_SHUTDOWN_TIMEOUT, meant as the deadline of the context passed to
+// http.Server.Shutdown.
+func (sc *ServerConfig) ShutdownTimeout() time.Duration {
+	return sc.state.Load().shutdownTimeout
+}
+
+// TrustedProxies returns the networks of SERVER_TRUSTED_PROXIES; single IPs are returned as /32 or /128.
+func (sc *ServerConfig) TrustedProxies() []*net.IPNet {
+	trustedProxies := sc.state.Load().trustedProxies
+	proxies := make([]*net.IPNet, len(trustedProxies))
+	copy(proxies, trustedProxies)
+	return proxies
+}
+
+// IsTrustedProxy reports whether the IP belongs to one of the trusted proxies.
+func (sc *ServerConfig) IsTrustedProxy(ip net.IP) bool {
+	for _, network := range sc.state.Load().trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// TLSEnabled reports whether both a TLS certificate and key are configured.
+func (sc *ServerConfig) TLSEnabled() bool {
+	state := sc.state.Load()
+	return state.tlsCertFile != "" && state.tlsKeyFile != ""
+}
+
+// TLSCertFile returns SERVER_TLS_CERT_FILE.
+func (sc *ServerConfig) TLSCertFile() string {
+	return sc.state.Load().tlsCertFile
+}
+
+// TLSKeyFile returns SERVER_TLS_KEY_FILE.
+func (sc *ServerConfig) TLSKeyFile() string {
+	return sc.state.Load().tlsKeyFile
+}
+
+// NewHTTPServer returns an http.Server for the handler with the configured address, timeouts and header limit.
+// Start it with ListenAndServeTLS(sc.TLSCertFile(), sc.TLSKeyFile()) when TLSEnabled reports true.
+func (sc *ServerConfig) NewHTTPServer(handler http.Handler) *http.Server {
+	state := sc.state.Load()
+	return &http.Server{
+		Addr:              net.JoinHostPort(state.host, strconv.Itoa(state.port)),
+		Handler:           handler,
+		ReadTimeout:       state.readTimeout,
+		ReadHeaderTimeout: state.readHeaderTimeout,
+		WriteTimeout:      state.writeTimeout,
+		IdleTimeout:       state.idleTimeout,
+		MaxHeaderBytes:    state.maxHeaderBytes,
+	}
+}
+
+// Reload re-reads the server values and swaps them in at once, so it may run while the section is read. On error
+// the previous values are kept.
+func (sc *ServerConfig) Reload() diabuddyErrors.ApiErrors {
+	state, err := sc.resolve()
+	if err != nil {
+		return err
+	}
+	sc.state.Store(state)
+	return nil
+}
+
+// resolve reads and parses every server value.
+func (sc *ServerConfig) resolve() (*serverState, diabuddyErrors.ApiErrors) {
+	state := &serverState{}
+	port, err := config.Int(sc, envmanager.ServerPortKey)
+	if err != nil {
+		return nil, err
+	}
+
+	durations := []struct {
+		key    string
+		target *time.Duration
+	}{
+		{envmanager.ServerReadTimeoutKey, &state.readTimeout},
+		{envmanager.ServerReadHeaderTimeoutKey, &state.readHeaderTimeout},
+		{envmanager.ServerWriteTimeoutKey, &state.writeTimeout},
+		{envmanager.ServerIdleTimeoutKey, &state.idleTimeout},
+		{envmanager.ServerShutdownTimeoutKey, &state.shutdownTimeout},
+	}
+	for _, duration := range durations {
+		value, err := config.Duration(sc, duration.key)
+		if err != nil {
+			return nil, err
+		}
+		if value < 0 {
+			return nil, config.InvalidValueError(duration.key, sc.Get(duration.key), "a duration of zero or more", nil)
+		}
+		*duration.target = value
+	}
+
+	maxHeaderBytes, err := config.Int(sc, envmanager.ServerMaxHeaderBytesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	trustedProxies, err := parseTrustedProxies(sc.envManager.GetList(envmanager.ServerTrustedProxiesKey, sc.defaults[envmanager.ServerTrustedProxiesKey]))
+	if err != nil {
+		return nil, err
+	}
+
+	state.host = sc.Get(envmanager.ServerHostKey)
+	state.port = port
+	state.maxHeaderBytes = maxHeaderBytes
+	state.trustedProxies = trustedProxies
+	state.tlsCertFile = sc.Get(envmanager.ServerTLSCertFileKey)
+	state.tlsKeyFile = sc.Get(envmanager.ServerTLSKeyFileKey)
+	return state, nil
+}
+
+func parseTrustedProxies(values []string) ([]*net.IPNet, diabuddyErrors.ApiErrors) {
+	proxies := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if _, network, err := net.ParseCIDR(value); err == nil {
+			proxies = append(proxies, network)
+			continue
+		}
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, config.InvalidValueError(envmanager.ServerTrustedProxiesKey, value, "an IP address or CIDR", nil)
+		}
+		bits := 8 * net.IPv6len
+		if ipv4 := ip.To4(); ipv4 != nil {
+			ip, bits = ipv4, 8*net.IPv4len
+		}
+		proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return proxies, nil
+}
+
+// Keys returns the keys owned by the server section.
+func (sc *ServerConfig) Keys() []string {
+	return config.KeyNames(sc.Describe())
+}
+
+// RequiredKeys returns the keys Validate insists on.
+func (sc *ServerConfig) RequiredKeys() []string {
+	return append([]string(nil), requiredKeys...)
+}
+
+// Describe returns the definitions of the keys owned by the server section.
+func (sc *ServerConfig) Describe() []envmanager.KeyDefinition {
+	return envmanager.SectionKeyDefinitions(envmanager.ServerSection)
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults.
+func (sc *ServerConfig) Lookup(key string) (string, bool) {
+	value, found, source := sc.envManager.Lookup(key)
+	if serviceDefault, ok := sc.defaults[key]; ok && serviceDefault != "" && (!found || source == envmanager.SourceDefault) {
+		return serviceDefault, true
+	}
+	return value, found
+}
+
+// Snapshot returns the current values of the server section.
+func (sc *ServerConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(sc, sc.Describe())
+}
+
+func (sc *ServerConfig) Validate() diabuddyErrors.ApiErrors {
+	var missingKeys []string
+	for _, key := range requiredKeys {
+		if strings.TrimSpace(sc.Get(key)) == "" {
+			missingKeys = append(missingKeys, key)
+		}
+	}
+	if len(missingKeys) > 0 {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("missing required key(s): %s", strings.Join(missingKeys, ", ")))
+	}
+
+	state := sc.state.Load()
+	if state.port < 1 || state.port > 65535 {
+		return config.InvalidValueError(envmanager.ServerPortKey, strconv.Itoa(state.port), "a port between 1 and 65535", nil)
+	}
+	if (state.tlsCertFile == "") != (state.tlsKeyFile == "") {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s and %s must be set together", envmanager.ServerTLSCertFileKey, envmanager.ServerTLSKeyFileKey))
+	}
+	for _, file := range []struct{ key, path string }{{envmanager.ServerTLSCertFileKey, state.tlsCertFile}, {envmanager.ServerTLSKeyFileKey, state.tlsKeyFile}} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s points to a file that cannot be read: %s", file.key, file.path), diabuddyErrors.WithInternalError(err))
+		}
+	}
+	return nil
+}
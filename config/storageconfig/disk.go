@@ -0,0 +1,92 @@
+package storageconfig
+
+import (
+	"fmt"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Drivers accepted in STORAGE_<NAME>_DRIVER.
+const (
+	LocalDriver = "local"
+	S3Driver    = "s3"
+)
+
+// Suffixes of the per-disk keys, e.g. STORAGE_PHOTOS_BUCKET.
+const (
+	DriverKeySuffix          = "DRIVER"
+	RootKeySuffix            = "ROOT"
+	URLKeySuffix             = "URL"
+	EndpointKeySuffix        = "ENDPOINT"
+	RegionKeySuffix          = "REGION"
+	BucketKeySuffix          = "BUCKET"
+	UsePathStyleKeySuffix    = "USE_PATH_STYLE"
+	AccessKeyIDKeySuffix     = "ACCESS_KEY_ID"
+	SecretAccessKeyKeySuffix = "SECRET_ACCESS_KEY"
+)
+
+// Disk is the client independent configuration of one disk.
+type Disk struct {
+	Name   string
+	Driver string
+	// Root is the absolute directory of a local disk, or the key prefix inside the bucket of an s3 disk.
+	Root string
+	// URL is the public base URL under which the files below Root are served; it may be empty.
+	URL string
+	// Endpoint overrides the S3 endpoint, e.g. for MinIO; empty uses AWS.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	UsePathStyle    bool
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// DiskKey returns a per-disk key: DiskKey("meal-photos", BucketKeySuffix) is STORAGE_MEAL_PHOTOS_BUCKET.
+func DiskKey(name, suffix string) string {
+	return "STORAGE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_" + suffix
+}
+
+// Path returns the location of a file on the disk: a file path below Root for a local disk, and an object key
+// below Root for an s3 disk. The elements usually come from users, so a path that leaves Root is rejected.
+func (d Disk) Path(elem ...string) (string, diabuddyErrors.ApiErrors) {
+	relative, err := d.relativePath(elem)
+	if err != nil {
+		return "", err
+	}
+	if d.Driver == S3Driver {
+		return strings.TrimPrefix(path.Join(d.Root, relative), "/"), nil
+	}
+	return filepath.Join(d.Root, filepath.FromSlash(relative)), nil
+}
+
+// PublicURL returns the public URL of a file, or an empty string when the disk has no URL. Like Path, it
+// rejects a path that leaves Root.
+func (d Disk) PublicURL(elem ...string) (string, diabuddyErrors.ApiErrors) {
+	relative, err := d.relativePath(elem)
+	if err != nil || d.URL == "" {
+		return "", err
+	}
+	segments := strings.Split(relative, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.TrimSuffix(d.URL, "/") + "/" + strings.Join(segments, "/"), nil
+}
+
+// relativePath joins the elements into a clean slash separated path relative to Root. A leading slash is
+// relative to Root as well; any path that cleans to a location above Root is an error.
+func (d Disk) relativePath(elem []string) (string, diabuddyErrors.ApiErrors) {
+	slashed := make([]string, len(elem))
+	for i, e := range elem {
+		slashed[i] = filepath.ToSlash(e)
+	}
+	relative := strings.TrimPrefix(path.Join(slashed...), "/")
+	if relative == ".." || strings.HasPrefix(relative, "../") {
+		return "", diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("path %q leaves the root of disk %s", path.Join(slashed...), d.Name))
+	}
+	return relative, nil
+}
@@ -0,0 +1,320 @@
+package storageconfig
+
+import (
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	appconfig "github.com/hbttundar/diabuddy-api-config/config/appconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	validDiskName   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+	validBucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+)
+
+var (
+	_ config.Introspectable = (*StorageConfig)(nil)
+	_ config.Reloader       = (*StorageConfig)(nil)
+)
+
+type StorageConfig struct {
+	envManager *envmanager.EnvManager
+	appConfig  *appconfig.AppConfig
+	// state holds the parsed values; Reload replaces it as a whole, so readers never see half a reload.
+	state atomic.Pointer[storageState]
+}
+
+type storageState struct {
+	names       []string
+	disks       map[string]Disk
+	defaultDisk string
+}
+
+// ConfigOption Option function type for configuring StorageConfig.
+type ConfigOption func(*StorageConfig) diabuddyErrors.ApiErrors
+
+// WithAppConfig roots the local disks under the storage path of the AppConfig. Without it, StorageConfig creates
+// an AppConfig from the same EnvManager.
+func WithAppConfig(appConfig *appconfig.AppConfig) ConfigOption {
+	return func(sc *StorageConfig) diabuddyErrors.ApiErrors {
+		sc.appConfig = appConfig
+		return nil
+	}
+}
+
+// NewStorageConfig creates a StorageConfig with one Disk for every name in STORAGE_DISKS.
+func NewStorageConfig(envManager *envmanager.EnvManager, options ...ConfigOption) (*StorageConfig, diabuddyErrors.ApiErrors) {
+	sc := &StorageConfig{envManager: envManager}
+	for _, option := range options {
+		if err := option(sc); err != nil {
+			return nil, err
+		}
+	}
+	if sc.appConfig == nil {
+		appConfig, err := appconfig.NewAppConfig(envManager)
+		if err != nil {
+			return nil, err
+		}
+		sc.appConfig = appConfig
+	}
+	state, err := sc.resolve()
+	if err != nil {
+		return nil, err
+	}
+	sc.state.Store(state)
+	return sc, nil
+}
+
+func (sc *StorageConfig) Get(key string, defaultValue ...string) string {
+	return sc.envManager.Get(key, defaultValue...)
+}
+
+// Disks returns the disk names in the order of STORAGE_DISKS.
+func (sc *StorageConfig) Disks() []string {
+	return append([]string(nil), sc.state.Load().names...)
+}
+
+// Disk returns the disk with the name.
+func (sc *StorageConfig) Disk(name string) (Disk, bool) {
+	disk, ok := sc.state.Load().disks[name]
+	return disk, ok
+}
+
+// DefaultDisk returns the disk named by STORAGE_DEFAULT_DISK, or the first disk when it is empty.
+func (sc *StorageConfig) DefaultDisk() Disk {
+	state := sc.state.Load()
+	return state.disks[state.defaultDisk]
+}
+
+// Reload re-reads the storage values and swaps them in at once, so it may run while the section is read. On error
+// the previous values are kept.
+func (sc *StorageConfig) Reload() diabuddyErrors.ApiErrors {
+	state, err := sc.resolve()
+	if err != nil {
+		return err
+	}
+	sc.state.Store(state)
+	return nil
+}
+
+// resolve reads the disk names and the per-disk keys of each disk.
+func (sc *StorageConfig) resolve() (*storageState, diabuddyErrors.ApiErrors) {
+	state := &storageState{}
+	names := sc.envManager.GetList(envmanager.StorageDisksKey)
+	disks := make(map[string]Disk, len(names))
+	owners := make(map[string]string, len(names))
+	for _, name := range names {
+		if !validDiskName.MatchString(name) {
+			return nil, config.InvalidValueError(envmanager.StorageDisksKey, name, "a disk name of letters, digits, - and _", nil)
+		}
+		// Names that differ only in case or in - and _ read the same STORAGE_<NAME>_* keys.
+		driverKey := DiskKey(name, DriverKeySuffix)
+		if owner, ok := owners[driverKey]; ok {
+			return nil, config.InvalidValueError(envmanager.StorageDisksKey, name, "a list of disk names with their own keys, but "+owner+" uses the same keys", nil)
+		}
+		owners[driverKey] = name
+		sc.envManager.Declare(diskKeys(name)...)
+		disk, err := sc.resolveDisk(name)
+		if err != nil {
+			return nil, err
+		}
+		disks[name] = disk
+	}
+
+	defaultDisk := sc.Get(envmanager.StorageDefaultDiskKey)
+	if defaultDisk == "" && len(names) > 0 {
+		defaultDisk = names[0]
+	}
+
+	state.names = names
+	state.disks = disks
+	state.defaultDisk = defaultDisk
+	return state, nil
+}
+
+func (sc *StorageConfig) resolveDisk(name string) (Disk, diabuddyErrors.ApiErrors) {
+	driver := strings.ToLower(sc.Get(DiskKey(name, DriverKeySuffix)))
+	if driver == "" && name == LocalDriver {
+		driver = LocalDriver
+	}
+	if driver != "" && driver != LocalDriver && driver != S3Driver {
+		return Disk{}, config.InvalidValueError(DiskKey(name, DriverKeySuffix), driver, "local or s3", nil)
+	}
+
+	disk := Disk{
+		Name:            name,
+		Driver:          driver,
+		Root:            sc.Get(DiskKey(name, RootKeySuffix)),
+		URL:             sc.Get(DiskKey(name, URLKeySuffix)),
+		Endpoint:        sc.Get(DiskKey(name, EndpointKeySuffix)),
+		Region:          sc.Get(DiskKey(name, RegionKeySuffix)),
+		Bucket:          sc.Get(DiskKey(name, BucketKeySuffix)),
+		AccessKeyID:     sc.Get(DiskKey(name, AccessKeyIDKeySuffix)),
+		SecretAccessKey: sc.Get(DiskKey(name, SecretAccessKeyKeySuffix)),
+	}
+	for _, key := range []string{DiskKey(name, URLKeySuffix), DiskKey(name, EndpointKeySuffix)} {
+		if value := sc.Get(key); value != "" {
+			if parsed, err := url.Parse(value); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return Disk{}, config.InvalidValueError(key, value, "an http or https URL", err)
+			}
+		}
+	}
+	usePathStyle, err := config.Bool(sc, DiskKey(name, UsePathStyleKeySuffix))
+	if err != nil {
+		return Disk{}, err
+	}
+	disk.UsePathStyle = usePathStyle
+
+	if driver == LocalDriver {
+		if disk.Root == "" {
+			disk.Root = filepath.Join("app", name)
+		}
+		if !filepath.IsAbs(disk.Root) {
+			root, err := sc.appConfig.StoragePath(disk.Root)
+			if err != nil {
+				return Disk{}, err
+			}
+			disk.Root = root
+		}
+		disk.Root = filepath.Clean(disk.Root)
+	} else {
+		disk.Root = strings.Trim(disk.Root, "/")
+	}
+	return disk, nil
+}
+
+// diskKeys returns every per-disk key of a disk.
+func diskKeys(name string) []string {
+	suffixes := []string{DriverKeySuffix, RootKeySuffix, URLKeySuffix, EndpointKeySuffix, RegionKeySuffix, BucketKeySuffix, UsePathStyleKeySuffix, AccessKeyIDKeySuffix, SecretAccessKeyKeySuffix}
+	keys := make([]string, 0, len(suffixes))
+	for _, suffix := range suffixes {
+		keys = append(keys, DiskKey(name, suffix))
+	}
+	return keys
+}
+
+// Keys returns the keys owned by the storage section, including the per-disk keys of the configured disks.
+func (sc *StorageConfig) Keys() []string {
+	return config.KeyNames(sc.Describe())
+}
+
+// RequiredKeys returns the keys Validate insists on: the driver of every disk, and the bucket and region of the
+// s3 disks.
+func (sc *StorageConfig) RequiredKeys() []string {
+	state := sc.state.Load()
+	var keys []string
+	for _, name := range state.names {
+		if name != LocalDriver {
+			keys = append(keys, DiskKey(name, DriverKeySuffix))
+		}
+		if state.disks[name].Driver == S3Driver {
+			keys = append(keys, DiskKey(name, BucketKeySuffix), DiskKey(name, RegionKeySuffix))
+		}
+	}
+	return keys
+}
+
+// Describe returns the definitions of the keys owned by the storage section, followed by the per-disk keys of
+// the configured disks.
+func (sc *StorageConfig) Describe() []envmanager.KeyDefinition {
+	definitions := envmanager.SectionKeyDefinitions(envmanager.StorageSection)
+	for _, name := range sc.state.Load().names {
+		for _, key := range diskKeys(name) {
+			definitions = append(definitions, envmanager.KeyDefinition{
+				Name:      key,
+				Section:   envmanager.StorageSection,
+				Sensitive: strings.HasSuffix(key, SecretAccessKeyKeySuffix),
+			})
+		}
+	}
+	return definitions
+}
+
+// Lookup returns the value of a key and whether it was found in the environment or the defaults.
+func (sc *StorageConfig) Lookup(key string) (string, bool) {
+	value, found, _ := sc.envManager.Lookup(key)
+	return value, found
+}
+
+// Snapshot returns the current values of the storage section.
+func (sc *StorageConfig) Snapshot() map[string]string {
+	return config.SnapshotOf(sc, sc.Describe())
+}
+
+// Validate checks that the required keys are present, that the default disk exists, that bucket names follow
+// the S3 naming rules, that S3 credentials are set together and that the root of every local disk is writable.
+// Missing local roots are created.
+func (sc *StorageConfig) Validate() diabuddyErrors.ApiErrors {
+	state := sc.state.Load()
+	var missingKeys []string
+	for _, key := range sc.RequiredKeys() {
+		if strings.TrimSpace(sc.Get(key)) == "" {
+			missingKeys = append(missingKeys, key)
+		}
+	}
+	if len(missingKeys) > 0 {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("missing required key(s): %s", strings.Join(missingKeys, ", ")))
+	}
+
+	if !slices.Contains(state.names, state.defaultDisk) {
+		return config.InvalidValueError(envmanager.StorageDefaultDiskKey, state.defaultDisk, "one of the disks in "+envmanager.StorageDisksKey, nil)
+	}
+	for _, name := range state.names {
+		disk := state.disks[name]
+		var err diabuddyErrors.ApiErrors
+		if disk.Driver == S3Driver {
+			err = validateS3Disk(disk)
+		} else {
+			err = validateLocalDisk(disk)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateS3Disk(disk Disk) diabuddyErrors.ApiErrors {
+	if !isValidBucketName(disk.Bucket) {
+		return config.InvalidValueError(DiskKey(disk.Name, BucketKeySuffix), disk.Bucket, "a bucket name of 3 to 63 lower case letters, digits, dots and hyphens", nil)
+	}
+	if (disk.AccessKeyID == "") != (disk.SecretAccessKey == "") {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("%s and %s must be set together", DiskKey(disk.Name, AccessKeyIDKeySuffix), DiskKey(disk.Name, SecretAccessKeyKeySuffix)))
+	}
+	return nil
+}
+
+// isValidBucketName applies the S3 rules for general purpose buckets.
+func isValidBucketName(bucket string) bool {
+	if !validBucketName.MatchString(bucket) || strings.Contains(bucket, "..") || net.ParseIP(bucket) != nil {
+		return false
+	}
+	return !strings.HasPrefix(bucket, "xn--") && !strings.HasPrefix(bucket, "sthree-") &&
+		!strings.HasSuffix(bucket, "-s3alias") && !strings.HasSuffix(bucket, "--ol-s3")
+}
+
+func validateLocalDisk(disk Disk) diabuddyErrors.ApiErrors {
+	if err := os.MkdirAll(disk.Root, 0o755); err != nil {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("could not create the root of disk %s: %s", disk.Name, disk.Root), diabuddyErrors.WithInternalError(err))
+	}
+	file, err := os.CreateTemp(disk.Root, ".write-check-*")
+	if err == nil {
+		name := file.Name()
+		if err = file.Close(); err == nil {
+			err = os.Remove(name)
+		}
+	}
+	if err != nil {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("the root of disk %s is not writable: %s", disk.Name, disk.Root), diabuddyErrors.WithInternalError(err))
+	}
+	return nil
+}
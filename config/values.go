@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"strconv"
+	"time"
+)
+
+// InvalidValueError reports a value that cannot be parsed, e.g. "APP_DEBUG must be a boolean, got "sometimes"".
+func InvalidValueError(key, value, expected string, err error) diabuddyErrors.ApiErrors {
+	message := fmt.Sprintf("%s must be %s, got %q", key, expected, value)
+	if err == nil {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, message)
+	}
+	return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, message, diabuddyErrors.WithInternalError(err))
+}
+
+// Bool reads a boolean key from the section; an empty value means false.
+func Bool(section Config, key string) (bool, diabuddyErrors.ApiErrors) {
+	value := section.Get(key)
+	if value == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, InvalidValueError(key, value, "a boolean", err)
+	}
+	return parsed, nil
+}
+
+// Int reads an integer key from the section; an empty value means 0.
+func Int(section Config, key string) (int, diabuddyErrors.ApiErrors) {
+	value := section.Get(key)
+	if value == "" {
+		return 0, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, InvalidValueError(key, value, "an integer", err)
+	}
+	return parsed, nil
+}
+
+// Duration reads a duration key such as 15s or 1m30s from the section; an empty value means 0.
+func Duration(section Config, key string) (time.Duration, diabuddyErrors.ApiErrors) {
+	value := section.Get(key)
+	if value == "" {
+		return 0, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, InvalidValueError(key, value, "a duration such as 15s", err)
+	}
+	return parsed, nil
+}
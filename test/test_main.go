@@ -1,10 +1,20 @@
 package testmain
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
 	"log"
+	"math/big"
 	"os"
 	"path/filepath"
+	"testing"
+	"time"
 )
 
 var (
@@ -12,12 +22,27 @@ var (
 		envmanager.AppNameKey,
 		envmanager.AppEnvKey,
 		envmanager.AppEncryptionKey,
+		envmanager.AppPreviousKeysKey,
 		envmanager.AppDebugKey,
 		envmanager.AppUrlKey,
 		envmanager.AppTimezoneKey,
 		envmanager.AppLocaleKey,
 		envmanager.AppFallbackLocaleKey,
+		envmanager.AppSupportedLocalesKey,
 		envmanager.AppCipherKey,
+		envmanager.AuthAlgorithmKey,
+		envmanager.AuthSecretKey,
+		envmanager.AuthKeyIDKey,
+		envmanager.AuthPrivateKeyKey,
+		envmanager.AuthPrivateKeyFileKey,
+		envmanager.AuthPublicKeyKey,
+		envmanager.AuthPublicKeyFileKey,
+		envmanager.AuthPreviousKeysKey,
+		envmanager.AuthIssuerKey,
+		envmanager.AuthAudienceKey,
+		envmanager.AuthAccessTokenTTLKey,
+		envmanager.AuthRefreshTokenTTLKey,
+		envmanager.AuthClockSkewKey,
 		envmanager.DbUrlKey,
 		envmanager.DbHostKey,
 		envmanager.DbPortKey,
@@ -25,6 +50,94 @@ var (
 		envmanager.DbUsernameKey,
 		envmanager.DbPasswordKey,
 		envmanager.DbSslModeKey,
+		envmanager.DbConnectionsKey,
+		envmanager.OtelSDKDisabledKey,
+		envmanager.OtelServiceNameKey,
+		envmanager.OtelResourceAttributesKey,
+		envmanager.OtelTracesSamplerKey,
+		envmanager.OtelTracesSamplerArgKey,
+		envmanager.OtelTracesExporterKey,
+		envmanager.OtelMetricsExporterKey,
+		envmanager.OtelExporterOTLPEndpointKey,
+		envmanager.OtelExporterOTLPProtocolKey,
+		envmanager.OtelExporterOTLPHeadersKey,
+		envmanager.OtelExporterOTLPTimeoutKey,
+		envmanager.OtelExporterOTLPCompressionKey,
+		envmanager.OtelMetricExportIntervalKey,
+		envmanager.StorageDisksKey,
+		envmanager.StorageDefaultDiskKey,
+		envmanager.MailDriverKey,
+		envmanager.MailHostKey,
+		envmanager.MailPortKey,
+		envmanager.MailEncryptionKey,
+		envmanager.MailUsernameKey,
+		envmanager.MailPasswordKey,
+		envmanager.MailFromAddressKey,
+		envmanager.MailFromNameKey,
+		envmanager.MailDialTimeoutKey,
+		envmanager.MailSendTimeoutKey,
+		envmanager.RedisURLKey,
+		envmanager.RedisHostKey,
+		envmanager.RedisPortKey,
+		envmanager.RedisUsernameKey,
+		envmanager.RedisPasswordKey,
+		envmanager.RedisDBKey,
+		envmanager.RedisTLSEnabledKey,
+		envmanager.RedisTLSCAFileKey,
+		envmanager.RedisSentinelMasterKey,
+		envmanager.RedisSentinelAddressesKey,
+		envmanager.RedisSentinelPasswordKey,
+		envmanager.RedisClusterAddressesKey,
+		envmanager.RedisKeyPrefixKey,
+		envmanager.RedisPoolSizeKey,
+		envmanager.RedisMinIdleConnsKey,
+		envmanager.ElasticsearchAddressesKey,
+		envmanager.ElasticsearchUsernameKey,
+		envmanager.ElasticsearchPasswordKey,
+		envmanager.ElasticsearchAPIKeyKey,
+		envmanager.ElasticsearchCAFingerprintKey,
+		envmanager.ElasticsearchCACertFileKey,
+		envmanager.ElasticsearchIndexPrefixKey,
+		envmanager.ElasticsearchRequestTimeoutKey,
+		envmanager.ElasticsearchDialTimeoutKey,
+		envmanager.ElasticsearchVersionKey,
+		envmanager.StackVersionKey,
+		envmanager.KafkaBrokersKey,
+		envmanager.KafkaClientIDKey,
+		envmanager.KafkaSASLMechanismKey,
+		envmanager.KafkaSASLUsernameKey,
+		envmanager.KafkaSASLPasswordKey,
+		envmanager.KafkaTLSEnabledKey,
+		envmanager.KafkaTLSCAFileKey,
+		envmanager.KafkaTLSCertFileKey,
+		envmanager.KafkaTLSKeyFileKey,
+		envmanager.KafkaTLSInsecureSkipVerifyKey,
+		envmanager.KafkaConsumerGroupKey,
+		envmanager.KafkaTopicPrefixKey,
+		envmanager.KafkaTopicSeparatorKey,
+		envmanager.LogLevelKey,
+		envmanager.LogFormatKey,
+		envmanager.LogOutputKey,
+		envmanager.CorsAllowedOriginsKey,
+		envmanager.CorsAllowedMethodsKey,
+		envmanager.CorsAllowedHeadersKey,
+		envmanager.CorsAllowCredentialsKey,
+		envmanager.CorsMaxAgeKey,
+		envmanager.SecurityHSTSMaxAgeKey,
+		envmanager.SecurityHSTSIncludeSubdomainsKey,
+		envmanager.SecurityCSPKey,
+		envmanager.SecurityFrameOptionsKey,
+		envmanager.ServerHostKey,
+		envmanager.ServerPortKey,
+		envmanager.ServerReadTimeoutKey,
+		envmanager.ServerReadHeaderTimeoutKey,
+		envmanager.ServerWriteTimeoutKey,
+		envmanager.ServerIdleTimeoutKey,
+		envmanager.ServerMaxHeaderBytesKey,
+		envmanager.ServerShutdownTimeoutKey,
+		envmanager.ServerTrustedProxiesKey,
+		envmanager.ServerTLSCertFileKey,
+		envmanager.ServerTLSKeyFileKey,
 		// other global environment variables
 	}
 
@@ -45,6 +158,82 @@ func TearDown() {
 
 }
 
+// SetupEnv clears the environment variables in EnvKey and sets envVariables for the duration of the test.
+func SetupEnv(t testing.TB, envVariables map[string]string) {
+	t.Helper()
+	TearDown()
+	t.Cleanup(TearDown)
+	for key, value := range envVariables {
+		t.Setenv(key, value)
+	}
+}
+
+// NewSection sets envVariables like SetupEnv and creates a section from a new EnvManager, e.g.
+// NewSection(t, kafkaconfig.NewKafkaConfig, envVariables). The error is returned as a plain error, so a failed
+// constructor can be checked with assert.Error.
+func NewSection[T any](t testing.TB, newSection func(*envmanager.EnvManager) (T, diabuddyErrors.ApiErrors), envVariables map[string]string) (T, error) {
+	t.Helper()
+	return NewSectionWithOptions(t, func(envManager *envmanager.EnvManager, _ ...struct{}) (T, diabuddyErrors.ApiErrors) {
+		return newSection(envManager)
+	}, envVariables)
+}
+
+// NewSectionWithOptions is NewSection for constructors that take options, e.g.
+// NewSectionWithOptions(t, logconfig.NewLogConfig, envVariables, logconfig.WithWriter(&buffer)).
+func NewSectionWithOptions[T, O any](t testing.TB, newSection func(*envmanager.EnvManager, ...O) (T, diabuddyErrors.ApiErrors), envVariables map[string]string, options ...O) (T, error) {
+	t.Helper()
+	SetupEnv(t, envVariables)
+
+	envManager, err := envmanager.NewEnvManager()
+	if err != nil {
+		t.Fatalf("failed to create env manager: %v", err)
+	}
+	section, apiErr := newSection(envManager, options...)
+	if apiErr != nil {
+		var zero T
+		return zero, apiErr
+	}
+	return section, nil
+}
+
+// WriteCertificate writes a self-signed CA certificate for commonName and its private key as PEM files into a
+// temporary directory and returns their paths. The certificate is valid for an hour on either side of now.
+func WriteCertificate(t testing.TB, commonName string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, commonName+".crt")
+	keyFile = filepath.Join(dir, commonName+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
 // SetEnvVars Helper function to set environment variables
 func SetEnvVars(vars map[string]string) {
 	for key, value := range vars {
@@ -0,0 +1,134 @@
+package apiconfig_test
+
+import (
+	apiconfig "github.com/hbttundar/diabuddy-api-config/config/apiconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/appconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/authconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"github.com/hbttundar/diabuddy-api-config/config/storageconfig"
+	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	authSecret = "an-auth-secret-of-at-least-32-bytes"
+	appKey     = "base64:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+)
+
+func TestProfiles(t *testing.T) {
+	assert.Subset(t, apiconfig.Profiles(), []string{apiconfig.AuthApiProfile, apiconfig.FoodApiProfile, apiconfig.UserApiProfile})
+
+	profile, ok := apiconfig.LookupProfile(apiconfig.FoodApiProfile)
+	assert.True(t, ok)
+	assert.Contains(t, profile.Sections, envmanager.ElasticsearchSection)
+
+	profile.Sections[0] = "changed"
+	again, _ := apiconfig.LookupProfile(apiconfig.FoodApiProfile)
+	assert.NotEqual(t, "changed", again.Sections[0], "expected LookupProfile to return a copy")
+}
+
+func TestWithProfile_AuthApi(t *testing.T) {
+	testmain.SetupEnv(t, map[string]string{envmanager.AuthSecretKey: authSecret, envmanager.AppEncryptionKey: appKey})
+
+	apiConfig, err := apiconfig.NewApiConfig(apiconfig.WithProfile(apiconfig.AuthApiProfile))
+	assert.NoError(t, err, "expected no error while creating ApiConfig")
+	assert.Equal(t, []string{"app", "db", "server", "log", "security", "auth", "cache", "observability"}, apiConfig.Sections())
+
+	profile, ok := apiConfig.Profile()
+	assert.True(t, ok)
+	assert.Equal(t, apiconfig.AuthApiProfile, profile.Name)
+
+	authConfig, sectionErr := apiconfig.SectionAs[*authconfig.AuthConfig](apiConfig, "auth")
+	assert.Nil(t, sectionErr)
+	assert.NotNil(t, authConfig)
+}
+
+func TestWithProfile_RequiredKeys(t *testing.T) {
+	testmain.SetupEnv(t, nil)
+
+	for _, name := range []string{apiconfig.UserApiProfile, apiconfig.FoodApiProfile} {
+		_, err := apiconfig.NewApiConfig(apiconfig.WithProfile(name))
+		assert.NoError(t, err, "expected %s to validate without AUTH_SECRET and APP_KEY", name)
+	}
+
+	apiConfig, err := apiconfig.NewApiConfig(apiconfig.WithProfile(apiconfig.AuthApiProfile), apiconfig.WithValidationPolicy(apiconfig.ValidateNone))
+	assert.NoError(t, err, "expected no error while creating ApiConfig")
+
+	validationErr := apiConfig.Validate()
+	assert.Error(t, validationErr)
+	assert.Contains(t, validationErr.Error(), "profile auth_api: Error 500: missing required key(s): AUTH_SECRET, APP_KEY")
+
+	_, err = apiconfig.NewApiConfig(apiconfig.WithProfile(apiconfig.AuthApiProfile))
+	assert.Error(t, err, "expected ValidateAll to fail on the missing profile keys")
+}
+
+func TestWithProfile_SectionOverride(t *testing.T) {
+	testmain.SetupEnv(t, nil)
+
+	elasticsearch := &stubSection{}
+	apiConfig, err := apiconfig.NewApiConfig(apiconfig.WithProfile(apiconfig.FoodApiProfile), apiconfig.WithSection("elasticsearch", elasticsearch))
+	assert.NoError(t, err, "expected no error while creating ApiConfig")
+	assert.Equal(t, []string{"app", "db", "server", "log", "security", "observability", "elasticsearch"}, apiConfig.Sections())
+
+	section, _ := apiConfig.Section("elasticsearch")
+	assert.Same(t, elasticsearch, section, "expected the given section to replace the one of the profile")
+}
+
+func TestWithProfile_CustomProfile(t *testing.T) {
+	testmain.SetupEnv(t, nil)
+	t.Setenv("REMINDER_QUEUE", "reminders")
+
+	assert.NoError(t, apiconfig.RegisterProfile(apiconfig.Profile{
+		Name:         "reminder_api",
+		Sections:     []string{envmanager.LogSection},
+		RequiredKeys: []string{"REMINDER_QUEUE"},
+		Defaults:     map[string]string{envmanager.LogLevelKey: "debug"},
+	}))
+
+	apiConfig, err := apiconfig.NewApiConfig(apiconfig.WithProfile("reminder_api"), apiconfig.WithEnvOptions(envmanager.WithStrictMode(envmanager.StrictPanic)))
+	assert.NoError(t, err, "expected no error while creating ApiConfig")
+	assert.Equal(t, []string{"app", "db", "log"}, apiConfig.Sections())
+	assert.Equal(t, "debug", apiConfig.EnvManager().Get(envmanager.LogLevelKey), "expected the profile default to apply")
+	assert.True(t, apiConfig.EnvManager().IsDeclared("REMINDER_QUEUE"), "expected the required keys to be declared")
+
+	apiConfig, err = apiconfig.NewApiConfig(
+		apiconfig.WithProfile("reminder_api"),
+		apiconfig.WithEnvOptions(envmanager.WithExtendedDefaults(func(defaults map[string]string) { defaults[envmanager.LogLevelKey] = "warn" })),
+	)
+	assert.NoError(t, err, "expected no error while creating ApiConfig")
+	assert.Equal(t, "warn", apiConfig.EnvManager().Get(envmanager.LogLevelKey), "expected env options to win over the profile defaults")
+}
+
+func TestWithProfile_StorageUsesApp(t *testing.T) {
+	testmain.SetupEnv(t, nil)
+	basePath := t.TempDir()
+	t.Setenv(envmanager.AppBasePathKey, basePath)
+
+	assert.NoError(t, apiconfig.RegisterProfile(apiconfig.Profile{Name: "media_api", Sections: []string{envmanager.StorageSection}}))
+	apiConfig, err := apiconfig.NewApiConfig(
+		apiconfig.WithProfile("media_api"),
+		apiconfig.WithAppOptions(appconfig.WithEnsurePaths(true)),
+		apiconfig.WithValidationPolicy(apiconfig.ValidateNone),
+	)
+	assert.NoError(t, err, "expected no error while creating ApiConfig")
+
+	storage, sectionErr := apiconfig.SectionAs[*storageconfig.StorageConfig](apiConfig, "storage")
+	assert.Nil(t, sectionErr)
+	assert.Equal(t, filepath.Join(basePath, "storage", "app", "local"), storage.DefaultDisk().Root)
+	_, statErr := os.Stat(filepath.Join(basePath, "storage"))
+	assert.NoError(t, statErr, "expected the storage path to be created through the AppConfig of ApiConfig")
+}
+
+func TestWithProfile_Errors(t *testing.T) {
+	testmain.SetupEnv(t, nil)
+
+	_, err := apiconfig.NewApiConfig(apiconfig.WithProfile("billing_api"))
+	assert.Error(t, err, "expected an unknown profile to be rejected")
+	assert.Contains(t, err.Error(), "auth_api, food_api")
+
+	assert.Error(t, apiconfig.RegisterProfile(apiconfig.Profile{}), "expected a profile without a name to be rejected")
+	assert.Error(t, apiconfig.RegisterProfile(apiconfig.Profile{Name: "billing_api", Sections: []string{"payments"}}), "expected an unknown section to be rejected")
+}
@@ -1,11 +1,15 @@
 package appconfig_test
 
 import (
+	"encoding/base64"
+	"github.com/hbttundar/diabuddy-api-config/config"
 	appconfig "github.com/hbttundar/diabuddy-api-config/config/appconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/appconfig/encrypter"
 	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
 	testmain "github.com/hbttundar/diabuddy-api-config/test"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -100,6 +104,7 @@ func TestAppConfig_Validate(t *testing.T) {
 			"APP_ENV":   "production",
 			"APP_URL":   "http://localhost",
 			"APP_DEBUG": "true",
+			"APP_KEY":   "base64:" + base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")),
 		}
 
 		// Set the environment variables for the test
@@ -153,3 +158,254 @@ func TestAppConfig_Validate(t *testing.T) {
 		}
 	})
 }
+
+func TestAppConfig_Introspection(t *testing.T) {
+	testmain.EnvVars[envmanager.AppNameKey] = "Diabuddy"
+	testmain.EnvVars[envmanager.AppEncryptionKey] = "secret-key"
+	testmain.Setup()
+	defer testmain.TearDown()
+
+	envManager, err := envmanager.NewEnvManager()
+	assert.NoError(t, err, "Expected no error during env manager initialization")
+	appConfig, err := appconfig.NewAppConfig(envManager)
+	assert.NoError(t, err, "Expected no error during appconfig initialization")
+
+	var section config.Config = appConfig
+	introspectable, ok := section.(config.Introspectable)
+	assert.True(t, ok, "Expected AppConfig to implement every capability interface")
+
+	assert.Contains(t, introspectable.Keys(), envmanager.AppNameKey, "Expected APP_NAME to be owned by the app section")
+	assert.NotContains(t, introspectable.Keys(), envmanager.DbHostKey, "Expected DB_HOST not to be owned by the app section")
+	assert.ElementsMatch(t, []string{envmanager.AppNameKey, envmanager.AppEnvKey, envmanager.AppUrlKey, envmanager.AppDebugKey}, introspectable.RequiredKeys())
+	assert.Len(t, introspectable.Describe(), len(introspectable.Keys()), "Expected a description for every key")
+
+	value, found := introspectable.Lookup(envmanager.AppNameKey)
+	assert.True(t, found, "Expected APP_NAME to be found")
+	assert.Equal(t, "Diabuddy", value)
+	_, found = introspectable.Lookup("NON_EXISTENT_KEY")
+	assert.False(t, found, "Expected an unknown key not to be found")
+
+	snapshot := introspectable.Snapshot()
+	assert.Equal(t, "Diabuddy", snapshot[envmanager.AppNameKey], "Expected APP_NAME in the snapshot")
+	assert.Equal(t, config.RedactedValue, snapshot[envmanager.AppEncryptionKey], "Expected APP_KEY to be redacted")
+}
+
+func TestAppConfig_TypedAccessors(t *testing.T) {
+	t.Run("Typed values are parsed at construction", func(t *testing.T) {
+		envVariables := map[string]string{
+			"APP_NAME":            "Diabuddy",
+			"APP_ENV":             "production",
+			"APP_DEBUG":           "true",
+			"APP_URL":             "https://diabuddy.example:8443/api",
+			"APP_TIMEZONE":        "Europe/Berlin",
+			"APP_LOCALE":          "de",
+			"APP_FALLBACK_LOCALE": "en",
+			"APP_CIPHER":          "AES-256-GCM",
+		}
+		for key, value := range envVariables {
+			testmain.EnvVars[key] = value
+		}
+		testmain.Setup()
+		defer testmain.TearDown()
+
+		envManager, err := envmanager.NewEnvManager()
+		assert.NoError(t, err, "Expected no error during env manager initialization")
+		appConfig, err := appconfig.NewAppConfig(envManager)
+		assert.NoError(t, err, "Expected no error during appconfig initialization")
+
+		assert.Equal(t, "Diabuddy", appConfig.Name())
+		assert.Equal(t, envmanager.Production, appConfig.Env())
+		assert.True(t, appConfig.Debug(), "Expected APP_DEBUG to be parsed as true")
+		assert.Equal(t, "diabuddy.example:8443", appConfig.URL().Host)
+		assert.Equal(t, "/api", appConfig.URL().Path)
+		assert.Equal(t, "Europe/Berlin", appConfig.Location().String())
+		assert.Equal(t, "de", appConfig.Locale())
+		assert.Equal(t, "en", appConfig.FallbackLocale())
+		assert.Equal(t, "AES-256-GCM", appConfig.Cipher())
+
+		appConfig.URL().Host = "mutated"
+		assert.Equal(t, "diabuddy.example:8443", appConfig.URL().Host, "Expected URL() to return a copy")
+	})
+
+	tests := []struct {
+		name           string
+		envVariables   map[string]string
+		expectedErrMsg string
+	}{
+		{
+			name:           "Invalid APP_DEBUG",
+			envVariables:   map[string]string{"APP_DEBUG": "sometimes"},
+			expectedErrMsg: `APP_DEBUG must be a boolean, got "sometimes"`,
+		},
+		{
+			name:           "Invalid APP_URL",
+			envVariables:   map[string]string{"APP_URL": "http://[::1"},
+			expectedErrMsg: "APP_URL must be a URL",
+		},
+		{
+			name:           "Invalid APP_TIMEZONE",
+			envVariables:   map[string]string{"APP_TIMEZONE": "Mars/Olympus_Mons"},
+			expectedErrMsg: "APP_TIMEZONE must be an IANA time zone",
+		},
+		{
+			name:           "Invalid APP_CIPHER",
+			envVariables:   map[string]string{"APP_CIPHER": "DES"},
+			expectedErrMsg: `APP_CIPHER must be AES-256-CBC or AES-256-GCM, got "DES"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envVariables {
+				testmain.EnvVars[key] = value
+			}
+			testmain.Setup()
+			defer testmain.TearDown()
+
+			envManager, err := envmanager.NewEnvManager()
+			assert.NoError(t, err, "Expected no error during env manager initialization")
+			_, err = appconfig.NewAppConfig(envManager)
+			assert.Error(t, err, "Expected a parse error")
+			if err != nil {
+				assert.Contains(t, err.Error(), tt.expectedErrMsg)
+			}
+		})
+	}
+}
+
+func TestAppConfig_Encrypter(t *testing.T) {
+	t.Run("Encrypter uses APP_KEY and APP_CIPHER", func(t *testing.T) {
+		testmain.EnvVars["APP_KEY"] = "base64:" + base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+		testmain.EnvVars["APP_CIPHER"] = "AES-256-GCM"
+		testmain.Setup()
+		defer testmain.TearDown()
+
+		envManager, err := envmanager.NewEnvManager()
+		assert.NoError(t, err, "Expected no error during env manager initialization")
+		appConfig, err := appconfig.NewAppConfig(envManager)
+		assert.NoError(t, err, "Expected no error during appconfig initialization")
+
+		e, err := appConfig.Encrypter()
+		assert.NoError(t, err, "Expected a valid APP_KEY to produce an encrypter")
+		assert.Equal(t, encrypter.AES256GCM, e.Cipher())
+		same, _ := appConfig.Encrypter()
+		assert.Same(t, e, same, "Expected the encrypter to be created once")
+
+		payload, err := e.EncryptString("diabuddy")
+		assert.NoError(t, err)
+		plaintext, err := e.DecryptString(payload)
+		assert.NoError(t, err)
+		assert.Equal(t, "diabuddy", plaintext)
+	})
+
+	t.Run("Encrypter requires a valid APP_KEY", func(t *testing.T) {
+		for key, expectedErrMsg := range map[string]string{
+			"":          "APP_KEY is required",
+			"too-short": "APP_KEY must hold 32 byte keys for AES-256-CBC",
+		} {
+			testmain.EnvVars["APP_KEY"] = key
+			testmain.Setup()
+
+			envManager, err := envmanager.NewEnvManager()
+			assert.NoError(t, err, "Expected no error during env manager initialization")
+			appConfig, err := appconfig.NewAppConfig(envManager)
+			assert.NoError(t, err, "Expected APP_KEY not to be needed at construction")
+
+			_, err = appConfig.Encrypter()
+			assert.Error(t, err)
+			if err != nil {
+				assert.Contains(t, err.Error(), expectedErrMsg)
+			}
+			testmain.TearDown()
+		}
+	})
+}
+
+func TestAppConfig_KeyManagement(t *testing.T) {
+	t.Run("Validate requires APP_KEY outside local and test", func(t *testing.T) {
+		for environment, expectError := range map[string]bool{"local": false, "test": false, "staging": true, "production": true} {
+			testmain.EnvVars["APP_KEY"] = ""
+			testmain.EnvVars["APP_ENV"] = environment
+			testmain.Setup()
+
+			envManager, err := envmanager.NewEnvManager()
+			assert.NoError(t, err, "Expected no error during env manager initialization")
+			appConfig, err := appconfig.NewAppConfig(envManager)
+			assert.NoError(t, err, "Expected no error during appconfig initialization")
+			err = appConfig.Validate()
+			if expectError {
+				assert.ErrorContains(t, err, "APP_KEY is required", "Expected an empty APP_KEY to be rejected in %s", environment)
+			} else {
+				assert.NoError(t, err, "Expected APP_KEY to be checked only by Encrypter in %s", environment)
+			}
+			testmain.TearDown()
+		}
+	})
+
+	t.Run("Validate rejects an invalid APP_KEY", func(t *testing.T) {
+		for key, expectedErrMsg := range map[string]string{
+			"base64:%%%":      "APP_KEY must hold 32 byte keys",
+			"base64:c2hvcnQ=": "APP_KEY must hold 32 byte keys",
+		} {
+			testmain.EnvVars["APP_KEY"] = key
+			testmain.Setup()
+
+			envManager, err := envmanager.NewEnvManager()
+			assert.NoError(t, err, "Expected no error during env manager initialization")
+			appConfig, err := appconfig.NewAppConfig(envManager)
+			assert.NoError(t, err, "Expected no error during appconfig initialization")
+
+			err = appConfig.Validate()
+			assert.Error(t, err, "Expected APP_KEY %q to be rejected", key)
+			if err != nil {
+				assert.Contains(t, err.Error(), expectedErrMsg)
+			}
+			testmain.TearDown()
+		}
+	})
+
+	t.Run("Validate rejects invalid APP_PREVIOUS_KEYS", func(t *testing.T) {
+		testmain.EnvVars["APP_PREVIOUS_KEYS"] = "base64:" + base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")) + ", short"
+		testmain.EnvVars["APP_KEY"] = "base64:" + base64.StdEncoding.EncodeToString([]byte("abcdef0123456789abcdef0123456789"))
+		testmain.Setup()
+		defer testmain.TearDown()
+
+		envManager, err := envmanager.NewEnvManager()
+		assert.NoError(t, err, "Expected no error during env manager initialization")
+		appConfig, err := appconfig.NewAppConfig(envManager)
+		assert.NoError(t, err, "Expected no error during appconfig initialization")
+
+		err = appConfig.Validate()
+		assert.Error(t, err)
+		if err != nil {
+			assert.Contains(t, err.Error(), "APP_PREVIOUS_KEYS must hold 32 byte keys")
+		}
+	})
+
+	t.Run("Generated keys are written to the env file and rotation keeps old payloads readable", func(t *testing.T) {
+		testmain.EnvVars["APP_KEY"] = "base64:" + base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+		testmain.Setup()
+		defer testmain.TearDown()
+
+		envManager, err := envmanager.NewEnvManager()
+		assert.NoError(t, err, "Expected no error during env manager initialization")
+		appConfig, err := appconfig.NewAppConfig(envManager)
+		assert.NoError(t, err, "Expected no error during appconfig initialization")
+		oldEncrypter, err := appConfig.Encrypter()
+		assert.NoError(t, err)
+		payload, _ := oldEncrypter.EncryptString("diabuddy")
+
+		envFile := filepath.Join(t.TempDir(), ".env")
+		assert.NoError(t, os.WriteFile(envFile, []byte("# App variables\nAPP_KEY=\nAPP_NAME=diabuddy\n"), 0600))
+		key, err := appConfig.GenerateKey(envFile)
+		assert.NoError(t, err)
+		content, _ := os.ReadFile(envFile)
+		assert.Equal(t, "# App variables\nAPP_KEY="+key+"\nAPP_NAME=diabuddy\n", string(content))
+
+		rotated, err := encrypter.NewFromString(key, encrypter.Cipher(appConfig.Cipher()), encrypter.WithPreviousKeys(appConfig.Get("APP_KEY")))
+		assert.NoError(t, err)
+		plaintext, err := rotated.DecryptString(payload)
+		assert.NoError(t, err, "Expected the previous key to decrypt old payloads")
+		assert.Equal(t, "diabuddy", plaintext)
+	})
+}
@@ -0,0 +1,125 @@
+package encrypter_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/hbttundar/diabuddy-api-config/config/appconfig/encrypter"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var testKey = bytes.Repeat([]byte{0x42}, encrypter.KeySize)
+
+func TestEncrypter_RoundTrip(t *testing.T) {
+	for _, cipher := range encrypter.Ciphers() {
+		t.Run(string(cipher), func(t *testing.T) {
+			e, err := encrypter.New(testKey, cipher)
+			assert.NoError(t, err, "expected no error while creating the encrypter")
+
+			payload, err := e.Encrypt([]byte("diabuddy"))
+			assert.NoError(t, err)
+			assert.Equal(t, encrypter.PayloadVersion, payload[0], "expected the payload to start with the version")
+
+			plaintext, err := e.Decrypt(payload)
+			assert.NoError(t, err)
+			assert.Equal(t, "diabuddy", string(plaintext))
+
+			again, _ := e.Encrypt([]byte("diabuddy"))
+			assert.NotEqual(t, payload, again, "expected a fresh IV or nonce per payload")
+
+			encoded, err := e.EncryptString("")
+			assert.NoError(t, err)
+			decoded, err := e.DecryptString(encoded)
+			assert.NoError(t, err)
+			assert.Equal(t, "", decoded, "expected empty strings to round trip")
+		})
+	}
+}
+
+func TestEncrypter_DecryptsEitherCipher(t *testing.T) {
+	cbc, _ := encrypter.New(testKey, encrypter.AES256CBC)
+	gcm, _ := encrypter.New(testKey, encrypter.AES256GCM)
+
+	payload, err := cbc.EncryptString("secret")
+	assert.NoError(t, err)
+	plaintext, err := gcm.DecryptString(payload)
+	assert.NoError(t, err, "expected the cipher id in the payload to select the cipher")
+	assert.Equal(t, "secret", plaintext)
+}
+
+func TestEncrypter_RejectsTamperedPayloads(t *testing.T) {
+	for _, cipher := range encrypter.Ciphers() {
+		t.Run(string(cipher), func(t *testing.T) {
+			e, _ := encrypter.New(testKey, cipher)
+			payload, _ := e.Encrypt([]byte("diabuddy"))
+
+			tampered := append([]byte(nil), payload...)
+			tampered[len(tampered)/2] ^= 0x01
+			_, err := e.Decrypt(tampered)
+			assert.Error(t, err, "expected a modified payload to be rejected")
+
+			other, _ := encrypter.New(bytes.Repeat([]byte{0x24}, encrypter.KeySize), cipher)
+			_, err = other.Decrypt(payload)
+			assert.Error(t, err, "expected a payload for another key to be rejected")
+
+			_, err = e.Decrypt([]byte{0x09, 0x01})
+			assert.Error(t, err, "expected an unknown version to be rejected")
+
+			_, err = e.DecryptString("not base64!")
+			assert.Error(t, err, "expected invalid base64 to be rejected")
+		})
+	}
+}
+
+func TestEncrypter_Keys(t *testing.T) {
+	e, err := encrypter.NewFromString(encrypter.KeyPrefix+base64.StdEncoding.EncodeToString(testKey), encrypter.AES256GCM)
+	assert.NoError(t, err, "expected base64: keys to be decoded")
+	assert.Equal(t, testKey, e.Key())
+
+	_, err = encrypter.NewFromString(string(testKey), encrypter.AES256CBC)
+	assert.NoError(t, err, "expected raw 32 byte keys to be accepted")
+
+	_, err = encrypter.NewFromString("base64:%%%", encrypter.AES256CBC)
+	assert.Error(t, err, "expected invalid base64 keys to be rejected")
+
+	_, err = encrypter.NewFromString("too-short", encrypter.AES256CBC)
+	assert.Error(t, err, "expected short keys to be rejected")
+
+	_, err = encrypter.New(testKey, encrypter.Cipher("DES"))
+	assert.Error(t, err, "expected unsupported ciphers to be rejected")
+}
+
+func TestEncrypter_PreviousKeys(t *testing.T) {
+	oldKey := encrypter.KeyPrefix + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x01}, encrypter.KeySize))
+	old, _ := encrypter.NewFromString(oldKey, encrypter.AES256CBC)
+	payload, err := old.EncryptString("before rotation")
+	assert.NoError(t, err)
+
+	rotated, err := encrypter.New(testKey, encrypter.AES256CBC, encrypter.WithPreviousKeys(oldKey))
+	assert.NoError(t, err, "expected valid previous keys to be accepted")
+	plaintext, err := rotated.DecryptString(payload)
+	assert.NoError(t, err, "expected previous keys to decrypt old payloads")
+	assert.Equal(t, "before rotation", plaintext)
+
+	fresh, _ := rotated.EncryptString("after rotation")
+	_, err = old.DecryptString(fresh)
+	assert.Error(t, err, "expected new payloads to use the current key")
+
+	_, err = encrypter.New(testKey, encrypter.AES256CBC, encrypter.WithPreviousKeys("short"))
+	assert.Error(t, err, "expected invalid previous keys to be rejected")
+}
+
+func TestGenerateKey(t *testing.T) {
+	for _, cipher := range encrypter.Ciphers() {
+		key, err := encrypter.GenerateKey(cipher)
+		assert.NoError(t, err)
+		assert.NoError(t, encrypter.ValidateKey(key, cipher), "expected generated keys to be valid")
+
+		other, _ := encrypter.GenerateKey(cipher)
+		assert.NotEqual(t, key, other, "expected random keys")
+	}
+
+	_, err := encrypter.GenerateKey(encrypter.Cipher("DES"))
+	assert.Error(t, err, "expected unsupported ciphers to be rejected")
+	assert.Error(t, encrypter.ValidateKey("base64:"+base64.StdEncoding.EncodeToString([]byte("16-byte-key-here")), encrypter.AES256GCM))
+}
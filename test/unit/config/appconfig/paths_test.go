@@ -0,0 +1,86 @@
+package appconfig_test
+
+import (
+	appconfig "github.com/hbttundar/diabuddy-api-config/config/appconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newPathTestAppConfig(t *testing.T, options ...appconfig.AppOption) (*appconfig.AppConfig, error) {
+	t.Helper()
+	envManager, err := envmanager.NewEnvManager()
+	assert.NoError(t, err, "expected no error while creating env manager")
+	appConfig, apiErr := appconfig.NewAppConfig(envManager, options...)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	return appConfig, nil
+}
+
+func TestAppConfig_PathsFromModuleRoot(t *testing.T) {
+	t.Setenv(envmanager.AppBasePathKey, "")
+
+	appConfig, err := newPathTestAppConfig(t)
+	assert.NoError(t, err, "expected no error while creating AppConfig")
+
+	basePath, err := appConfig.BasePath()
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(basePath, "go.mod"), "expected the module root as base path")
+
+	workingDir, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(workingDir) })
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	samePath, err := appConfig.BasePath()
+	assert.NoError(t, err)
+	assert.Equal(t, basePath, samePath, "expected the base path not to depend on the working directory")
+}
+
+func TestAppConfig_PathsFromOverride(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(envmanager.AppBasePathKey, root)
+
+	appConfig, err := newPathTestAppConfig(t)
+	assert.NoError(t, err, "expected no error while creating AppConfig")
+
+	paths := map[string]func(...string) (string, diabuddyErrors.ApiErrors){
+		filepath.Join(root, "app.yaml"):                   appConfig.BasePath,
+		filepath.Join(root, "config", "app.yaml"):         appConfig.ConfigPath,
+		filepath.Join(root, "storage", "app.yaml"):        appConfig.StoragePath,
+		filepath.Join(root, "resources", "app.yaml"):      appConfig.ResourcePath,
+		filepath.Join(root, "storage", "tmp", "app.yaml"): appConfig.TempPath,
+	}
+	for expected, path := range paths {
+		actual, err := path("app.yaml")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+	assert.NoDirExists(t, filepath.Join(root, "storage"), "expected no directories to be created by default")
+}
+
+func TestAppConfig_EnsurePaths(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(envmanager.AppBasePathKey, root)
+
+	appConfig, err := newPathTestAppConfig(t, appconfig.WithEnsurePaths(true))
+	assert.NoError(t, err, "expected no error while creating AppConfig")
+
+	tempPath, err := appConfig.TempPath("upload.bin")
+	assert.NoError(t, err)
+	assert.DirExists(t, filepath.Dir(tempPath), "expected the tmp directory to be created")
+	assert.NoFileExists(t, tempPath, "expected only directories to be created")
+
+	entries, _ := os.ReadDir(filepath.Dir(tempPath))
+	assert.Empty(t, entries, "expected the writability check to clean up after itself")
+}
+
+func TestAppConfig_InvalidBasePathOverride(t *testing.T) {
+	t.Setenv(envmanager.AppBasePathKey, filepath.Join(t.TempDir(), "missing"))
+
+	_, err := newPathTestAppConfig(t)
+	assert.Error(t, err, "expected a missing APP_BASE_PATH to be rejected")
+	assert.Contains(t, err.Error(), "APP_BASE_PATH must be an existing directory")
+}
@@ -0,0 +1,179 @@
+package authconfig_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/authconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = "base64:" + base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), strings.ReplaceAll(strings.ToLower(blockType), " ", "_")+".pem")
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600))
+	return path
+}
+
+func TestAuthConfig_HS256(t *testing.T) {
+	authConfig, err := testmain.NewSection(t, authconfig.NewAuthConfig, map[string]string{
+		"AUTH_SECRET":   testSecret,
+		"AUTH_ISSUER":   "https://auth.diabuddy.example",
+		"AUTH_AUDIENCE": "user_api, food_api",
+	})
+	assert.NoError(t, err, "expected no error while creating AuthConfig")
+	assert.NoError(t, authConfig.Validate())
+
+	assert.Equal(t, authconfig.HS256, authConfig.Algorithm())
+	assert.Equal(t, "https://auth.diabuddy.example", authConfig.Issuer())
+	assert.Equal(t, []string{"user_api", "food_api"}, authConfig.Audience())
+	assert.Equal(t, 15*time.Minute, authConfig.AccessTokenTTL())
+	assert.Equal(t, 720*time.Hour, authConfig.RefreshTokenTTL())
+	assert.Equal(t, 30*time.Second, authConfig.ClockSkew())
+
+	key := authConfig.SigningKey()
+	assert.True(t, key.CanSign())
+	assert.Equal(t, []byte("0123456789abcdef0123456789abcdef"), key.Secret)
+	assert.NotEmpty(t, key.ID, "expected a derived key ID")
+
+	again, err := testmain.NewSection(t, authconfig.NewAuthConfig, map[string]string{"AUTH_SECRET": testSecret})
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, again.SigningKey().ID, "expected services with the same secret to derive the same key ID")
+}
+
+func TestAuthConfig_RequiresKeyMaterial(t *testing.T) {
+	authConfig, err := testmain.NewSection(t, authconfig.NewAuthConfig, nil)
+	assert.NoError(t, err, "expected a missing secret not to fail construction")
+	err = authConfig.Validate()
+	assert.Error(t, err)
+	if err != nil {
+		assert.Contains(t, err.Error(), "AUTH_SECRET is required for HS256")
+	}
+
+	authConfig, err = testmain.NewSection(t, authconfig.NewAuthConfig, map[string]string{"AUTH_ALGORITHM": "EdDSA"})
+	assert.NoError(t, err)
+	err = authConfig.Validate()
+	assert.Error(t, err)
+	if err != nil {
+		assert.Contains(t, err.Error(), "EdDSA requires a private or public key")
+	}
+}
+
+func TestAuthConfig_RS256(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	publicDER, _ := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	privateFile := writePEM(t, "PRIVATE KEY", privateDER)
+	publicFile := writePEM(t, "PUBLIC KEY", publicDER)
+
+	issuer, err := testmain.NewSection(t, authconfig.NewAuthConfig, map[string]string{"AUTH_ALGORITHM": "RS256", "AUTH_PRIVATE_KEY_FILE": privateFile, "AUTH_PUBLIC_KEY_FILE": publicFile})
+	assert.NoError(t, err, "expected no error while creating AuthConfig")
+	assert.NoError(t, issuer.Validate())
+	assert.True(t, issuer.SigningKey().CanSign())
+
+	inlinePEM := strings.ReplaceAll(strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))), "\n", `\n`)
+	verifier, err := testmain.NewSection(t, authconfig.NewAuthConfig, map[string]string{"AUTH_ALGORITHM": "RS256", "AUTH_PUBLIC_KEY": inlinePEM})
+	assert.NoError(t, err, "expected an escaped single line PEM to be accepted")
+	assert.NoError(t, verifier.Validate())
+	assert.False(t, verifier.SigningKey().CanSign(), "expected a verify-only key without private key")
+	assert.Equal(t, issuer.SigningKey().ID, verifier.SigningKey().ID, "expected issuer and verifier to derive the same key ID")
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherDER, _ := x509.MarshalPKIXPublicKey(&otherKey.PublicKey)
+	_, err = testmain.NewSection(t, authconfig.NewAuthConfig, map[string]string{"AUTH_ALGORITHM": "RS256", "AUTH_PRIVATE_KEY_FILE": privateFile, "AUTH_PUBLIC_KEY_FILE": writePEM(t, "PUBLIC KEY", otherDER)})
+	assert.Error(t, err, "expected a public key of another pair to be rejected")
+}
+
+func TestAuthConfig_KeyStrength(t *testing.T) {
+	weakRSA, _ := rsa.GenerateKey(rand.Reader, 1024)
+	weakDER, _ := x509.MarshalPKIXPublicKey(&weakRSA.PublicKey)
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+
+	tests := []struct {
+		name           string
+		envVariables   map[string]string
+		expectedErrMsg string
+	}{
+		{"Short secret", map[string]string{"AUTH_SECRET": "short"}, "AUTH_SECRET is invalid: secret must be at least 32 bytes, got 5"},
+		{"Weak RSA key", map[string]string{"AUTH_ALGORITHM": "RS256", "AUTH_PUBLIC_KEY_FILE": writePEM(t, "PUBLIC KEY", weakDER)}, "RSA key must be at least 2048 bits, got 1024"},
+		{"Wrong key type", map[string]string{"AUTH_ALGORITHM": "RS256", "AUTH_PRIVATE_KEY_FILE": writePEM(t, "PRIVATE KEY", edDER)}, "RS256 requires an RSA key"},
+		{"Unsupported algorithm", map[string]string{"AUTH_ALGORITHM": "none"}, `AUTH_ALGORITHM must be HS256, RS256 or EdDSA, got "none"`},
+		{"Inline and file key", map[string]string{"AUTH_ALGORITHM": "EdDSA", "AUTH_PRIVATE_KEY": "x", "AUTH_PRIVATE_KEY_FILE": "y"}, "set either AUTH_PRIVATE_KEY or AUTH_PRIVATE_KEY_FILE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testmain.NewSection(t, authconfig.NewAuthConfig, tt.envVariables)
+			assert.Error(t, err)
+			if err != nil {
+				assert.Contains(t, err.Error(), tt.expectedErrMsg)
+				assert.NotContains(t, err.Error(), "BEGIN", "expected key material not to leak into errors")
+			}
+		})
+	}
+}
+
+func TestAuthConfig_KeyRotation(t *testing.T) {
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+	oldPublic, _, _ := ed25519.GenerateKey(rand.Reader)
+	oldDER, _ := x509.MarshalPKIXPublicKey(oldPublic)
+
+	authConfig, err := testmain.NewSection(t, authconfig.NewAuthConfig, map[string]string{
+		"AUTH_ALGORITHM":        "EdDSA",
+		"AUTH_KEY_ID":           "2026-10",
+		"AUTH_PRIVATE_KEY_FILE": writePEM(t, "PRIVATE KEY", privateDER),
+		"AUTH_PREVIOUS_KEYS":    "2026-04=" + writePEM(t, "PUBLIC KEY", oldDER),
+	})
+	assert.NoError(t, err, "expected no error while creating AuthConfig")
+	assert.NoError(t, authConfig.Validate())
+
+	assert.Equal(t, "2026-10", authConfig.SigningKey().ID)
+	assert.Equal(t, edPublic, authConfig.SigningKey().PublicKey)
+	assert.Len(t, authConfig.VerificationKeys(), 2)
+	previous, ok := authConfig.VerificationKey("2026-04")
+	assert.True(t, ok, "expected the previous key to be found by its ID")
+	assert.False(t, previous.CanSign(), "expected previous keys to only verify")
+	assert.Equal(t, oldPublic, previous.PublicKey)
+
+	_, err = testmain.NewSection(t, authconfig.NewAuthConfig, map[string]string{"AUTH_SECRET": testSecret, "AUTH_KEY_ID": "k1", "AUTH_PREVIOUS_KEYS": "k1=" + testSecret})
+	assert.Error(t, err, "expected repeated key IDs to be rejected")
+	_, err = testmain.NewSection(t, authconfig.NewAuthConfig, map[string]string{"AUTH_SECRET": testSecret, "AUTH_PREVIOUS_KEYS": testSecret})
+	assert.Error(t, err, "expected entries without a key ID to be rejected")
+}
+
+func TestAuthConfig_Validate(t *testing.T) {
+	authConfig, err := testmain.NewSection(t, authconfig.NewAuthConfig, map[string]string{"AUTH_SECRET": testSecret, "AUTH_ACCESS_TOKEN_TTL": "2h", "AUTH_REFRESH_TOKEN_TTL": "1h"})
+	assert.NoError(t, err)
+	err = authConfig.Validate()
+	assert.Error(t, err)
+	if err != nil {
+		assert.Contains(t, err.Error(), "AUTH_REFRESH_TOKEN_TTL must not be shorter than AUTH_ACCESS_TOKEN_TTL")
+	}
+}
+
+func TestAuthConfig_Introspection(t *testing.T) {
+	authConfig, err := testmain.NewSection(t, authconfig.NewAuthConfig, map[string]string{"AUTH_SECRET": testSecret})
+	assert.NoError(t, err, "expected no error while creating AuthConfig")
+
+	var section config.Config = authConfig
+	introspectable, ok := section.(config.Introspectable)
+	assert.True(t, ok, "expected AuthConfig to implement every capability interface")
+	assert.Contains(t, introspectable.Keys(), envmanager.AuthSecretKey)
+	assert.Equal(t, config.RedactedValue, introspectable.Snapshot()[envmanager.AuthSecretKey], "expected the secret to be redacted")
+}
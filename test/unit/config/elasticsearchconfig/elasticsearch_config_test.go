@@ -0,0 +1,144 @@
+package elasticsearchconfig_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/elasticsearchconfig"
+	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const fingerprint = "A1:B2:C3:D4:E5:F6:07:18:29:3A:4B:5C:6D:7E:8F:90:A1:B2:C3:D4:E5:F6:07:18:29:3A:4B:5C:6D:7E:8F:90"
+
+func TestElasticsearchConfig_Defaults(t *testing.T) {
+	elasticsearchConfig, err := testmain.NewSection(t, elasticsearchconfig.NewElasticsearchConfig, map[string]string{
+		"APP_ENV":                 "staging",
+		"ELASTICSEARCH_ADDRESSES": "http://es-1:9200, https://es-2:9200",
+		"STACK_VERSION":           "8.14.1",
+	})
+	assert.NoError(t, err, "expected no error while creating ElasticsearchConfig")
+
+	assert.Equal(t, []string{"http://es-1:9200", "https://es-2:9200"}, elasticsearchConfig.Addresses())
+	assert.Equal(t, "staging", elasticsearchConfig.IndexPrefix(), "expected the index prefix to default to APP_ENV")
+	assert.Equal(t, "staging-glucose-readings", elasticsearchConfig.Index("glucose-readings"))
+	assert.Equal(t, 30*time.Second, elasticsearchConfig.RequestTimeout())
+	assert.Equal(t, 5*time.Second, elasticsearchConfig.DialTimeout())
+	assert.Equal(t, "8.14.1", elasticsearchConfig.ServerVersion().String(), "expected the server version to default to STACK_VERSION")
+	assert.False(t, elasticsearchConfig.UsesAPIKey())
+	assert.NoError(t, elasticsearchConfig.Validate())
+}
+
+func TestElasticsearchConfig_Settings(t *testing.T) {
+	elasticsearchConfig, err := testmain.NewSection(t, elasticsearchconfig.NewElasticsearchConfig, map[string]string{
+		"ELASTICSEARCH_ADDRESSES":       "https://es:9200",
+		"ELASTICSEARCH_USERNAME":        "elastic",
+		"ELASTICSEARCH_PASSWORD":        "changeme",
+		"ELASTICSEARCH_CA_FINGERPRINT":  fingerprint,
+		"ELASTICSEARCH_INDEX_PREFIX":    "diabuddy",
+		"ELASTICSEARCH_REQUEST_TIMEOUT": "10s",
+	})
+	assert.NoError(t, err, "expected no error while creating ElasticsearchConfig")
+	assert.NoError(t, elasticsearchConfig.Validate())
+
+	settings, err := elasticsearchConfig.Settings()
+	assert.NoError(t, err)
+	assert.Equal(t, "elastic", settings.Username)
+	assert.Equal(t, "changeme", settings.Password)
+	assert.Empty(t, settings.APIKey)
+	assert.Equal(t, "a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90", settings.CertificateFingerprint, "expected the fingerprint without colons")
+	assert.Nil(t, settings.CACert)
+	assert.Equal(t, 10*time.Second, settings.RequestTimeout)
+	assert.Equal(t, "diabuddy-users", settings.Index("users"))
+}
+
+func TestElasticsearchConfig_APIKeyAndCACert(t *testing.T) {
+	caFile, _ := testmain.WriteCertificate(t, "elasticsearch-ca")
+	elasticsearchConfig, err := testmain.NewSection(t, elasticsearchconfig.NewElasticsearchConfig, map[string]string{
+		"ELASTICSEARCH_ADDRESSES":    "https://es:9200",
+		"ELASTICSEARCH_API_KEY":      "ZGlhYnVkZHk6c2VjcmV0",
+		"ELASTICSEARCH_CA_CERT_FILE": caFile,
+	})
+	assert.NoError(t, err, "expected no error while creating ElasticsearchConfig")
+	assert.NoError(t, elasticsearchConfig.Validate())
+	assert.True(t, elasticsearchConfig.UsesAPIKey())
+
+	settings, err := elasticsearchConfig.Settings()
+	assert.NoError(t, err)
+	assert.Equal(t, "ZGlhYnVkZHk6c2VjcmV0", settings.APIKey)
+	assert.Contains(t, string(settings.CACert), "BEGIN CERTIFICATE")
+	assert.Equal(t, config.RedactedValue, elasticsearchConfig.Snapshot()["ELASTICSEARCH_API_KEY"])
+}
+
+func TestElasticsearchConfig_InvalidValues(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"address without scheme", map[string]string{"ELASTICSEARCH_ADDRESSES": "es:9200"}},
+		{"address with other scheme", map[string]string{"ELASTICSEARCH_ADDRESSES": "tcp://es:9200"}},
+		{"fingerprint", map[string]string{"ELASTICSEARCH_CA_FINGERPRINT": "A1:B2"}},
+		{"index prefix", map[string]string{"ELASTICSEARCH_INDEX_PREFIX": "Diabuddy"}},
+		{"request timeout", map[string]string{"ELASTICSEARCH_REQUEST_TIMEOUT": "soon"}},
+		{"dial timeout", map[string]string{"ELASTICSEARCH_DIAL_TIMEOUT": "-1s"}},
+		{"server version", map[string]string{"ELASTICSEARCH_VERSION": "eight"}},
+		{"stack version", map[string]string{"STACK_VERSION": "latest"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testmain.NewSection(t, elasticsearchconfig.NewElasticsearchConfig, tt.env)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestElasticsearchConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		contains string
+	}{
+		{"missing addresses", map[string]string{"ELASTICSEARCH_ADDRESSES": ""}, "ELASTICSEARCH_ADDRESSES"},
+		{"username without password", map[string]string{"ELASTICSEARCH_USERNAME": "elastic"}, "ELASTICSEARCH_PASSWORD"},
+		{"password without username", map[string]string{"ELASTICSEARCH_PASSWORD": "changeme"}, "must be set together"},
+		{"api key with basic auth", map[string]string{"ELASTICSEARCH_API_KEY": "key", "ELASTICSEARCH_USERNAME": "elastic", "ELASTICSEARCH_PASSWORD": "changeme"}, "cannot be combined"},
+		{"fingerprint with ca file", map[string]string{"ELASTICSEARCH_CA_FINGERPRINT": fingerprint, "ELASTICSEARCH_CA_CERT_FILE": "ca.crt"}, "not both"},
+		{"unreadable ca file", map[string]string{"ELASTICSEARCH_CA_CERT_FILE": "missing.crt"}, "cannot be read"},
+		{"incompatible version", map[string]string{"ELASTICSEARCH_VERSION": "7.17.21", "STACK_VERSION": "8.14.1"}, "not compatible"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elasticsearchConfig, err := testmain.NewSection(t, elasticsearchconfig.NewElasticsearchConfig, tt.env)
+			assert.NoError(t, err, "expected no error while creating ElasticsearchConfig")
+			validationErr := elasticsearchConfig.Validate()
+			assert.Error(t, validationErr)
+			assert.Contains(t, validationErr.Error(), tt.contains)
+		})
+	}
+}
+
+func TestElasticsearchConfig_CompatibleVersion(t *testing.T) {
+	elasticsearchConfig, err := testmain.NewSection(t, elasticsearchconfig.NewElasticsearchConfig, map[string]string{"ELASTICSEARCH_VERSION": "8.15.0-SNAPSHOT", "STACK_VERSION": "8.14.1"})
+	assert.NoError(t, err, "expected no error while creating ElasticsearchConfig")
+	assert.NoError(t, elasticsearchConfig.Validate(), "expected versions of the same major to be compatible")
+	assert.Equal(t, elasticsearchconfig.Version{Major: 8, Minor: 15}, elasticsearchConfig.ServerVersion())
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected elasticsearchconfig.Version
+		ok       bool
+	}{
+		{"8.14.1", elasticsearchconfig.Version{Major: 8, Minor: 14, Patch: 1}, true},
+		{"v7.17", elasticsearchconfig.Version{Major: 7, Minor: 17}, true},
+		{"8", elasticsearchconfig.Version{Major: 8}, true},
+		{"8.x", elasticsearchconfig.Version{}, false},
+		{"", elasticsearchconfig.Version{}, false},
+	}
+	for _, tt := range tests {
+		version, ok := elasticsearchconfig.ParseVersion(tt.version)
+		assert.Equal(t, tt.ok, ok, tt.version)
+		assert.Equal(t, tt.expected, version, tt.version)
+	}
+}
@@ -1,10 +1,13 @@
 package envmanager_test
 
 import (
+	"fmt"
 	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
 	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/hbttundar/diabuddy-api-config/util/resolver/rootpath"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -212,3 +215,201 @@ func setupTestEnvironment(setupEnv map[string]string) {
 	}
 	testmain.Setup()
 }
+
+func TestEnvManager_Lookup(t *testing.T) {
+	tests := []struct {
+		name             string
+		setupEnv         map[string]string
+		unsetKeys        []string
+		policy           envmanager.EmptyValuePolicy
+		key              string
+		expectedValue    string
+		expectedFound    bool
+		expectedSource   string
+		expectedGetValue string
+	}{
+		{
+			name:             "Value set in the process environment",
+			setupEnv:         map[string]string{"APP_NAME": "from-env"},
+			key:              "APP_NAME",
+			expectedValue:    "from-env",
+			expectedFound:    true,
+			expectedSource:   envmanager.SourceEnvironment,
+			expectedGetValue: "from-env",
+		},
+		{
+			name:             "Value loaded from the env file",
+			unsetKeys:        []string{"CLUSTER_NAME"},
+			key:              "CLUSTER_NAME",
+			expectedValue:    "docker-cluster",
+			expectedFound:    true,
+			expectedSource:   envmanager.SourceEnvFile,
+			expectedGetValue: "docker-cluster",
+		},
+		{
+			name:             "Empty value falls back to the default by default",
+			setupEnv:         map[string]string{"APP_NAME": ""},
+			key:              "APP_NAME",
+			expectedValue:    "default_app",
+			expectedFound:    true,
+			expectedSource:   envmanager.SourceDefault,
+			expectedGetValue: "default_app",
+		},
+		{
+			name:             "Empty value overrides the default when configured",
+			setupEnv:         map[string]string{"APP_NAME": ""},
+			policy:           envmanager.EmptyOverridesDefault,
+			key:              "APP_NAME",
+			expectedValue:    "",
+			expectedFound:    true,
+			expectedSource:   envmanager.SourceEnvironment,
+			expectedGetValue: "",
+		},
+		{
+			name:             "Unknown key is not found",
+			key:              "NON_EXISTENT_KEY",
+			expectedValue:    "",
+			expectedFound:    false,
+			expectedSource:   "",
+			expectedGetValue: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestEnvironment(tt.setupEnv)
+			testmain.ClearEnvVars(tt.unsetKeys)
+
+			manager, err := envmanager.NewEnvManager(envmanager.WithEmptyValuePolicy(tt.policy), envmanager.WithUseCache(true))
+			assert.NoError(t, err, "expected no error while creating EnvManager")
+
+			value, found, source := manager.Lookup(tt.key)
+			assert.Equal(t, tt.expectedValue, value, "expected value to match")
+			assert.Equal(t, tt.expectedFound, found, "expected found to match")
+			assert.Equal(t, tt.expectedSource, source, "expected source to match")
+			assert.Equal(t, tt.expectedGetValue, manager.Get(tt.key), "expected Get to agree with Lookup")
+		})
+	}
+}
+
+func TestEnvManager_GetDoesNotCacheCallSiteDefaults(t *testing.T) {
+	setupTestEnvironment(map[string]string{})
+
+	manager, err := envmanager.NewEnvManager(envmanager.WithUseCache(true))
+	assert.NoError(t, err, "expected no error while creating EnvManager")
+
+	assert.Equal(t, "first", manager.Get("UNSET_CACHE_KEY", "first"), "expected the call-site default")
+	assert.Equal(t, "second", manager.Get("UNSET_CACHE_KEY", "second"), "expected each call-site default to be honoured")
+	_, found, _ := manager.Lookup("UNSET_CACHE_KEY")
+	assert.False(t, found, "expected the key to remain not found")
+}
+
+type recordingT struct {
+	cleanups []func()
+	errors   []string
+}
+
+func (r *recordingT) Helper()           {}
+func (r *recordingT) Cleanup(fn func()) { r.cleanups = append(r.cleanups, fn) }
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestEnvManager_StrictMode(t *testing.T) {
+	t.Run("Panic on undeclared key", func(t *testing.T) {
+		manager, err := envmanager.NewEnvManager(envmanager.WithEnvironment("test"), envmanager.WithStrictMode(envmanager.StrictPanic))
+		assert.NoError(t, err, "expected no error while creating EnvManager")
+
+		assert.NotPanics(t, func() { manager.Get(envmanager.DbHostKey) }, "expected registered keys to be readable")
+		assert.Panics(t, func() { manager.Get("DB_HOSTS", "localhost") }, "expected a typo to panic")
+	})
+
+	t.Run("Record undeclared keys and report them when the test ends", func(t *testing.T) {
+		manager, err := envmanager.NewEnvManager(
+			envmanager.WithEnvironment("test"),
+			envmanager.WithStrictMode(envmanager.StrictRecord),
+			envmanager.WithDeclaredKeys("SERVICE_SPECIFIC_KEY"),
+		)
+		assert.NoError(t, err, "expected no error while creating EnvManager")
+
+		fake := &recordingT{}
+		manager.ReportUndeclaredReads(fake)
+
+		manager.Get("SERVICE_SPECIFIC_KEY")
+		manager.Get("DB_HOSTS")
+		manager.Get("DB_HOSTS")
+		manager.Get("APP_NAEM")
+
+		assert.Equal(t, []string{"APP_NAEM", "DB_HOSTS"}, manager.UndeclaredReads(), "expected undeclared keys to be recorded once")
+		assert.Equal(t, uint64(3), manager.UndeclaredReadCount(), "expected every undeclared read to be counted")
+
+		for _, cleanup := range fake.cleanups {
+			cleanup()
+		}
+		assert.Len(t, fake.errors, 1, "expected a single report at the end of the test")
+		assert.Contains(t, fake.errors[0], "APP_NAEM, DB_HOSTS")
+	})
+
+	t.Run("Degrade to a warning counter in production", func(t *testing.T) {
+		manager, err := envmanager.NewEnvManager(envmanager.WithEnvironment("production"), envmanager.WithStrictMode(envmanager.StrictPanic))
+		assert.NoError(t, err, "expected no error while creating EnvManager")
+
+		assert.NotPanics(t, func() {
+			manager.Get("DB_HOSTS")
+			manager.Get("DB_HOSTS")
+		}, "expected production to only count undeclared reads")
+		assert.Equal(t, uint64(2), manager.UndeclaredReadCount(), "expected undeclared reads to be counted")
+	})
+
+	t.Run("Extended defaults count as declared", func(t *testing.T) {
+		manager, err := envmanager.NewEnvManager(
+			envmanager.WithEnvironment("test"),
+			envmanager.WithStrictMode(envmanager.StrictPanic),
+			envmanager.WithExtendedDefaults(func(defaults map[string]string) { defaults["KAFKA_TOPIC"] = "events" }),
+		)
+		assert.NoError(t, err, "expected no error while creating EnvManager")
+		assert.NotPanics(t, func() { manager.Get("KAFKA_TOPIC") }, "expected extended defaults to be declared")
+	})
+}
+
+func TestEnvManager_GetList(t *testing.T) {
+	t.Setenv("APP_PREVIOUS_KEYS", " first, ,second ,")
+
+	envManager, err := envmanager.NewEnvManager()
+	assert.NoError(t, err, "expected no error while creating env manager")
+
+	assert.Equal(t, []string{"first", "second"}, envManager.GetList("APP_PREVIOUS_KEYS"))
+	assert.Equal(t, []string{"a", "b"}, envManager.GetList("UNSET_LIST_KEY", "a,b"), "expected the call-site default to be split")
+	assert.Empty(t, envmanager.SplitList(""), "expected an empty value to be an empty list")
+}
+
+func TestEnvManager_Reload(t *testing.T) {
+	workingDir, _ := os.Getwd()
+	rootDir, apiErr := rootpath.NewRootPathResolver().Resolve(workingDir)
+	assert.Nil(t, apiErr, "expected the module root to be found")
+	envFile := filepath.Join(rootDir, ".env.reload")
+	writeEnvFile := func(content string) {
+		assert.NoError(t, os.WriteFile(envFile, []byte(content), 0o600))
+	}
+	t.Cleanup(func() {
+		_ = os.Remove(envFile)
+		for _, key := range []string{"RELOAD_CHANGED", "RELOAD_REMOVED", "RELOAD_ADDED", "RELOAD_PROCESS"} {
+			_ = os.Unsetenv(key)
+		}
+	})
+
+	writeEnvFile("RELOAD_CHANGED=before\nRELOAD_REMOVED=present\nRELOAD_PROCESS=file\n")
+	t.Setenv("RELOAD_PROCESS", "process")
+	envManager, err := envmanager.NewEnvManager(envmanager.WithEnvironment("reload"), envmanager.WithUseCache(true))
+	assert.NoError(t, err, "expected no error while creating env manager")
+	assert.Equal(t, "before", envManager.Get("RELOAD_CHANGED"))
+
+	writeEnvFile("RELOAD_CHANGED=after\nRELOAD_ADDED=new\nRELOAD_PROCESS=changed\n")
+	assert.NoError(t, envManager.Reload(), "expected no error while reloading")
+
+	assert.Equal(t, "after", envManager.Get("RELOAD_CHANGED"), "expected changed file values to be replaced and the cache cleared")
+	assert.Equal(t, "new", envManager.Get("RELOAD_ADDED"), "expected new file values to be loaded")
+	_, found, _ := envManager.Lookup("RELOAD_REMOVED")
+	assert.False(t, found, "expected values removed from the file to be removed")
+	assert.Equal(t, "process", envManager.Get("RELOAD_PROCESS"), "expected process values to win")
+}
@@ -0,0 +1,97 @@
+package envmanager_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeEnvironment(t *testing.T) {
+	tests := map[string]envmanager.Environment{
+		"production":  envmanager.Production,
+		" PROD ":      envmanager.Production,
+		"stage":       envmanager.Staging,
+		"dev":         envmanager.Local,
+		"Development": envmanager.Local,
+		"testing":     envmanager.Test,
+		"qa":          envmanager.Environment("qa"),
+		"":            envmanager.Environment(""),
+	}
+
+	for value, expected := range tests {
+		assert.Equal(t, expected, envmanager.NormalizeEnvironment(value), "unexpected environment for %q", value)
+	}
+}
+
+func TestEnvironment_Helpers(t *testing.T) {
+	assert.True(t, envmanager.Production.IsProduction())
+	assert.True(t, envmanager.Staging.IsStaging())
+	assert.True(t, envmanager.Local.IsLocal())
+	assert.True(t, envmanager.Test.IsTest())
+	assert.False(t, envmanager.Local.IsProduction())
+	assert.True(t, envmanager.Staging.IsKnown())
+	assert.False(t, envmanager.Environment("qa").IsKnown(), "expected custom environments to be unknown")
+}
+
+func TestEnvManager_Environment(t *testing.T) {
+	t.Run("Option wins over APP_ENV", func(t *testing.T) {
+		t.Setenv(envmanager.AppEnvKey, "staging")
+
+		envManager, err := envmanager.NewEnvManager(envmanager.WithEnvironment("prod"))
+		assert.NoError(t, err, "expected no error while creating env manager")
+		assert.Equal(t, envmanager.Production, envManager.Environment())
+	})
+
+	t.Run("APP_ENV is normalized", func(t *testing.T) {
+		t.Setenv(envmanager.AppEnvKey, "testing")
+
+		envManager, err := envmanager.NewEnvManager()
+		assert.NoError(t, err, "expected no error while creating env manager")
+		assert.Equal(t, envmanager.Test, envManager.Environment())
+	})
+
+	t.Run("Base .env file is used when APP_ENV is empty", func(t *testing.T) {
+		t.Setenv(envmanager.AppEnvKey, "")
+
+		envManager, err := envmanager.NewEnvManager(envmanager.WithEmptyValuePolicy(envmanager.EmptyOverridesDefault))
+		assert.NoError(t, err, "expected no error while creating env manager")
+		assert.Equal(t, envmanager.Local, envManager.Environment(), "expected APP_ENV from the .env file")
+	})
+
+	t.Run("Resolved environment is the APP_ENV default", func(t *testing.T) {
+		t.Setenv(envmanager.AppEnvKey, "")
+
+		envManager, err := envmanager.NewEnvManager(envmanager.WithEnvironment("stage"))
+		assert.NoError(t, err, "expected no error while creating env manager")
+		value, found, source := envManager.Lookup(envmanager.AppEnvKey)
+		assert.True(t, found, "expected APP_ENV to be found")
+		assert.Equal(t, "staging", value)
+		assert.Equal(t, envmanager.SourceDefault, source)
+	})
+
+	t.Run("APP_ENV reads the resolved environment", func(t *testing.T) {
+		t.Setenv(envmanager.AppEnvKey, "prod")
+
+		envManager, err := envmanager.NewEnvManager()
+		assert.NoError(t, err, "expected no error while creating env manager")
+		assert.Equal(t, "production", envManager.Get(envmanager.AppEnvKey), "expected the alias to be normalized")
+		assert.Equal(t, "production", envManager.Get(envmanager.AppEnvKey, "local"), "expected the call-site default to be ignored")
+		value, found, source := envManager.Lookup(envmanager.AppEnvKey)
+		assert.True(t, found)
+		assert.Equal(t, "production", value)
+		assert.Equal(t, envmanager.SourceEnvironment, source)
+
+		envManager, err = envmanager.NewEnvManager(envmanager.WithEnvironment("test"))
+		assert.NoError(t, err, "expected no error while creating env manager")
+		assert.Equal(t, "test", envManager.Get(envmanager.AppEnvKey), "expected WithEnvironment to win over APP_ENV")
+		_, _, source = envManager.Lookup(envmanager.AppEnvKey)
+		assert.Equal(t, envmanager.SourceDefault, source)
+	})
+
+	t.Run("Strict mode degrades in every production alias", func(t *testing.T) {
+		envManager, err := envmanager.NewEnvManager(envmanager.WithEnvironment("prod"), envmanager.WithStrictMode(envmanager.StrictPanic))
+		assert.NoError(t, err, "expected no error while creating env manager")
+		assert.NotPanics(t, func() { envManager.Get("SOME_UNDECLARED_KEY") })
+		assert.Equal(t, uint64(1), envManager.UndeclaredReadCount())
+	})
+}
@@ -0,0 +1,54 @@
+package envmanager_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKeyRegistry_CoreKeysAreRegistered(t *testing.T) {
+	tests := []struct {
+		key             string
+		expectedSection string
+		expectedDefault string
+	}{
+		{key: envmanager.AppNameKey, expectedSection: envmanager.AppSection, expectedDefault: "default_app"},
+		{key: envmanager.AppEnvKey, expectedSection: envmanager.AppSection, expectedDefault: "local"},
+		{key: envmanager.AuthSecretKey, expectedSection: envmanager.AuthSection, expectedDefault: ""},
+		{key: envmanager.AuthAlgorithmKey, expectedSection: envmanager.AuthSection, expectedDefault: "HS256"},
+		{key: envmanager.ServerPortKey, expectedSection: envmanager.ServerSection, expectedDefault: "8080"},
+		{key: envmanager.DbHostKey, expectedSection: envmanager.DbSection, expectedDefault: "127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			definition, ok := envmanager.LookupKeyDefinition(tt.key)
+			assert.True(t, ok, "expected key %s to be registered", tt.key)
+			assert.Equal(t, tt.expectedSection, definition.Section, "expected section to match")
+			assert.Equal(t, tt.expectedDefault, definition.Default, "expected default to match")
+			assert.NotEmpty(t, definition.Description, "expected key %s to be described", tt.key)
+		})
+	}
+}
+
+func TestKeyRegistry_KeyDefinitionsAreOrderedBySection(t *testing.T) {
+	definitions := envmanager.KeyDefinitions()
+	assert.NotEmpty(t, definitions, "expected registered key definitions")
+	for i := 1; i < len(definitions); i++ {
+		assert.LessOrEqual(t, definitions[i-1].Section, definitions[i].Section, "expected definitions to be grouped by section")
+	}
+	assert.Equal(t, definitions, envmanager.KeyDefinitions(), "expected the order to be stable between calls")
+}
+
+func TestKeyRegistry_RegisterKeys(t *testing.T) {
+	envmanager.RegisterKeys(envmanager.KeyDefinition{Name: "REGISTRY_TEST_KEY", Section: "registry_test", Default: "first"})
+	envmanager.RegisterKeys(envmanager.KeyDefinition{Name: "REGISTRY_TEST_KEY", Section: "registry_test", Default: "second"})
+
+	definitions := envmanager.SectionKeyDefinitions("registry_test")
+	assert.Len(t, definitions, 1, "expected re-registration to replace the existing definition")
+	assert.Equal(t, "second", definitions[0].Default, "expected the latest definition to win")
+
+	manager, err := envmanager.NewEnvManager()
+	assert.NoError(t, err, "expected no error while creating EnvManager")
+	assert.Equal(t, "second", manager.Get("REGISTRY_TEST_KEY"), "expected registered defaults to be used by EnvManager")
+}
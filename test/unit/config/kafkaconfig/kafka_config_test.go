@@ -0,0 +1,119 @@
+package kafkaconfig_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/kafkaconfig"
+	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestKafkaConfig_Defaults(t *testing.T) {
+	kafkaConfig, err := testmain.NewSection(t, kafkaconfig.NewKafkaConfig, map[string]string{
+		"APP_NAME":      "diabuddy-user-api",
+		"APP_ENV":       "staging",
+		"KAFKA_BROKERS": "kafka-1:9092, kafka-2:9092",
+	})
+	assert.NoError(t, err, "expected no error while creating KafkaConfig")
+
+	assert.Equal(t, []string{"kafka-1:9092", "kafka-2:9092"}, kafkaConfig.Brokers())
+	assert.Equal(t, "diabuddy-user-api", kafkaConfig.ClientID(), "expected the client ID to default to APP_NAME")
+	assert.Equal(t, "diabuddy-user-api", kafkaConfig.ConsumerGroup(), "expected the consumer group to default to the client ID")
+	assert.Equal(t, "staging", kafkaConfig.TopicPrefix(), "expected the topic prefix to default to APP_ENV")
+	assert.Equal(t, "staging.user-created", kafkaConfig.Topic("user-created"))
+	assert.NoError(t, kafkaConfig.Validate())
+
+	settings, apiErr := kafkaConfig.Settings()
+	assert.Nil(t, apiErr)
+	assert.Nil(t, settings.SASL, "expected SASL to be disabled")
+	assert.Nil(t, settings.TLS, "expected TLS to be disabled")
+	assert.Equal(t, "staging.meals", settings.Topic("meals"))
+}
+
+func TestKafkaConfig_ExplicitValues(t *testing.T) {
+	kafkaConfig, err := testmain.NewSection(t, kafkaconfig.NewKafkaConfig, map[string]string{
+		"KAFKA_BROKERS":         "localhost:9092",
+		"KAFKA_CLIENT_ID":       "food-api",
+		"KAFKA_CONSUMER_GROUP":  "food-api-workers",
+		"KAFKA_TOPIC_PREFIX":    "diabuddy",
+		"KAFKA_TOPIC_SEPARATOR": "_",
+		"KAFKA_SASL_MECHANISM":  "scram-sha-512",
+		"KAFKA_SASL_USERNAME":   "food",
+		"KAFKA_SASL_PASSWORD":   "secret",
+	})
+	assert.NoError(t, err, "expected no error while creating KafkaConfig")
+
+	settings, apiErr := kafkaConfig.Settings()
+	assert.Nil(t, apiErr)
+	assert.Equal(t, "food-api", settings.ClientID)
+	assert.Equal(t, "food-api-workers", settings.ConsumerGroup)
+	assert.Equal(t, "diabuddy_meals", settings.Topic("meals"))
+	assert.Equal(t, &kafkaconfig.SASL{Mechanism: kafkaconfig.SASLScramSHA512, Username: "food", Password: "secret"}, settings.SASL)
+	assert.Equal(t, config.RedactedValue, kafkaConfig.Snapshot()["KAFKA_SASL_PASSWORD"])
+	assert.NoError(t, kafkaConfig.Validate())
+}
+
+func TestKafkaConfig_InvalidValues(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"broker without port", map[string]string{"KAFKA_BROKERS": "kafka-1"}},
+		{"unknown SASL mechanism", map[string]string{"KAFKA_SASL_MECHANISM": "GSSAPI"}},
+		{"malformed TLS flag", map[string]string{"KAFKA_TLS_ENABLED": "sometimes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testmain.NewSection(t, kafkaconfig.NewKafkaConfig, tt.env)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestKafkaConfig_Validate(t *testing.T) {
+	certFile, keyFile := testmain.WriteCertificate(t, "kafka")
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		expectedErr string
+	}{
+		{"missing brokers", map[string]string{"KAFKA_BROKERS": " "}, "KAFKA_BROKERS"},
+		{"missing SASL credentials", map[string]string{"KAFKA_SASL_MECHANISM": "PLAIN", "KAFKA_SASL_USERNAME": "food"}, "KAFKA_SASL_PASSWORD"},
+		{"certificate without key", map[string]string{"KAFKA_TLS_ENABLED": "true", "KAFKA_TLS_CERT_FILE": certFile}, "must be set together"},
+		{"unreadable CA file", map[string]string{"KAFKA_TLS_ENABLED": "true", "KAFKA_TLS_CA_FILE": filepath.Join(t.TempDir(), "missing.pem")}, "KAFKA_TLS_CA_FILE"},
+		{"insecure TLS in production", map[string]string{"APP_ENV": "production", "KAFKA_TLS_ENABLED": "true", "KAFKA_TLS_INSECURE_SKIP_VERIFY": "true"}, "KAFKA_TLS_INSECURE_SKIP_VERIFY"},
+		{"mutual TLS", map[string]string{"KAFKA_TLS_ENABLED": "true", "KAFKA_TLS_CA_FILE": certFile, "KAFKA_TLS_CERT_FILE": certFile, "KAFKA_TLS_KEY_FILE": keyFile}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kafkaConfig, err := testmain.NewSection(t, kafkaconfig.NewKafkaConfig, tt.env)
+			assert.NoError(t, err, "expected no error while creating KafkaConfig")
+			validationErr := kafkaConfig.Validate()
+			if tt.expectedErr == "" {
+				assert.NoError(t, validationErr)
+				return
+			}
+			assert.Error(t, validationErr)
+			assert.Contains(t, validationErr.Error(), tt.expectedErr)
+		})
+	}
+}
+
+func TestKafkaConfig_TLSConfig(t *testing.T) {
+	certFile, keyFile := testmain.WriteCertificate(t, "kafka")
+	kafkaConfig, err := testmain.NewSection(t, kafkaconfig.NewKafkaConfig, map[string]string{
+		"KAFKA_TLS_ENABLED":   "true",
+		"KAFKA_TLS_CA_FILE":   certFile,
+		"KAFKA_TLS_CERT_FILE": certFile,
+		"KAFKA_TLS_KEY_FILE":  keyFile,
+	})
+	assert.NoError(t, err, "expected no error while creating KafkaConfig")
+
+	tlsConfig, apiErr := kafkaConfig.TLSConfig()
+	assert.Nil(t, apiErr)
+	assert.NotNil(t, tlsConfig.RootCAs, "expected the CA file to replace the system pool")
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+}
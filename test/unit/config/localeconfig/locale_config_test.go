@@ -0,0 +1,113 @@
+package localeconfig_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"github.com/hbttundar/diabuddy-api-config/config/localeconfig"
+	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "de-DE", localeconfig.Normalize("de_de"))
+	assert.Equal(t, "en", localeconfig.Normalize(" EN "))
+	assert.Equal(t, "zh-Hant-TW", localeconfig.Normalize("zh-hant-tw"))
+	assert.Equal(t, "es-419", localeconfig.Normalize("es-419"))
+}
+
+func TestLocaleConfig_SupportedLocales(t *testing.T) {
+	localeConfig, err := testmain.NewSection(t, localeconfig.NewLocaleConfig, map[string]string{"APP_LOCALE": "de", "APP_FALLBACK_LOCALE": "en"})
+	assert.NoError(t, err, "expected no error while creating LocaleConfig")
+	assert.Equal(t, []string{"de", "en"}, localeConfig.SupportedLocales(), "expected the default and fallback when no list is set")
+
+	localeConfig, err = testmain.NewSection(t, localeconfig.NewLocaleConfig, map[string]string{"APP_LOCALE": "de_de", "APP_SUPPORTED_LOCALES": "en, de-DE, de, fr"})
+	assert.NoError(t, err, "expected no error while creating LocaleConfig")
+	assert.Equal(t, "de-DE", localeConfig.Locale())
+	assert.Equal(t, []string{"en", "de-DE", "de", "fr"}, localeConfig.SupportedLocales())
+	assert.True(t, localeConfig.IsSupported("DE_de"))
+	assert.False(t, localeConfig.IsSupported("it"))
+	assert.NoError(t, localeConfig.Validate())
+}
+
+func TestLocaleConfig_FallbackChain(t *testing.T) {
+	localeConfig, err := testmain.NewSection(t, localeconfig.NewLocaleConfig, map[string]string{
+		"APP_LOCALE":            "de-AT",
+		"APP_FALLBACK_LOCALE":   "en",
+		"APP_SUPPORTED_LOCALES": "de-AT,de,en,fr",
+	})
+	assert.NoError(t, err, "expected no error while creating LocaleConfig")
+
+	assert.Equal(t, []string{"de-AT", "de", "en"}, localeConfig.FallbackChain(""), "expected APP_LOCALE by default")
+	assert.Equal(t, []string{"fr", "en"}, localeConfig.FallbackChain("fr-CA"), "expected unsupported variants to fall back to their parent")
+	assert.Equal(t, []string{"en"}, localeConfig.FallbackChain("it"))
+	assert.Equal(t, []string{"en"}, localeConfig.FallbackChain("en-GB"), "expected no duplicates")
+}
+
+func TestLocaleConfig_Negotiate(t *testing.T) {
+	localeConfig, err := testmain.NewSection(t, localeconfig.NewLocaleConfig, map[string]string{
+		"APP_LOCALE":            "en",
+		"APP_FALLBACK_LOCALE":   "en",
+		"APP_SUPPORTED_LOCALES": "en,de,pt-BR",
+	})
+	assert.NoError(t, err, "expected no error while creating LocaleConfig")
+
+	tests := map[string]string{
+		"":                                "en",
+		"de":                              "de",
+		"de-CH, fr;q=0.9":                 "de",
+		"fr;q=0.9, de;q=0.8":              "de",
+		"it, pt;q=0.5":                    "pt-BR",
+		"de;q=0, pt-br;q=0.1":             "pt-BR",
+		"da, en-GB;q=0.8, en;q=0.7":       "en",
+		"fr, *;q=0.5":                     "en",
+		"not a tag!, de_de;q=0.3":         "de",
+		"DE;q=0.4, pt-BR;q=0.6, it;q=0.9": "pt-BR",
+	}
+	for header, expected := range tests {
+		assert.Equal(t, expected, localeConfig.Negotiate(header), "unexpected locale for %q", header)
+	}
+}
+
+func TestLocaleConfig_Validate(t *testing.T) {
+	localeConfig, err := testmain.NewSection(t, localeconfig.NewLocaleConfig, map[string]string{
+		"APP_LOCALE":            "de",
+		"APP_FALLBACK_LOCALE":   "fr",
+		"APP_SUPPORTED_LOCALES": "de,en",
+	})
+	assert.NoError(t, err, "expected no error while creating LocaleConfig")
+	err = localeConfig.Validate()
+	assert.Error(t, err, "expected an unsupported fallback to be rejected")
+	if err != nil {
+		assert.Contains(t, err.Error(), `APP_FALLBACK_LOCALE "fr" is not one of APP_SUPPORTED_LOCALES: de, en`)
+	}
+
+	testmain.SetupEnv(t, map[string]string{"APP_LOCALE": "", "APP_FALLBACK_LOCALE": ""})
+	envManager, envErr := envmanager.NewEnvManager(envmanager.WithUseDefault(false))
+	assert.NoError(t, envErr, "expected no error while creating env manager")
+	localeConfig, err = localeconfig.NewLocaleConfig(envManager)
+	assert.NoError(t, err, "expected no error while creating LocaleConfig")
+	err = localeConfig.Validate()
+	assert.Error(t, err, "expected empty locales to be reported as missing")
+	if err != nil {
+		assert.Equal(t, "Error 500: missing required key(s): APP_LOCALE, APP_FALLBACK_LOCALE", err.Error())
+	}
+
+	_, err = testmain.NewSection(t, localeconfig.NewLocaleConfig, map[string]string{"APP_SUPPORTED_LOCALES": "en,english please"})
+	assert.Error(t, err, "expected an invalid locale to be rejected")
+	if err != nil {
+		assert.Contains(t, err.Error(), `APP_SUPPORTED_LOCALES must be a locale such as en or de-DE, got "english please"`)
+	}
+}
+
+func TestLocaleConfig_Introspection(t *testing.T) {
+	localeConfig, err := testmain.NewSection(t, localeconfig.NewLocaleConfig, nil)
+	assert.NoError(t, err, "expected no error while creating LocaleConfig")
+
+	var section config.Config = localeConfig
+	introspectable, ok := section.(config.Introspectable)
+	assert.True(t, ok, "expected LocaleConfig to implement every capability interface")
+	assert.Equal(t, []string{envmanager.AppLocaleKey, envmanager.AppFallbackLocaleKey, envmanager.AppSupportedLocalesKey}, introspectable.Keys())
+	assert.Len(t, introspectable.Describe(), 3)
+	assert.Equal(t, "en", introspectable.Snapshot()[envmanager.AppLocaleKey])
+}
@@ -0,0 +1,177 @@
+package logconfig_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/logconfig"
+	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func decodeRecord(t *testing.T, buffer *bytes.Buffer) map[string]any {
+	t.Helper()
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &record), "expected a JSON log record")
+	return record
+}
+
+func TestLogConfig_Defaults(t *testing.T) {
+	logConfig, err := testmain.NewSectionWithOptions(t, logconfig.NewLogConfig, map[string]string{"APP_DEBUG": "false"})
+	assert.NoError(t, err, "expected no error while creating LogConfig")
+
+	assert.Equal(t, slog.LevelInfo, logConfig.Level())
+	assert.Equal(t, logconfig.JSONFormat, logConfig.Format())
+	assert.Equal(t, logconfig.StdoutOutput, logConfig.Output())
+	assert.NoError(t, logConfig.Validate())
+	assert.Equal(t, []string{"LOG_LEVEL", "LOG_FORMAT", "LOG_OUTPUT"}, logConfig.Keys())
+}
+
+func TestLogConfig_Level(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected slog.Level
+	}{
+		{"configured level", map[string]string{"LOG_LEVEL": "warn", "APP_DEBUG": "false"}, slog.LevelWarn},
+		{"upper case level", map[string]string{"LOG_LEVEL": "ERROR", "APP_DEBUG": "false"}, slog.LevelError},
+		{"debug raises the level", map[string]string{"LOG_LEVEL": "error", "APP_DEBUG": "true"}, slog.LevelDebug},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logConfig, err := testmain.NewSectionWithOptions(t, logconfig.NewLogConfig, tt.env)
+			assert.NoError(t, err, "expected no error while creating LogConfig")
+			assert.Equal(t, tt.expected, logConfig.Level())
+		})
+	}
+}
+
+func TestLogConfig_InvalidValues(t *testing.T) {
+	_, err := testmain.NewSectionWithOptions(t, logconfig.NewLogConfig, map[string]string{"LOG_LEVEL": "verbose"})
+	assert.Error(t, err, "expected an unknown level to be rejected")
+	assert.Contains(t, err.Error(), "LOG_LEVEL")
+
+	_, err = testmain.NewSectionWithOptions(t, logconfig.NewLogConfig, map[string]string{"LOG_FORMAT": "xml"})
+	assert.Error(t, err, "expected an unknown format to be rejected")
+	assert.Contains(t, err.Error(), "LOG_FORMAT")
+
+	_, err = testmain.NewSectionWithOptions(t, logconfig.NewLogConfig, map[string]string{"APP_DEBUG": "maybe"})
+	assert.Error(t, err, "expected a malformed APP_DEBUG to be rejected")
+}
+
+func TestLogConfig_NewLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logConfig, err := testmain.NewSectionWithOptions(t, logconfig.NewLogConfig, map[string]string{"APP_NAME": "diabuddy", "APP_ENV": "staging", "APP_DEBUG": "false"}, logconfig.WithWriter(&buffer))
+	assert.NoError(t, err, "expected no error while creating LogConfig")
+
+	logger, apiErr := logConfig.NewLogger()
+	assert.Nil(t, apiErr, "expected no error while creating the logger")
+
+	logger.Debug("hidden")
+	assert.Empty(t, buffer.String(), "expected debug records to be dropped at info level")
+
+	logger.Info("started", "port", 8080)
+	record := decodeRecord(t, &buffer)
+	assert.Equal(t, "started", record["msg"])
+	assert.Equal(t, "diabuddy", record["app"])
+	assert.Equal(t, "staging", record["env"])
+	assert.Equal(t, float64(8080), record["port"])
+}
+
+func TestLogConfig_NewLoggerText(t *testing.T) {
+	var buffer bytes.Buffer
+	logConfig, err := testmain.NewSectionWithOptions(t, logconfig.NewLogConfig, map[string]string{"LOG_FORMAT": "text", "APP_ENV": "local"}, logconfig.WithWriter(&buffer))
+	assert.NoError(t, err, "expected no error while creating LogConfig")
+
+	logger, apiErr := logConfig.NewLogger()
+	assert.Nil(t, apiErr, "expected no error while creating the logger")
+	logger.Info("started")
+	assert.Contains(t, buffer.String(), "msg=started")
+	assert.Contains(t, buffer.String(), "env=local")
+}
+
+func TestLogConfig_FileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	assert.NoError(t, os.WriteFile(path, []byte("existing\n"), 0o644))
+
+	logConfig, err := testmain.NewSectionWithOptions(t, logconfig.NewLogConfig, map[string]string{"LOG_OUTPUT": path})
+	assert.NoError(t, err, "expected no error while creating LogConfig")
+	logger, apiErr := logConfig.NewLogger()
+	assert.Nil(t, apiErr, "expected no error while creating the logger")
+	logger.Info("appended")
+	assert.NoError(t, logConfig.Close())
+
+	content, readErr := os.ReadFile(path)
+	assert.NoError(t, readErr)
+	assert.True(t, strings.HasPrefix(string(content), "existing\n"), "expected the file to be appended to")
+	assert.Contains(t, string(content), "appended")
+
+	logConfig, err = testmain.NewSectionWithOptions(t, logconfig.NewLogConfig, map[string]string{"LOG_OUTPUT": filepath.Join(t.TempDir(), "missing", "app.log")})
+	assert.NoError(t, err, "expected no error while creating LogConfig")
+	_, apiErr = logConfig.NewLogger()
+	assert.NotNil(t, apiErr, "expected an error for a file that cannot be opened")
+}
+
+func TestLogConfig_ReloadSwitchesFile(t *testing.T) {
+	first := filepath.Join(t.TempDir(), "first.log")
+	second := filepath.Join(t.TempDir(), "second.log")
+	logConfig, err := testmain.NewSectionWithOptions(t, logconfig.NewLogConfig, map[string]string{"LOG_OUTPUT": first})
+	assert.NoError(t, err, "expected no error while creating LogConfig")
+	t.Cleanup(func() { _ = logConfig.Close() })
+
+	logger, apiErr := logConfig.NewLogger()
+	assert.Nil(t, apiErr)
+	logger.Info("before")
+
+	t.Setenv("LOG_OUTPUT", second)
+	t.Setenv("LOG_LEVEL", "warn")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = logConfig.Level()
+		}
+	}()
+	assert.Nil(t, logConfig.Reload(), "expected no error while reloading")
+	<-done
+	assert.Equal(t, second, logConfig.Output())
+	assert.Equal(t, slog.LevelWarn, logConfig.Level())
+
+	logger, apiErr = logConfig.NewLogger()
+	assert.Nil(t, apiErr)
+	logger.Warn("after")
+
+	firstContent, _ := os.ReadFile(first)
+	secondContent, _ := os.ReadFile(second)
+	assert.Contains(t, string(firstContent), "before")
+	assert.NotContains(t, string(firstContent), "after", "expected the old log file not to be reused")
+	assert.Contains(t, string(secondContent), "after")
+}
+
+func TestRedactingHandler(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(logconfig.NewRedactingHandler(slog.NewJSONHandler(&buffer, nil), "token"))
+
+	logger.With("db_password", "secret").WithGroup("request").Info("login",
+		"TOKEN", "abc",
+		"user", "jane",
+		slog.Group("auth", slog.String("AUTH_SECRET", "hidden"), slog.String("method", "password")),
+	)
+
+	record := decodeRecord(t, &buffer)
+	assert.Equal(t, config.RedactedValue, record["db_password"], "expected registered sensitive keys to be redacted")
+	request := record["request"].(map[string]any)
+	assert.Equal(t, config.RedactedValue, request["TOKEN"], "expected extra keys to be redacted case-insensitively")
+	assert.Equal(t, "jane", request["user"])
+	auth := request["auth"].(map[string]any)
+	assert.Equal(t, config.RedactedValue, auth["AUTH_SECRET"], "expected keys inside groups to be redacted")
+	assert.Equal(t, "password", auth["method"])
+	assert.NotContains(t, buffer.String(), "secret\"")
+	assert.NotContains(t, buffer.String(), "hidden")
+}
@@ -0,0 +1,116 @@
+package mailconfig_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config/mailconfig"
+	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMailConfig_Defaults(t *testing.T) {
+	mailConfig, err := testmain.NewSectionWithOptions(t, mailconfig.NewMailConfig, map[string]string{
+		"APP_NAME":          "diabuddy-user-api",
+		"MAIL_DRIVER":       "smtp",
+		"MAIL_HOST":         "smtp.example.com",
+		"MAIL_FROM_ADDRESS": "no-reply@diabuddy.io",
+	})
+	assert.NoError(t, err, "expected no error while creating MailConfig")
+
+	assert.Equal(t, mailconfig.SMTPDriver, mailConfig.Driver())
+	assert.Equal(t, mailconfig.StartTLSEncryption, mailConfig.Encryption())
+	assert.Equal(t, 587, mailConfig.Port(), "expected the STARTTLS submission port by default")
+	assert.Equal(t, "diabuddy-user-api", mailConfig.From().Name, "expected the sender name to default to APP_NAME")
+	assert.Equal(t, "no-reply@diabuddy.io", mailConfig.From().Address)
+	assert.Equal(t, 10*time.Second, mailConfig.DialTimeout())
+	assert.Equal(t, 30*time.Second, mailConfig.SendTimeout())
+	assert.IsType(t, &mailconfig.SMTPSender{}, mailConfig.NewSender())
+	assert.NoError(t, mailConfig.Validate())
+}
+
+func TestMailConfig_PortFollowsEncryption(t *testing.T) {
+	tests := []struct {
+		encryption string
+		expected   int
+	}{
+		{"tls", 465},
+		{"STARTTLS", 587},
+		{"none", 25},
+	}
+	for _, tt := range tests {
+		mailConfig, err := testmain.NewSectionWithOptions(t, mailconfig.NewMailConfig, map[string]string{"MAIL_ENCRYPTION": tt.encryption})
+		assert.NoError(t, err, "expected no error while creating MailConfig")
+		assert.Equal(t, tt.expected, mailConfig.Port(), tt.encryption)
+	}
+
+	mailConfig, err := testmain.NewSectionWithOptions(t, mailconfig.NewMailConfig, map[string]string{"MAIL_ENCRYPTION": "tls", "MAIL_PORT": "2465"})
+	assert.NoError(t, err, "expected no error while creating MailConfig")
+	assert.Equal(t, 2465, mailConfig.Port(), "expected an explicit port to win")
+}
+
+func TestMailConfig_Drivers(t *testing.T) {
+	mailConfig, err := testmain.NewSectionWithOptions(t, mailconfig.NewMailConfig, map[string]string{"MAIL_DRIVER": "log"})
+	assert.NoError(t, err, "expected no error while creating MailConfig")
+	assert.IsType(t, &mailconfig.LogSender{}, mailConfig.NewSender())
+	assert.NoError(t, mailConfig.Validate(), "expected the log driver to need no SMTP keys")
+
+	mailConfig, err = testmain.NewSectionWithOptions(t, mailconfig.NewMailConfig, map[string]string{"MAIL_DRIVER": "Array"})
+	assert.NoError(t, err, "expected no error while creating MailConfig")
+	assert.IsType(t, &mailconfig.ArraySender{}, mailConfig.NewSender())
+}
+
+func TestMailConfig_InvalidValues(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"driver", map[string]string{"MAIL_DRIVER": "sendmail"}},
+		{"encryption", map[string]string{"MAIL_ENCRYPTION": "ssl"}},
+		{"port", map[string]string{"MAIL_PORT": "smtp"}},
+		{"port range", map[string]string{"MAIL_PORT": "70000"}},
+		{"from address", map[string]string{"MAIL_FROM_ADDRESS": "no-reply"}},
+		{"from address with name", map[string]string{"MAIL_FROM_ADDRESS": "DiaBuddy <no-reply@diabuddy.io>"}},
+		{"dial timeout", map[string]string{"MAIL_DIAL_TIMEOUT": "-1s"}},
+		{"send timeout", map[string]string{"MAIL_SEND_TIMEOUT": "later"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testmain.NewSectionWithOptions(t, mailconfig.NewMailConfig, tt.env)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMailConfig_ValidateLoopbackCredentials(t *testing.T) {
+	for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+		t.Run(host, func(t *testing.T) {
+			mailConfig, err := testmain.NewSectionWithOptions(t, mailconfig.NewMailConfig, map[string]string{"APP_ENV": "production", "MAIL_DRIVER": "smtp", "MAIL_HOST": host, "MAIL_ENCRYPTION": "none", "MAIL_USERNAME": "app", "MAIL_PASSWORD": "secret"})
+			assert.NoError(t, err, "expected no error while creating MailConfig")
+			assert.NoError(t, mailConfig.Validate(), "expected credentials to a loopback host to be allowed without encryption")
+		})
+	}
+}
+
+func TestMailConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		contains string
+	}{
+		{"smtp without host", map[string]string{"MAIL_DRIVER": "smtp", "MAIL_FROM_ADDRESS": ""}, "MAIL_HOST, MAIL_FROM_ADDRESS"},
+		{"username without password", map[string]string{"MAIL_DRIVER": "smtp", "MAIL_HOST": "smtp", "MAIL_USERNAME": "app"}, "MAIL_PASSWORD"},
+		{"log driver in production", map[string]string{"APP_ENV": "production", "MAIL_DRIVER": "log"}, "MAIL_DRIVER"},
+		{"array driver in production", map[string]string{"APP_ENV": "production", "MAIL_DRIVER": "array"}, "MAIL_DRIVER"},
+		{"plain text credentials in production", map[string]string{"APP_ENV": "production", "MAIL_DRIVER": "smtp", "MAIL_HOST": "smtp", "MAIL_ENCRYPTION": "none", "MAIL_USERNAME": "app", "MAIL_PASSWORD": "secret"}, "MAIL_ENCRYPTION"},
+		{"plain text credentials locally", map[string]string{"APP_ENV": "local", "MAIL_DRIVER": "smtp", "MAIL_HOST": "mailpit", "MAIL_ENCRYPTION": "none", "MAIL_USERNAME": "app", "MAIL_PASSWORD": "secret"}, "MAIL_ENCRYPTION"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mailConfig, err := testmain.NewSectionWithOptions(t, mailconfig.NewMailConfig, tt.env)
+			assert.NoError(t, err, "expected no error while creating MailConfig")
+			validationErr := mailConfig.Validate()
+			assert.Error(t, validationErr)
+			assert.Contains(t, validationErr.Error(), tt.contains)
+		})
+	}
+}
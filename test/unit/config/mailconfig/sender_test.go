@@ -0,0 +1,94 @@
+package mailconfig_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/hbttundar/diabuddy-api-config/config/mailconfig"
+	"github.com/hbttundar/diabuddy-api-config/config/mailconfig/smtptest"
+	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/mail"
+	"strconv"
+	"testing"
+)
+
+func TestSMTPSender_Send(t *testing.T) {
+	server := smtptest.NewServer()
+	t.Cleanup(server.Close)
+
+	mailConfig, err := testmain.NewSectionWithOptions(t, mailconfig.NewMailConfig, map[string]string{
+		"APP_NAME":          "DiaBuddy",
+		"MAIL_DRIVER":       "smtp",
+		"MAIL_HOST":         server.Host(),
+		"MAIL_PORT":         strconv.Itoa(server.Port()),
+		"MAIL_ENCRYPTION":   "none",
+		"MAIL_USERNAME":     "app",
+		"MAIL_PASSWORD":     "secret",
+		"MAIL_FROM_ADDRESS": "no-reply@diabuddy.io",
+	})
+	assert.NoError(t, err, "expected no error while creating MailConfig")
+	assert.NoError(t, mailConfig.Validate())
+
+	sendErr := mailConfig.NewSender().Send(context.Background(), mailconfig.Message{
+		To:      []string{"Jane Doe <jane@example.com>", "john@example.com"},
+		Subject: "Verify your e-mail address",
+		Body:    "Welcome!\nOpen https://diabuddy.io/verify?token=abc to continue.",
+	})
+	assert.NoError(t, sendErr)
+
+	messages := server.Messages()
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "app", messages[0].Username)
+	assert.Equal(t, "no-reply@diabuddy.io", messages[0].From)
+	assert.Equal(t, []string{"jane@example.com", "john@example.com"}, messages[0].To)
+	assert.Contains(t, messages[0].Data, `From: "DiaBuddy" <no-reply@diabuddy.io>`)
+	assert.Contains(t, messages[0].Data, `To: "Jane Doe" <jane@example.com>, <john@example.com>`)
+	assert.Contains(t, messages[0].Data, "Subject: Verify your e-mail address")
+	assert.Contains(t, messages[0].Data, "Content-Type: text/plain; charset=utf-8")
+	assert.Contains(t, messages[0].Data, "Welcome!\r\nOpen https://diabuddy.io/verify?token=3Dabc to continue.")
+}
+
+func TestSMTPSender_Errors(t *testing.T) {
+	server := smtptest.NewServer()
+	t.Cleanup(server.Close)
+
+	sender := &mailconfig.SMTPSender{Host: server.Host(), Port: server.Port(), Encryption: mailconfig.StartTLSEncryption}
+	err := sender.Send(context.Background(), mailconfig.Message{To: []string{"jane@example.com"}})
+	assert.Error(t, err, "expected STARTTLS to be required")
+
+	sender.Encryption = mailconfig.NoEncryption
+	assert.Error(t, sender.Send(context.Background(), mailconfig.Message{}), "expected a recipient to be required")
+	assert.Error(t, sender.Send(context.Background(), mailconfig.Message{To: []string{"jane"}}), "expected invalid recipients to be rejected")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, sender.Send(ctx, mailconfig.Message{To: []string{"jane@example.com"}}), "expected a cancelled context to abort")
+	assert.Empty(t, server.Messages())
+}
+
+func TestLogSender_Send(t *testing.T) {
+	var output bytes.Buffer
+	mailConfig, err := testmain.NewSectionWithOptions(t, mailconfig.NewMailConfig, map[string]string{"MAIL_DRIVER": "log"}, mailconfig.WithLogger(slog.New(slog.NewTextHandler(&output, nil))))
+	assert.NoError(t, err, "expected no error while creating MailConfig")
+
+	assert.NoError(t, mailConfig.NewSender().Send(context.Background(), mailconfig.Message{To: []string{"jane@example.com"}, Subject: "Hello"}))
+	assert.Contains(t, output.String(), "mail sent")
+	assert.Contains(t, output.String(), "subject=Hello")
+}
+
+func TestArraySender_Send(t *testing.T) {
+	sender := mailconfig.NewArraySender()
+	message := mailconfig.Message{To: []string{"jane@example.com"}, Subject: "<b>Hello</b>", HTML: true}
+	assert.NoError(t, sender.Send(context.Background(), message))
+	assert.Equal(t, []mailconfig.Message{message}, sender.Messages())
+
+	sender.Reset()
+	assert.Empty(t, sender.Messages())
+	assert.Error(t, sender.Send(context.Background(), mailconfig.Message{To: []string{"not an address"}}))
+}
+
+func TestLogSender_NilLogger(t *testing.T) {
+	sender := mailconfig.NewLogSender(nil, mail.Address{Address: "no-reply@diabuddy.io"})
+	assert.NoError(t, sender.Send(context.Background(), mailconfig.Message{To: []string{"jane@example.com"}}))
+}
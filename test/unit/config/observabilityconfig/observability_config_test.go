@@ -0,0 +1,145 @@
+package observabilityconfig_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/observabilityconfig"
+	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestObservabilityConfig_Defaults(t *testing.T) {
+	observabilityConfig, err := testmain.NewSection(t, observabilityconfig.NewObservabilityConfig, map[string]string{"APP_NAME": "diabuddy-user-api", "APP_ENV": "staging"})
+	assert.NoError(t, err, "expected no error while creating ObservabilityConfig")
+
+	assert.True(t, observabilityConfig.Enabled())
+	assert.Equal(t, "diabuddy-user-api", observabilityConfig.ServiceName(), "expected the service name to default to APP_NAME")
+	assert.Equal(t, map[string]string{
+		"service.name":                "diabuddy-user-api",
+		"deployment.environment.name": "staging",
+	}, observabilityConfig.ResourceAttributes())
+	assert.Equal(t, observabilityconfig.ParentBasedAlwaysOnSampler, observabilityConfig.Sampler())
+	assert.Equal(t, 1.0, observabilityConfig.SamplingRatio())
+	assert.Equal(t, observabilityconfig.OTLPExporter, observabilityConfig.TracesExporter())
+	assert.Equal(t, observabilityconfig.OTLPExporter, observabilityConfig.MetricsExporter())
+	assert.Equal(t, time.Minute, observabilityConfig.MetricsInterval())
+	assert.Equal(t, observabilityconfig.ExporterOptions{
+		Endpoint:    "http://localhost:4318",
+		Host:        "localhost:4318",
+		Insecure:    true,
+		Protocol:    observabilityconfig.HTTPProtobufProtocol,
+		Headers:     map[string]string{},
+		Timeout:     10 * time.Second,
+		Compression: "none",
+	}, observabilityConfig.ExporterOptions())
+	assert.NoError(t, observabilityConfig.Validate())
+}
+
+func TestObservabilityConfig_ExplicitValues(t *testing.T) {
+	observabilityConfig, err := testmain.NewSection(t, observabilityconfig.NewObservabilityConfig, map[string]string{
+		"APP_NAME":                       "diabuddy-user-api",
+		"APP_ENV":                        "production",
+		"OTEL_SERVICE_NAME":              "user-api",
+		"OTEL_RESOURCE_ATTRIBUTES":       "service.name=ignored, service.version=1.4.0, team=care%20platform",
+		"OTEL_TRACES_SAMPLER":            "parentbased_traceidratio",
+		"OTEL_TRACES_SAMPLER_ARG":        "0.25",
+		"OTEL_METRICS_EXPORTER":          "none",
+		"OTEL_EXPORTER_OTLP_ENDPOINT":    "https://otel.diabuddy.io:4317/",
+		"OTEL_EXPORTER_OTLP_PROTOCOL":    "grpc",
+		"OTEL_EXPORTER_OTLP_HEADERS":     "api-key=abc%3D%3D,x-tenant=diabuddy",
+		"OTEL_EXPORTER_OTLP_TIMEOUT":     "2500",
+		"OTEL_EXPORTER_OTLP_COMPRESSION": "gzip",
+		"OTEL_METRIC_EXPORT_INTERVAL":    "15000",
+	})
+	assert.NoError(t, err, "expected no error while creating ObservabilityConfig")
+
+	assert.Equal(t, "user-api", observabilityConfig.ServiceName(), "expected OTEL_SERVICE_NAME to win over the resource attributes")
+	attributes := observabilityConfig.ResourceAttributes()
+	assert.Equal(t, "1.4.0", attributes["service.version"])
+	assert.Equal(t, "care platform", attributes["team"])
+	assert.Equal(t, "production", attributes["deployment.environment.name"])
+	assert.Equal(t, 0.25, observabilityConfig.SamplingRatio())
+	assert.Equal(t, observabilityconfig.NoneExporter, observabilityConfig.MetricsExporter())
+	assert.Equal(t, 15*time.Second, observabilityConfig.MetricsInterval())
+
+	options := observabilityConfig.ExporterOptions()
+	assert.Equal(t, "https://otel.diabuddy.io:4317", options.Endpoint)
+	assert.Equal(t, "otel.diabuddy.io:4317", options.Host)
+	assert.False(t, options.Insecure)
+	assert.Equal(t, observabilityconfig.GRPCProtocol, options.Protocol)
+	assert.Equal(t, map[string]string{"api-key": "abc==", "x-tenant": "diabuddy"}, options.Headers)
+	assert.Equal(t, 2500*time.Millisecond, options.Timeout)
+	assert.Equal(t, "gzip", options.Compression)
+	assert.Equal(t, config.RedactedValue, observabilityConfig.Snapshot()["OTEL_EXPORTER_OTLP_HEADERS"])
+}
+
+func TestObservabilityConfig_ResourceAttributesWin(t *testing.T) {
+	observabilityConfig, err := testmain.NewSection(t, observabilityconfig.NewObservabilityConfig, map[string]string{
+		"APP_NAME":                 "diabuddy-user-api",
+		"OTEL_RESOURCE_ATTRIBUTES": "service.name=users,deployment.environment.name=eu-prod",
+	})
+	assert.NoError(t, err, "expected no error while creating ObservabilityConfig")
+	assert.Equal(t, "users", observabilityConfig.ServiceName())
+	assert.Equal(t, "eu-prod", observabilityConfig.ResourceAttributes()["deployment.environment.name"])
+}
+
+func TestObservabilityConfig_GRPCDefaultEndpoint(t *testing.T) {
+	observabilityConfig, err := testmain.NewSection(t, observabilityconfig.NewObservabilityConfig, map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"})
+	assert.NoError(t, err, "expected no error while creating ObservabilityConfig")
+	assert.Equal(t, "http://localhost:4317", observabilityConfig.ExporterOptions().Endpoint)
+}
+
+func TestObservabilityConfig_Disabled(t *testing.T) {
+	observabilityConfig, err := testmain.NewSection(t, observabilityconfig.NewObservabilityConfig, map[string]string{"OTEL_SDK_DISABLED": "true"})
+	assert.NoError(t, err, "expected no error while creating ObservabilityConfig")
+	assert.False(t, observabilityConfig.Enabled())
+	assert.Equal(t, observabilityconfig.NoneExporter, observabilityConfig.TracesExporter())
+	assert.Equal(t, observabilityconfig.NoneExporter, observabilityConfig.MetricsExporter())
+}
+
+func TestObservabilityConfig_SamplerArgIgnoredForOtherSamplers(t *testing.T) {
+	observabilityConfig, err := testmain.NewSection(t, observabilityconfig.NewObservabilityConfig, map[string]string{"OTEL_TRACES_SAMPLER": "always_on", "OTEL_TRACES_SAMPLER_ARG": "0.1"})
+	assert.NoError(t, err, "expected no error while creating ObservabilityConfig")
+	assert.Equal(t, 1.0, observabilityConfig.SamplingRatio())
+}
+
+func TestObservabilityConfig_InvalidValues(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"disabled", map[string]string{"OTEL_SDK_DISABLED": "maybe"}},
+		{"resource attributes", map[string]string{"OTEL_RESOURCE_ATTRIBUTES": "team"}},
+		{"resource attribute encoding", map[string]string{"OTEL_RESOURCE_ATTRIBUTES": "team=%zz"}},
+		{"sampler", map[string]string{"OTEL_TRACES_SAMPLER": "jaeger_remote"}},
+		{"sampling ratio", map[string]string{"OTEL_TRACES_SAMPLER": "traceidratio", "OTEL_TRACES_SAMPLER_ARG": "1.5"}},
+		{"traces exporter", map[string]string{"OTEL_TRACES_EXPORTER": "zipkin"}},
+		{"endpoint", map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "localhost:4318"}},
+		{"protocol", map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "thrift"}},
+		{"timeout", map[string]string{"OTEL_EXPORTER_OTLP_TIMEOUT": "10s"}},
+		{"compression", map[string]string{"OTEL_EXPORTER_OTLP_COMPRESSION": "zstd"}},
+		{"metric interval", map[string]string{"OTEL_METRIC_EXPORT_INTERVAL": "-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testmain.NewSection(t, observabilityconfig.NewObservabilityConfig, tt.env)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestObservabilityConfig_InvalidHeadersAreNotLeaked(t *testing.T) {
+	_, err := testmain.NewSection(t, observabilityconfig.NewObservabilityConfig, map[string]string{"OTEL_EXPORTER_OTLP_HEADERS": "api-key"})
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "api-key")
+}
+
+func TestObservabilityConfig_Validate(t *testing.T) {
+	observabilityConfig, err := testmain.NewSection(t, observabilityconfig.NewObservabilityConfig, map[string]string{"OTEL_SERVICE_NAME": " "})
+	assert.NoError(t, err, "expected no error while creating ObservabilityConfig")
+	assert.Empty(t, observabilityConfig.RequiredKeys(), "expected OTEL_SERVICE_NAME to be checked by Validate only")
+	validationErr := observabilityConfig.Validate()
+	assert.Error(t, validationErr)
+	assert.Contains(t, validationErr.Error(), "OTEL_SERVICE_NAME")
+}
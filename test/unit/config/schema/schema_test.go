@@ -0,0 +1,62 @@
+package schema_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"github.com/hbttundar/diabuddy-api-config/config/schema"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGenerate_CoversRegisteredKeys(t *testing.T) {
+	s := schema.Generate()
+
+	assert.Equal(t, schema.Draft, s.Schema, "expected the draft 2020-12 meta schema")
+	assert.Equal(t, "object", s.Type, "expected the configuration to be an object")
+	for _, definition := range envmanager.KeyDefinitions() {
+		property, ok := s.Properties[definition.Name]
+		assert.True(t, ok, "expected property for key %s", definition.Name)
+		if ok {
+			assert.Equal(t, definition.Section, property.Section, "expected section of %s to be exported", definition.Name)
+		}
+	}
+}
+
+func TestGenerateFor_PropertyTypes(t *testing.T) {
+	s := schema.GenerateFor([]envmanager.KeyDefinition{
+		{Name: "PORT", Section: "server", Type: envmanager.IntegerKey, Default: "8080"},
+		{Name: "DEBUG", Section: "app", Type: envmanager.BooleanKey},
+		{Name: "SECRET", Section: "auth", Default: "hidden", Sensitive: true},
+		{Name: "DRIVER", Section: "mail", Enum: []string{"smtp", "log"}},
+		{Name: "TOKEN", Section: "auth", Required: true},
+		{Name: "NAME", Section: "app", Required: true, Default: "app"},
+	})
+
+	assert.Equal(t, schema.Types{"integer", "string"}, s.Properties["PORT"].Type, "expected integers to accept strings")
+	assert.NotEmpty(t, s.Properties["PORT"].Pattern, "expected a pattern for string encoded integers")
+	assert.Contains(t, s.Properties["DEBUG"].Enum, "true", "expected boolean strings to be accepted")
+	assert.Empty(t, s.Properties["SECRET"].Default, "expected sensitive defaults to be omitted")
+	assert.True(t, s.Properties["SECRET"].WriteOnly, "expected sensitive keys to be write only")
+	assert.Equal(t, []any{"", "smtp", "log"}, s.Properties["DRIVER"].Enum, "expected enum values to be exported")
+	assert.Equal(t, []string{"TOKEN"}, s.Required, "expected only required keys without default to be required")
+}
+
+func TestSchema_JSONRoundTrip(t *testing.T) {
+	document, err := schema.Generate().JSON()
+	assert.NoError(t, err, "expected no error while encoding schema")
+
+	parsed, err := schema.Parse(document)
+	assert.NoError(t, err, "expected no error while parsing schema")
+
+	again, err := parsed.JSON()
+	assert.NoError(t, err, "expected no error while encoding parsed schema")
+	assert.JSONEq(t, string(document), string(again), "expected the schema to survive a round trip")
+}
+
+func TestGenerate_SectionKeysAreNotRequiredGlobally(t *testing.T) {
+	s := schema.Generate()
+
+	assert.NotContains(t, s.Required, envmanager.KafkaBrokersKey, "expected only the Kafka section to require brokers")
+	assert.NotContains(t, s.Required, envmanager.ElasticsearchAddressesKey, "expected only the Elasticsearch section to require addresses")
+	violations := s.ValidateMap("environment", map[string]string{envmanager.AppNameKey: "diabuddy-user-api"})
+	assert.Empty(t, violations, "expected a service without Kafka and Elasticsearch to validate")
+}
@@ -0,0 +1,94 @@
+package schema_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"github.com/hbttundar/diabuddy-api-config/config/schema"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testSchema() *schema.Schema {
+	return schema.GenerateFor([]envmanager.KeyDefinition{
+		{Name: "APP_NAME", Section: "app", Required: true},
+		{Name: "APP_DEBUG", Section: "app", Type: envmanager.BooleanKey, Default: "false"},
+		{Name: "DB_PORT", Section: "db", Type: envmanager.IntegerKey, Default: "5432"},
+		{Name: "TIMEOUT", Section: "server", Type: envmanager.DurationKey, Default: "5s"},
+		{Name: "MAIL_DRIVER", Section: "mail", Enum: []string{"smtp", "log"}},
+	})
+}
+
+func TestSchema_ValidateJSON(t *testing.T) {
+	document := "{\n  \"APP_NAME\": \"diabuddy\",\n  \"DB_PORT\": \"not-a-port\",\n  \"APP_DEBUG\": true\n}"
+
+	violations, err := testSchema().ValidateJSON("config.json", []byte(document))
+	assert.NoError(t, err, "expected no parse error")
+	assert.Len(t, violations, 1, "expected a single violation")
+	assert.Equal(t, "DB_PORT", violations[0].Key, "expected DB_PORT to be reported")
+	assert.Equal(t, 3, violations[0].Line, "expected the line of the key")
+	assert.Equal(t, 3, violations[0].Column, "expected the column of the key")
+	assert.Contains(t, violations.Err().Error(), "config.json:3:3: DB_PORT must match pattern", "expected the position in the error")
+}
+
+func TestSchema_ValidateJSON_SyntaxError(t *testing.T) {
+	_, err := testSchema().ValidateJSON("config.json", []byte("{\n  \"APP_NAME\": diabuddy\n}"))
+	assert.Error(t, err, "expected a parse error")
+	assert.Contains(t, err.Error(), "config.json:2:", "expected the parse error to carry a position")
+}
+
+func TestSchema_ValidateYAML(t *testing.T) {
+	document := "APP_NAME: diabuddy\nDB_PORT: 5432\nAPP_DEBUG: maybe\nMAIL_DRIVER: sendmail\n"
+
+	violations, err := testSchema().ValidateYAML("values.yaml", []byte(document))
+	assert.NoError(t, err, "expected no parse error")
+	assert.Len(t, violations, 2, "expected two violations")
+	assert.Equal(t, schema.Violation{Source: "values.yaml", Key: "APP_DEBUG", Line: 3, Column: 1, Message: violations[0].Message}, violations[0])
+	assert.Equal(t, "MAIL_DRIVER", violations[1].Key, "expected MAIL_DRIVER to be reported")
+	assert.Equal(t, 4, violations[1].Line, "expected the line of MAIL_DRIVER")
+	assert.Contains(t, violations[1].Message, "smtp, log", "expected allowed values in the message")
+}
+
+func TestSchema_ValidateDotenv(t *testing.T) {
+	document := "# comment\nAPP_NAME=diabuddy\n\nexport TIMEOUT=soon\nDB_PORT=\n"
+
+	violations, err := testSchema().ValidateDotenv(".env", []byte(document))
+	assert.NoError(t, err, "expected no parse error")
+	assert.Len(t, violations, 1, "expected empty values to be accepted as unset")
+	assert.Equal(t, "TIMEOUT", violations[0].Key, "expected TIMEOUT to be reported")
+	assert.Equal(t, 4, violations[0].Line, "expected the line of TIMEOUT")
+	assert.Equal(t, 8, violations[0].Column, "expected the column after the export keyword")
+}
+
+func TestSchema_ValidateMap_RequiredKeys(t *testing.T) {
+	violations := testSchema().ValidateMap("environment", map[string]string{"UNKNOWN_KEY": "x"})
+	assert.Len(t, violations, 1, "expected unknown keys to be allowed")
+	assert.Equal(t, "APP_NAME", violations[0].Key, "expected missing required key to be reported")
+	assert.Equal(t, "is required", violations[0].Message)
+
+	violations = testSchema().ValidateMap("environment", map[string]string{"APP_NAME": ""})
+	assert.Len(t, violations, 1, "expected an empty required key to be reported once")
+	assert.Equal(t, "must not be empty", violations[0].Message)
+	assert.Nil(t, schema.Violations{}.Err(), "expected no error without violations")
+}
+
+func TestSchema_ValidateFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": "{\"APP_NAME\": \"diabuddy\", \"DB_PORT\": 1.5}",
+		"config.yml":  "APP_NAME: diabuddy\nDB_PORT: 1.5\n",
+		".env.local":  "APP_NAME=diabuddy\nDB_PORT=1.5\n",
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+			violations, err := testSchema().ValidateFile(path)
+			assert.NoError(t, err, "expected no parse error")
+			assert.Len(t, violations, 1, "expected DB_PORT to be rejected")
+			assert.Equal(t, path, violations[0].Source, "expected the file path as source")
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package securityconfig_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config/securityconfig"
+	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func serve(securityConfig *securityconfig.SecurityConfig, request *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	securityConfig.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestSecurityConfig_EnvironmentDefaults(t *testing.T) {
+	securityConfig, err := testmain.NewSection(t, securityconfig.NewSecurityConfig, map[string]string{"APP_ENV": "local", "APP_URL": "http://localhost:3000"})
+	assert.NoError(t, err, "expected no error while creating SecurityConfig")
+	assert.True(t, securityConfig.IsAllowedOrigin("http://127.0.0.1:5173"), "expected any origin locally")
+	assert.True(t, securityConfig.IsAllowedHeader("X-Debug"), "expected any header locally")
+	assert.Equal(t, time.Duration(0), securityConfig.HSTSMaxAge())
+	assert.Empty(t, securityConfig.ContentSecurityPolicy())
+	assert.Equal(t, "SAMEORIGIN", securityConfig.FrameOptions())
+	assert.NoError(t, securityConfig.Validate())
+
+	securityConfig, err = testmain.NewSection(t, securityconfig.NewSecurityConfig, map[string]string{"APP_ENV": "production", "APP_URL": "https://app.diabuddy.io/dashboard"})
+	assert.NoError(t, err, "expected no error while creating SecurityConfig")
+	assert.Equal(t, []string{"https://app.diabuddy.io"}, securityConfig.AllowedOrigins(), "expected only the APP_URL origin in production")
+	assert.False(t, securityConfig.IsAllowedOrigin("http://127.0.0.1:5173"))
+	assert.False(t, securityConfig.IsAllowedHeader("X-Debug"))
+	assert.Equal(t, 8760*time.Hour, securityConfig.HSTSMaxAge())
+	assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", securityConfig.ContentSecurityPolicy())
+	assert.Equal(t, "DENY", securityConfig.FrameOptions())
+	assert.NoError(t, securityConfig.Validate())
+}
+
+func TestSecurityConfig_ExplicitValuesWin(t *testing.T) {
+	securityConfig, err := testmain.NewSection(t, securityconfig.NewSecurityConfig, map[string]string{
+		"APP_ENV":                "local",
+		"CORS_ALLOWED_ORIGINS":   "https://app.example.com",
+		"SECURITY_FRAME_OPTIONS": "deny",
+		"SECURITY_HSTS_MAX_AGE":  "1h",
+	})
+	assert.NoError(t, err, "expected no error while creating SecurityConfig")
+	assert.False(t, securityConfig.IsAllowedOrigin("https://other.example.com"))
+	assert.Equal(t, "DENY", securityConfig.FrameOptions())
+	assert.Equal(t, time.Hour, securityConfig.HSTSMaxAge())
+
+	value, found := securityConfig.Lookup("SECURITY_CSP")
+	assert.False(t, found, "expected the empty local CSP default to count as not found")
+	assert.Empty(t, value)
+}
+
+func TestSecurityConfig_DisabledHeaders(t *testing.T) {
+	securityConfig, err := testmain.NewSection(t, securityconfig.NewSecurityConfig, map[string]string{"APP_ENV": "production", "SECURITY_CSP": "none", "SECURITY_FRAME_OPTIONS": "None"})
+	assert.NoError(t, err, "expected no error while creating SecurityConfig")
+	assert.Empty(t, securityConfig.ContentSecurityPolicy())
+	assert.Empty(t, securityConfig.FrameOptions())
+	response := serve(securityConfig, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, response.Header().Get("Content-Security-Policy"), "expected none to disable the CSP header")
+	assert.Empty(t, response.Header().Get("X-Frame-Options"), "expected none to disable the X-Frame-Options header")
+
+	securityConfig, err = testmain.NewSection(t, securityconfig.NewSecurityConfig, map[string]string{"APP_ENV": "production", "SECURITY_CSP": "", "SECURITY_FRAME_OPTIONS": ""})
+	assert.NoError(t, err, "expected no error while creating SecurityConfig")
+	assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", securityConfig.ContentSecurityPolicy(), "expected an empty value to fall back to the default")
+	assert.Equal(t, "DENY", securityConfig.FrameOptions(), "expected an empty value to fall back to the default")
+}
+
+func TestSecurityConfig_IsAllowedOrigin(t *testing.T) {
+	securityConfig, err := testmain.NewSection(t, securityconfig.NewSecurityConfig, map[string]string{
+		"APP_ENV":              "production",
+		"APP_URL":              "https://diabuddy.io",
+		"CORS_ALLOWED_ORIGINS": "https://*.example.com, http://localhost:*/",
+	})
+	assert.NoError(t, err, "expected no error while creating SecurityConfig")
+
+	tests := []struct {
+		origin   string
+		expected bool
+	}{
+		{"https://diabuddy.io", true},
+		{"https://DIABUDDY.io", true},
+		{"https://app.example.com", true},
+		{"https://eu.app.example.com", true},
+		{"https://example.com", false},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://app.example.com.evil.io", false},
+		{"http://localhost:5173", true},
+		{"http://localhost", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, securityConfig.IsAllowedOrigin(tt.origin), tt.origin)
+	}
+}
+
+func TestSecurityConfig_InvalidValues(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"origin without scheme", map[string]string{"CORS_ALLOWED_ORIGINS": "example.com"}},
+		{"origin with path", map[string]string{"CORS_ALLOWED_ORIGINS": "https://example.com/app"}},
+		{"frame options", map[string]string{"SECURITY_FRAME_OPTIONS": "ALLOW-FROM https://example.com"}},
+		{"max age", map[string]string{"CORS_MAX_AGE": "-1s"}},
+		{"credentials", map[string]string{"CORS_ALLOW_CREDENTIALS": "sometimes"}},
+		{"app url", map[string]string{"APP_URL": "localhost"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testmain.NewSection(t, securityconfig.NewSecurityConfig, tt.env)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSecurityConfig_Validate(t *testing.T) {
+	securityConfig, err := testmain.NewSection(t, securityconfig.NewSecurityConfig, map[string]string{"APP_ENV": "local", "CORS_ALLOW_CREDENTIALS": "true"})
+	assert.NoError(t, err, "expected no error while creating SecurityConfig")
+	assert.Error(t, securityConfig.Validate(), "expected credentials with any origin to be rejected")
+
+	securityConfig, err = testmain.NewSection(t, securityconfig.NewSecurityConfig, map[string]string{"APP_ENV": "production", "CORS_ALLOWED_ORIGINS": "*"})
+	assert.NoError(t, err, "expected no error while creating SecurityConfig")
+	validationErr := securityConfig.Validate()
+	assert.Error(t, validationErr, "expected any origin to be rejected in production")
+	assert.Contains(t, validationErr.Error(), "CORS_ALLOWED_ORIGINS")
+}
+
+func TestSecurityConfig_Middleware(t *testing.T) {
+	securityConfig, err := testmain.NewSection(t, securityconfig.NewSecurityConfig, map[string]string{
+		"APP_ENV":                "production",
+		"APP_URL":                "https://diabuddy.io",
+		"CORS_ALLOW_CREDENTIALS": "true",
+		"CORS_MAX_AGE":           "1m",
+	})
+	assert.NoError(t, err, "expected no error while creating SecurityConfig")
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	response := serve(securityConfig, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "max-age=31536000; includeSubDomains", response.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "DENY", response.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", response.Header().Get("X-Content-Type-Options"))
+	assert.NotEmpty(t, response.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Origin"), "expected no CORS headers without an Origin")
+
+	request = httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Origin", "https://diabuddy.io")
+	response = serve(securityConfig, request)
+	assert.Equal(t, "https://diabuddy.io", response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", response.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Origin", response.Header().Get("Vary"))
+
+	request = httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Origin", "https://evil.io")
+	response = serve(securityConfig, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Origin"), "expected no CORS headers for other origins")
+
+	request = httptest.NewRequest(http.MethodOptions, "/", nil)
+	request.Header.Set("Origin", "https://diabuddy.io")
+	request.Header.Set("Access-Control-Request-Method", "PATCH")
+	request.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+	response = serve(securityConfig, request)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, "GET, POST, PUT, PATCH, DELETE, OPTIONS", response.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Accept, Authorization, Content-Type, X-Requested-With", response.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "60", response.Header().Get("Access-Control-Max-Age"))
+
+	request = httptest.NewRequest(http.MethodOptions, "/", nil)
+	request.Header.Set("Origin", "https://diabuddy.io")
+	request.Header.Set("Access-Control-Request-Method", "GET")
+	request.Header.Set("Access-Control-Request-Headers", "X-Debug")
+	response = serve(securityConfig, request)
+	assert.Equal(t, http.StatusForbidden, response.Code, "expected a preflight with an unknown header to be rejected")
+
+	request = httptest.NewRequest(http.MethodOptions, "/", nil)
+	request.Header.Set("Origin", "https://evil.io")
+	request.Header.Set("Access-Control-Request-Method", "GET")
+	response = serve(securityConfig, request)
+	assert.Equal(t, http.StatusForbidden, response.Code, "expected a preflight from another origin to be rejected")
+}
+
+func TestSecurityConfig_MiddlewareAnyHeader(t *testing.T) {
+	securityConfig, err := testmain.NewSection(t, securityconfig.NewSecurityConfig, map[string]string{"APP_ENV": "local"})
+	assert.NoError(t, err, "expected no error while creating SecurityConfig")
+
+	request := httptest.NewRequest(http.MethodOptions, "/", nil)
+	request.Header.Set("Origin", "http://localhost:5173")
+	request.Header.Set("Access-Control-Request-Method", "DELETE")
+	request.Header.Set("Access-Control-Request-Headers", "X-Debug")
+	response := serve(securityConfig, request)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, "http://localhost:5173", response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Debug", response.Header().Get("Access-Control-Allow-Headers"))
+	assert.Empty(t, response.Header().Get("Strict-Transport-Security"), "expected no HSTS locally")
+}
+
+func TestSecurityConfig_MiddlewareDuringReload(t *testing.T) {
+	securityConfig, err := testmain.NewSection(t, securityconfig.NewSecurityConfig, map[string]string{"APP_ENV": "production", "CORS_ALLOWED_ORIGINS": "https://app.diabuddy.io"})
+	assert.NoError(t, err, "expected no error while creating SecurityConfig")
+	handler := securityConfig.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://admin.diabuddy.io")
+
+	var requests sync.WaitGroup
+	for range 4 {
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+			for range 50 {
+				request := httptest.NewRequest(http.MethodGet, "/", nil)
+				request.Header.Set("Origin", "https://app.diabuddy.io")
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, request)
+				assert.Equal(t, "https://app.diabuddy.io", recorder.Header().Get("Access-Control-Allow-Origin"), "expected the middleware to keep the origins it was built with")
+			}
+		}()
+	}
+	for range 50 {
+		assert.NoError(t, securityConfig.Reload(), "expected no error while reloading")
+	}
+	requests.Wait()
+	assert.False(t, securityConfig.IsAllowedOrigin("https://app.diabuddy.io"), "expected the section to pick up the new origins")
+}
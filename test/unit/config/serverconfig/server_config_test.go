@@ -0,0 +1,150 @@
+package serverconfig_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"github.com/hbttundar/diabuddy-api-config/config/serverconfig"
+	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServerConfig_Defaults(t *testing.T) {
+	serverConfig, err := testmain.NewSectionWithOptions(t, serverconfig.NewServerConfig, map[string]string{"APP_PORT": ""})
+	assert.NoError(t, err, "expected no error while creating ServerConfig")
+
+	assert.Equal(t, ":8080", serverConfig.Addr())
+	assert.Equal(t, 15*time.Second, serverConfig.ReadTimeout())
+	assert.Equal(t, 5*time.Second, serverConfig.ReadHeaderTimeout())
+	assert.Equal(t, 15*time.Second, serverConfig.WriteTimeout())
+	assert.Equal(t, time.Minute, serverConfig.IdleTimeout())
+	assert.Equal(t, 1<<20, serverConfig.MaxHeaderBytes())
+	assert.Equal(t, 10*time.Second, serverConfig.ShutdownTimeout())
+	assert.Empty(t, serverConfig.TrustedProxies())
+	assert.False(t, serverConfig.TLSEnabled())
+	assert.NoError(t, serverConfig.Validate())
+}
+
+func TestServerConfig_NewHTTPServer(t *testing.T) {
+	serverConfig, err := testmain.NewSectionWithOptions(t, serverconfig.NewServerConfig, map[string]string{
+		"SERVER_HOST":             "127.0.0.1",
+		"APP_PORT":                "9000",
+		"SERVER_WRITE_TIMEOUT":    "30s",
+		"SERVER_MAX_HEADER_BYTES": "4096",
+		"SERVER_TRUSTED_PROXIES":  "10.0.0.0/8, 192.168.1.10, ::1",
+	})
+	assert.NoError(t, err, "expected no error while creating ServerConfig")
+
+	handler := http.NewServeMux()
+	server := serverConfig.NewHTTPServer(handler)
+	assert.Equal(t, "127.0.0.1:9000", server.Addr)
+	assert.Equal(t, handler, server.Handler)
+	assert.Equal(t, 30*time.Second, server.WriteTimeout)
+	assert.Equal(t, 5*time.Second, server.ReadHeaderTimeout)
+	assert.Equal(t, 4096, server.MaxHeaderBytes)
+
+	assert.Len(t, serverConfig.TrustedProxies(), 3)
+	assert.True(t, serverConfig.IsTrustedProxy(net.ParseIP("10.1.2.3")))
+	assert.True(t, serverConfig.IsTrustedProxy(net.ParseIP("192.168.1.10")))
+	assert.True(t, serverConfig.IsTrustedProxy(net.ParseIP("::1")))
+	assert.False(t, serverConfig.IsTrustedProxy(net.ParseIP("192.168.1.11")))
+}
+
+func TestServerConfig_WithDefault(t *testing.T) {
+	serverConfig, err := testmain.NewSectionWithOptions(t, serverconfig.NewServerConfig, map[string]string{"APP_PORT": ""}, serverconfig.WithDefault(envmanager.ServerPortKey, "8081"))
+	assert.NoError(t, err, "expected no error while creating ServerConfig")
+	assert.Equal(t, 8081, serverConfig.Port(), "expected the service default to win over the registered one")
+	value, found := serverConfig.Lookup(envmanager.ServerPortKey)
+	assert.True(t, found)
+	assert.Equal(t, "8081", value)
+
+	serverConfig, err = testmain.NewSectionWithOptions(t, serverconfig.NewServerConfig, map[string]string{"APP_PORT": "9090"}, serverconfig.WithDefault(envmanager.ServerPortKey, "8081"))
+	assert.NoError(t, err, "expected no error while creating ServerConfig")
+	assert.Equal(t, 9090, serverConfig.Port(), "expected the environment to win over the service default")
+
+	_, err = testmain.NewSectionWithOptions(t, serverconfig.NewServerConfig, nil, serverconfig.WithDefault(envmanager.DbHostKey, "db"))
+	assert.Error(t, err, "expected keys of other sections to be rejected")
+}
+
+func TestServerConfig_InvalidValues(t *testing.T) {
+	tests := []struct {
+		name           string
+		envVariables   map[string]string
+		expectedErrMsg string
+	}{
+		{"Invalid port", map[string]string{"APP_PORT": "http"}, `APP_PORT must be an integer, got "http"`},
+		{"Invalid duration", map[string]string{"SERVER_IDLE_TIMEOUT": "forever"}, "SERVER_IDLE_TIMEOUT must be a duration"},
+		{"Negative duration", map[string]string{"SERVER_READ_TIMEOUT": "-1s"}, "SERVER_READ_TIMEOUT must be a duration of zero or more"},
+		{"Invalid proxy", map[string]string{"SERVER_TRUSTED_PROXIES": "10.0.0.0/8,proxy.local"}, `SERVER_TRUSTED_PROXIES must be an IP address or CIDR, got "proxy.local"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testmain.NewSectionWithOptions(t, serverconfig.NewServerConfig, tt.envVariables)
+			assert.Error(t, err)
+			if err != nil {
+				assert.Contains(t, err.Error(), tt.expectedErrMsg)
+			}
+		})
+	}
+}
+
+func TestServerConfig_Validate(t *testing.T) {
+	certFile := filepath.Join(t.TempDir(), "server.crt")
+	assert.NoError(t, os.WriteFile(certFile, []byte("cert"), 0600))
+
+	tests := []struct {
+		name           string
+		envVariables   map[string]string
+		expectedErrMsg string
+	}{
+		{"Port out of range", map[string]string{"APP_PORT": "70000"}, "APP_PORT must be a port between 1 and 65535"},
+		{"TLS certificate without key", map[string]string{"SERVER_TLS_CERT_FILE": certFile}, "SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE must be set together"},
+		{"Missing TLS key file", map[string]string{"SERVER_TLS_CERT_FILE": certFile, "SERVER_TLS_KEY_FILE": certFile + ".missing"}, "SERVER_TLS_KEY_FILE points to a file that cannot be read"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConfig, err := testmain.NewSectionWithOptions(t, serverconfig.NewServerConfig, tt.envVariables)
+			assert.NoError(t, err, "expected no error while creating ServerConfig")
+			err = serverConfig.Validate()
+			assert.Error(t, err)
+			if err != nil {
+				assert.Contains(t, err.Error(), tt.expectedErrMsg)
+			}
+		})
+	}
+
+	testmain.SetupEnv(t, map[string]string{"APP_PORT": ""})
+	envManager, envErr := envmanager.NewEnvManager(envmanager.WithUseDefault(false))
+	assert.NoError(t, envErr, "expected no error while creating env manager")
+	serverConfig, err := serverconfig.NewServerConfig(envManager)
+	assert.NoError(t, err, "expected no error while creating ServerConfig")
+	err = serverConfig.Validate()
+	assert.Error(t, err, "expected an empty APP_PORT to be reported as missing")
+	if err != nil {
+		assert.Equal(t, "Error 500: missing required key(s): APP_PORT", err.Error())
+	}
+
+	serverConfig, err = testmain.NewSectionWithOptions(t, serverconfig.NewServerConfig, map[string]string{"SERVER_TLS_CERT_FILE": certFile, "SERVER_TLS_KEY_FILE": certFile})
+	assert.NoError(t, err)
+	assert.NoError(t, serverConfig.Validate())
+	assert.True(t, serverConfig.TLSEnabled())
+}
+
+func TestServerConfig_Introspection(t *testing.T) {
+	serverConfig, err := testmain.NewSectionWithOptions(t, serverconfig.NewServerConfig, nil)
+	assert.NoError(t, err, "expected no error while creating ServerConfig")
+
+	var section config.Config = serverConfig
+	introspectable, ok := section.(config.Introspectable)
+	assert.True(t, ok, "expected ServerConfig to implement every capability interface")
+	assert.Contains(t, introspectable.Keys(), envmanager.ServerPortKey)
+	assert.Equal(t, []string{envmanager.ServerPortKey}, introspectable.RequiredKeys())
+	assert.Equal(t, "15s", introspectable.Snapshot()[envmanager.ServerReadTimeoutKey])
+}
@@ -0,0 +1,204 @@
+package storageconfig_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/config"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"github.com/hbttundar/diabuddy-api-config/config/storageconfig"
+	testmain "github.com/hbttundar/diabuddy-api-config/test"
+	"github.com/stretchr/testify/assert"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newStorageConfig roots the storage path in a temporary APP_BASE_PATH.
+func newStorageConfig(t *testing.T, envVariables map[string]string) (*storageconfig.StorageConfig, string, error) {
+	t.Helper()
+	basePath := t.TempDir()
+	withBasePath := make(map[string]string, len(envVariables)+1)
+	maps.Copy(withBasePath, envVariables)
+	withBasePath[envmanager.AppBasePathKey] = basePath
+
+	storageConfig, err := testmain.NewSectionWithOptions(t, storageconfig.NewStorageConfig, withBasePath)
+	return storageConfig, basePath, err
+}
+
+func assertPath(t *testing.T, expected string, disk storageconfig.Disk, elem ...string) {
+	t.Helper()
+	actual, err := disk.Path(elem...)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func assertPublicURL(t *testing.T, expected string, disk storageconfig.Disk, elem ...string) {
+	t.Helper()
+	actual, err := disk.PublicURL(elem...)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestStorageConfig_Defaults(t *testing.T) {
+	storageConfig, basePath, err := newStorageConfig(t, nil)
+	assert.NoError(t, err, "expected no error while creating StorageConfig")
+
+	assert.Equal(t, []string{"local"}, storageConfig.Disks())
+	disk := storageConfig.DefaultDisk()
+	assert.Equal(t, "local", disk.Name)
+	assert.Equal(t, storageconfig.LocalDriver, disk.Driver)
+	assert.Equal(t, filepath.Join(basePath, "storage", "app", "local"), disk.Root)
+	assertPath(t, filepath.Join(disk.Root, "meals", "42.jpg"), disk, "meals", "42.jpg")
+	link, linkErr := disk.PublicURL("meals", "42.jpg")
+	assert.Nil(t, linkErr)
+	assert.Empty(t, link)
+
+	assert.NoError(t, storageConfig.Validate())
+	info, statErr := os.Stat(disk.Root)
+	assert.NoError(t, statErr, "expected Validate to create the local root")
+	assert.True(t, info.IsDir())
+}
+
+func TestStorageConfig_NamedDisks(t *testing.T) {
+	storageConfig, basePath, err := newStorageConfig(t, map[string]string{
+		"STORAGE_DISKS":                         "local, meal-photos",
+		"STORAGE_DEFAULT_DISK":                  "meal-photos",
+		"STORAGE_LOCAL_ROOT":                    "uploads",
+		"STORAGE_LOCAL_URL":                     "https://api.diabuddy.io/files",
+		"STORAGE_MEAL_PHOTOS_DRIVER":            "S3",
+		"STORAGE_MEAL_PHOTOS_ENDPOINT":          "http://localhost:9000",
+		"STORAGE_MEAL_PHOTOS_REGION":            "eu-central-1",
+		"STORAGE_MEAL_PHOTOS_BUCKET":            "diabuddy-meal-photos",
+		"STORAGE_MEAL_PHOTOS_ROOT":              "/production/",
+		"STORAGE_MEAL_PHOTOS_USE_PATH_STYLE":    "true",
+		"STORAGE_MEAL_PHOTOS_ACCESS_KEY_ID":     "minio",
+		"STORAGE_MEAL_PHOTOS_SECRET_ACCESS_KEY": "minio-secret",
+		"STORAGE_MEAL_PHOTOS_URL":               "https://cdn.diabuddy.io/",
+	})
+	assert.NoError(t, err, "expected no error while creating StorageConfig")
+	assert.NoError(t, storageConfig.Validate())
+	assert.Equal(t, []string{"local", "meal-photos"}, storageConfig.Disks())
+
+	local, ok := storageConfig.Disk("local")
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(basePath, "storage", "uploads"), local.Root)
+	assertPublicURL(t, "https://api.diabuddy.io/files/avatars/jane%20doe.png", local, "avatars", "jane doe.png")
+
+	photos := storageConfig.DefaultDisk()
+	assert.Equal(t, "meal-photos", photos.Name)
+	assert.Equal(t, storageconfig.S3Driver, photos.Driver)
+	assert.Equal(t, "http://localhost:9000", photos.Endpoint)
+	assert.Equal(t, "eu-central-1", photos.Region)
+	assert.Equal(t, "diabuddy-meal-photos", photos.Bucket)
+	assert.True(t, photos.UsePathStyle)
+	assert.Equal(t, "minio", photos.AccessKeyID)
+	assert.Equal(t, "minio-secret", photos.SecretAccessKey)
+	assertPath(t, "production/2026/10/meal.jpg", photos, "2026/10", "meal.jpg")
+	assertPublicURL(t, "https://cdn.diabuddy.io/2026/10/meal.jpg", photos, "2026/10", "meal.jpg")
+
+	snapshot := storageConfig.Snapshot()
+	assert.Equal(t, config.RedactedValue, snapshot["STORAGE_MEAL_PHOTOS_SECRET_ACCESS_KEY"])
+	assert.Equal(t, "diabuddy-meal-photos", snapshot["STORAGE_MEAL_PHOTOS_BUCKET"])
+	assert.Contains(t, storageConfig.Keys(), "STORAGE_MEAL_PHOTOS_REGION")
+
+	_, ok = storageConfig.Disk("avatars")
+	assert.False(t, ok)
+}
+
+func TestStorageConfig_AbsoluteLocalRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "mnt", "photos")
+	storageConfig, _, err := newStorageConfig(t, map[string]string{"STORAGE_LOCAL_ROOT": root})
+	assert.NoError(t, err, "expected no error while creating StorageConfig")
+	assert.Equal(t, root, storageConfig.DefaultDisk().Root)
+	assert.NoError(t, storageConfig.Validate())
+}
+
+func TestStorageConfig_InvalidValues(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"disk name", map[string]string{"STORAGE_DISKS": "local,1st"}},
+		{"duplicate disk", map[string]string{"STORAGE_DISKS": "local,local"}},
+		{"disks sharing keys", map[string]string{"STORAGE_DISKS": "meal-photos,Meal_Photos"}},
+		{"driver", map[string]string{"STORAGE_LOCAL_DRIVER": "ftp"}},
+		{"endpoint", map[string]string{"STORAGE_LOCAL_ENDPOINT": "localhost:9000"}},
+		{"url", map[string]string{"STORAGE_LOCAL_URL": "cdn.diabuddy.io"}},
+		{"path style", map[string]string{"STORAGE_LOCAL_USE_PATH_STYLE": "sometimes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := newStorageConfig(t, tt.env)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStorageConfig_Validate(t *testing.T) {
+	s3Disk := func(bucket string, extra map[string]string) map[string]string {
+		env := map[string]string{
+			"STORAGE_DISKS":         "photos",
+			"STORAGE_PHOTOS_DRIVER": "s3",
+			"STORAGE_PHOTOS_REGION": "eu-central-1",
+			"STORAGE_PHOTOS_BUCKET": bucket,
+		}
+		for key, value := range extra {
+			env[key] = value
+		}
+		return env
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		contains string
+	}{
+		{"missing driver", map[string]string{"STORAGE_DISKS": "photos"}, "STORAGE_PHOTOS_DRIVER"},
+		{"missing bucket and region", map[string]string{"STORAGE_DISKS": "photos", "STORAGE_PHOTOS_DRIVER": "s3"}, "STORAGE_PHOTOS_BUCKET, STORAGE_PHOTOS_REGION"},
+		{"unknown default disk", map[string]string{"STORAGE_DEFAULT_DISK": "photos"}, "STORAGE_DEFAULT_DISK"},
+		{"no disks", map[string]string{"STORAGE_DISKS": ","}, "STORAGE_DEFAULT_DISK"},
+		{"upper case bucket", s3Disk("Meal-Photos", nil), "STORAGE_PHOTOS_BUCKET"},
+		{"short bucket", s3Disk("mp", nil), "STORAGE_PHOTOS_BUCKET"},
+		{"bucket with dots in a row", s3Disk("meal..photos", nil), "STORAGE_PHOTOS_BUCKET"},
+		{"bucket like an ip address", s3Disk("192.168.5.4", nil), "STORAGE_PHOTOS_BUCKET"},
+		{"reserved bucket prefix", s3Disk("xn--photos", nil), "STORAGE_PHOTOS_BUCKET"},
+		{"access key without secret", s3Disk("meal-photos", map[string]string{"STORAGE_PHOTOS_ACCESS_KEY_ID": "minio"}), "must be set together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageConfig, _, err := newStorageConfig(t, tt.env)
+			assert.NoError(t, err, "expected no error while creating StorageConfig")
+			validationErr := storageConfig.Validate()
+			assert.Error(t, validationErr)
+			assert.Contains(t, validationErr.Error(), tt.contains)
+		})
+	}
+}
+
+func TestStorageConfig_ValidateUnwritableRoot(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "not-a-directory")
+	assert.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	storageConfig, _, err := newStorageConfig(t, map[string]string{"STORAGE_LOCAL_ROOT": filepath.Join(file, "photos")})
+	assert.NoError(t, err, "expected no error while creating StorageConfig")
+	validationErr := storageConfig.Validate()
+	assert.Error(t, validationErr)
+	assert.Contains(t, validationErr.Error(), "disk local")
+}
+
+func TestDisk_PathStaysBelowRoot(t *testing.T) {
+	local := storageconfig.Disk{Name: "photos", Driver: storageconfig.LocalDriver, Root: "/srv/storage/photos", URL: "https://cdn.diabuddy.io"}
+	s3 := storageconfig.Disk{Name: "photos", Driver: storageconfig.S3Driver, Root: "production", URL: "https://cdn.diabuddy.io"}
+
+	assertPath(t, "/srv/storage/photos/etc/passwd", local, "/etc/passwd")
+	assertPath(t, "/srv/storage/photos/meal.jpg", local, "2026", "../meal.jpg")
+	assertPath(t, "production/meal.jpg", s3, "/../meal.jpg")
+
+	for _, elem := range [][]string{{"../../../etc/passwd"}, {"2026", "../../meal.jpg"}, {".."}} {
+		for _, disk := range []storageconfig.Disk{local, s3} {
+			_, err := disk.Path(elem...)
+			assert.NotNil(t, err, "expected %v to be rejected on the %s disk", elem, disk.Driver)
+			_, err = disk.PublicURL(elem...)
+			assert.NotNil(t, err, "expected %v to be rejected on the %s disk", elem, disk.Driver)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package envfile_test
+
+import (
+	"github.com/hbttundar/diabuddy-api-config/util/envfile"
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		key      string
+		value    string
+		expected string
+	}{
+		{
+			name:     "Replaces an existing assignment in place",
+			content:  "# App variables\nAPP_NAME=diabuddy\nAPP_KEY=\n\n# Auth variables\nAUTH_SECRET=\n",
+			key:      "APP_KEY",
+			value:    "base64:abc=",
+			expected: "# App variables\nAPP_NAME=diabuddy\nAPP_KEY=base64:abc=\n\n# Auth variables\nAUTH_SECRET=\n",
+		},
+		{
+			name:     "Keeps export, indentation and inline comments",
+			content:  "  export APP_KEY=old # rotated yearly\r\nAPP_NAME=diabuddy\r\n",
+			key:      "APP_KEY",
+			value:    "new",
+			expected: "  export APP_KEY=new # rotated yearly\r\nAPP_NAME=diabuddy\r\n",
+		},
+		{
+			name:     "Does not match keys with the same prefix",
+			content:  "APP_KEY_ID=1\nAPP_KEY=old\n",
+			key:      "APP_KEY",
+			value:    "new",
+			expected: "APP_KEY_ID=1\nAPP_KEY=new\n",
+		},
+		{
+			name:     "Replaces every assignment, since godotenv uses the last one",
+			content:  "APP_KEY=old\nAPP_NAME=diabuddy\nAPP_KEY=duplicate\n",
+			key:      "APP_KEY",
+			value:    "new",
+			expected: "APP_KEY=new\nAPP_NAME=diabuddy\nAPP_KEY=new\n",
+		},
+		{
+			name:     "Appends a missing key",
+			content:  "APP_NAME=diabuddy",
+			key:      "APP_KEY",
+			value:    "new value",
+			expected: "APP_NAME=diabuddy\nAPP_KEY='new value'\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			assert.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			assert.NoError(t, envfile.Set(path, tt.key, tt.value))
+
+			content, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+		})
+	}
+}
+
+func TestSet_CreatesMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env.production")
+
+	assert.NoError(t, envfile.Set(path, "APP_KEY", "secret"))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "APP_KEY=secret\n", string(content))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "expected new files to be private")
+}
+
+func TestSet_InvalidKey(t *testing.T) {
+	assert.Error(t, envfile.Set(filepath.Join(t.TempDir(), ".env"), "APP KEY", "x"))
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, "base64:abc+/=", envfile.Quote("base64:abc+/="))
+	assert.Equal(t, "", envfile.Quote(""))
+	assert.Equal(t, `'a #b'`, envfile.Quote("a #b"))
+	assert.Equal(t, `C:\keys\`, envfile.Quote(`C:\keys\`))
+	assert.Equal(t, `'pa$$word'`, envfile.Quote("pa$$word"))
+	assert.Equal(t, `"it's \$5"`, envfile.Quote("it's $5"))
+	assert.Equal(t, `"line\nbreak"`, envfile.Quote("line\nbreak"))
+}
+
+func TestSet_RoundTrip(t *testing.T) {
+	values := []string{
+		"base64:abc+/=",
+		"new value",
+		"a #b",
+		`say "hi" now`,
+		"$HOME",
+		"pa$$word",
+		"${APP_NAME}",
+		`\$HOME`,
+		"it's $5",
+		"tab\tand space",
+		"line\nbreak\r\n",
+		`C:\keys\`,
+		`\n stays`,
+		"grüße ✓",
+	}
+	for _, value := range values {
+		t.Run(value, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			assert.NoError(t, envfile.Set(path, "APP_KEY", value))
+
+			read, err := godotenv.Read(path)
+			assert.NoError(t, err)
+			assert.Equal(t, value, read["APP_KEY"], "expected godotenv to read back the written value")
+		})
+	}
+}
+
+func TestSet_RejectsValuesThatDoNotReadBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	err := envfile.Set(path, "APP_KEY", `it's "quoted"`)
+	assert.ErrorContains(t, err, "cannot be written")
+	_, statErr := os.Stat(path)
+	assert.True(t, os.IsNotExist(statErr), "expected nothing to be written")
+}
@@ -0,0 +1,49 @@
+package envdoc_test
+
+import (
+	"bytes"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	"github.com/hbttundar/diabuddy-api-config/util/generator/envdoc"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+var definitions = []envmanager.KeyDefinition{
+	{Name: "APP_NAME", Section: "app", Default: "default_app", Required: true, Description: "Application name."},
+	{Name: "APP_KEY", Section: "app", Default: "should-not-leak", Sensitive: true, Description: "Encryption key."},
+	{Name: "DB_HOST", Section: "db", Default: "127.0.0.1", Description: "Database host | primary."},
+	{Name: "MAIL_FROM_NAME", Section: "mail", Default: "Diabuddy Team", Description: "Sender name."},
+}
+
+func TestWriteEnvExample(t *testing.T) {
+	var out bytes.Buffer
+	err := envdoc.WriteEnvExample(&out, definitions)
+	assert.NoError(t, err, "expected no error while writing env example")
+
+	generated := out.String()
+	assert.Contains(t, generated, "# --- app ---\n# Application name. (required)\nAPP_NAME=default_app\n", "expected annotated required key")
+	assert.Contains(t, generated, "APP_KEY=\n", "expected sensitive key to be written without a value")
+	assert.NotContains(t, generated, "should-not-leak", "expected sensitive default to be omitted")
+	assert.Contains(t, generated, "# --- db ---\n", "expected a header per section")
+	assert.Contains(t, generated, "MAIL_FROM_NAME=\"Diabuddy Team\"\n", "expected values with spaces to be quoted")
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var out bytes.Buffer
+	err := envdoc.WriteMarkdown(&out, definitions)
+	assert.NoError(t, err, "expected no error while writing markdown")
+
+	generated := out.String()
+	assert.Equal(t, 3, strings.Count(generated, "| Key | Default | Required | Sensitive | Description |"), "expected one table per section")
+	assert.Contains(t, generated, "| `APP_NAME` | `default_app` | yes | no | Application name. |", "expected a row for APP_NAME")
+	assert.Contains(t, generated, "| `APP_KEY` |  | no | yes | Encryption key. |", "expected sensitive default to be omitted")
+	assert.Contains(t, generated, "Database host \\| primary.", "expected pipes in descriptions to be escaped")
+}
+
+func TestGeneratorIsDeterministic(t *testing.T) {
+	var first, second bytes.Buffer
+	assert.NoError(t, envdoc.WriteEnvExample(&first, envmanager.KeyDefinitions()))
+	assert.NoError(t, envdoc.WriteEnvExample(&second, envmanager.KeyDefinitions()))
+	assert.Equal(t, first.String(), second.String(), "expected generating twice to produce identical output")
+}
@@ -0,0 +1,101 @@
+package envfile
+
+import (
+	"bytes"
+	"fmt"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"github.com/joho/godotenv"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	validKey      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	inlineComment = regexp.MustCompile(`\s+#.*$`)
+	// doubleQuoted escapes what godotenv unescapes or expands inside double quotes.
+	doubleQuoted = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`)
+)
+
+// Set writes key=value into the .env file at path. Every existing assignment of the key, with or without the
+// export keyword, is replaced in place together with its indentation and any inline comment after an unquoted
+// value; every other line stays as it is. If the key is not assigned yet, it is appended. A missing file is
+// created with mode 0600, since it usually holds secrets. Values that godotenv cannot read back unchanged, such
+// as one ending in a double quote, are rejected.
+func Set(path, key, value string) diabuddyErrors.ApiErrors {
+	if !validKey.MatchString(key) {
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("invalid key %q", key))
+	}
+	quoted := Quote(value)
+	if parsed, err := godotenv.Unmarshal(key + "=" + quoted); err != nil || parsed[key] != value {
+		// The value is usually a secret, so it is left out of the error.
+		return diabuddyErrors.NewApiError(diabuddyErrors.BadRequestErrorType, fmt.Sprintf("the value of %s cannot be written to a .env file so that it reads back unchanged", key))
+	}
+
+	mode := os.FileMode(0o600)
+	content, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if info, statErr := os.Stat(path); statErr == nil {
+			mode = info.Mode().Perm()
+		}
+	case os.IsNotExist(err):
+		content = nil
+	default:
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("could not read %s", path), diabuddyErrors.WithInternalError(err))
+	}
+
+	content = set(content, key, quoted)
+	if err := os.WriteFile(path, content, mode); err != nil {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, fmt.Sprintf("could not write %s", path), diabuddyErrors.WithInternalError(err))
+	}
+	return nil
+}
+
+// Quote returns the value as it has to be written into a .env file: unchanged if it is safe unquoted, in single
+// quotes, which godotenv reads literally, if it has no ' or line break, and otherwise in double quotes with \,
+// ", $ and line breaks escaped.
+func Quote(value string) string {
+	// godotenv keeps a backslash in an unquoted value unless it escapes a $.
+	if value == "" || !strings.ContainsAny(value, " \t\r\n#\"'`$") {
+		return value
+	}
+	// godotenv ends a quoted value at the first quote that does not follow a backslash.
+	if !strings.ContainsAny(value, "'\r\n") && !strings.HasSuffix(value, `\`) {
+		return "'" + value + "'"
+	}
+	return `"` + doubleQuoted.Replace(value) + `"`
+}
+
+func set(content []byte, key, value string) []byte {
+	assignment := regexp.MustCompile(`^(\s*(?:export\s+)?)` + regexp.QuoteMeta(key) + `\s*=(.*)$`)
+
+	lines := bytes.Split(content, []byte("\n"))
+	assigned := false
+	for i, line := range lines {
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		match := assignment.FindSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		var comment []byte
+		if rest := bytes.TrimSpace(match[2]); len(rest) > 0 && rest[0] != '"' && rest[0] != '\'' {
+			comment = inlineComment.Find(match[2])
+		}
+		replaced := append([]byte(string(match[1])+key+"="+value), comment...)
+		if bytes.HasSuffix(lines[i], []byte("\r")) {
+			replaced = append(replaced, '\r')
+		}
+		lines[i] = replaced
+		assigned = true
+	}
+	if assigned {
+		return bytes.Join(lines, []byte("\n"))
+	}
+
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		content = append(content, '\n')
+	}
+	return append(content, []byte(key+"="+value+"\n")...)
+}
@@ -0,0 +1,94 @@
+package envdoc
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/hbttundar/diabuddy-api-config/config/envmanager"
+	diabuddyErrors "github.com/hbttundar/diabuddy-errors"
+	"io"
+	"strings"
+)
+
+const generatedNotice = "Generated by cmd/envdoc from the registered configuration keys. Do not edit by hand."
+
+// WriteEnvExample writes an annotated dotenv file containing every given key with its default value.
+// Sensitive keys are always written without a value.
+func WriteEnvExample(w io.Writer, definitions []envmanager.KeyDefinition) diabuddyErrors.ApiErrors {
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "# %s\n", generatedNotice)
+
+	section := ""
+	for i, definition := range definitions {
+		if i == 0 || definition.Section != section {
+			section = definition.Section
+			fmt.Fprintf(buf, "\n# --- %s ---\n", section)
+		}
+		fmt.Fprintf(buf, "# %s\n", describe(definition))
+		value := definition.Default
+		if definition.Sensitive {
+			value = ""
+		}
+		fmt.Fprintf(buf, "%s=%s\n", definition.Name, quoteValue(value))
+	}
+
+	return flush(buf)
+}
+
+// WriteMarkdown writes a Markdown reference of the given keys, one table per section.
+func WriteMarkdown(w io.Writer, definitions []envmanager.KeyDefinition) diabuddyErrors.ApiErrors {
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "# Configuration reference\n\n<!-- %s -->\n", generatedNotice)
+
+	section := ""
+	for i, definition := range definitions {
+		if i == 0 || definition.Section != section {
+			section = definition.Section
+			fmt.Fprintf(buf, "\n## %s\n\n", section)
+			fmt.Fprintln(buf, "| Key | Default | Required | Sensitive | Description |")
+			fmt.Fprintln(buf, "|-----|---------|----------|-----------|-------------|")
+		}
+		defaultValue := ""
+		if definition.Default != "" && !definition.Sensitive {
+			defaultValue = "`" + definition.Default + "`"
+		}
+		fmt.Fprintf(buf, "| `%s` | %s | %s | %s | %s |\n",
+			definition.Name,
+			defaultValue,
+			yesNo(definition.Required),
+			yesNo(definition.Sensitive),
+			strings.ReplaceAll(definition.Description, "|", "\\|"),
+		)
+	}
+
+	return flush(buf)
+}
+
+func describe(definition envmanager.KeyDefinition) string {
+	description := definition.Description
+	if definition.Required {
+		description += " (required)"
+	}
+	return strings.TrimSpace(description)
+}
+
+// quoteValue quotes values that a dotenv parser would otherwise cut short or misread.
+func quoteValue(value string) string {
+	if strings.ContainsAny(value, " #\"'=") {
+		return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+	}
+	return value
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+func flush(buf *bufio.Writer) diabuddyErrors.ApiErrors {
+	if err := buf.Flush(); err != nil {
+		return diabuddyErrors.NewApiError(diabuddyErrors.InternalServerErrorType, "failed to write generated documentation", diabuddyErrors.WithInternalError(err))
+	}
+	return nil
+}